@@ -2,7 +2,13 @@ module github.com/aleksey925/agentbox
 
 go 1.25
 
-require github.com/vbauerster/mpb/v8 v8.11.3
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/creack/pty v1.1.24
+	github.com/vbauerster/mpb/v8 v8.11.3
+	golang.org/x/sys v0.39.0
+	golang.org/x/term v0.38.0
+)
 
 require (
 	github.com/VividCortex/ewma v1.2.0 // indirect
@@ -10,5 +16,4 @@ require (
 	github.com/clipperhouse/stringish v0.1.1 // indirect
 	github.com/clipperhouse/uax29/v2 v2.3.0 // indirect
 	github.com/mattn/go-runewidth v0.0.19 // indirect
-	golang.org/x/sys v0.39.0 // indirect
 )
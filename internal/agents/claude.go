@@ -47,6 +47,64 @@ func (c *ClaudeAgent) BinaryName() string {
 	return "claude"
 }
 
+func (c *ClaudeAgent) Interpreter() []string {
+	return nil
+}
+
+func (c *ClaudeAgent) Domains() []string {
+	return []string{"api.anthropic.com", "statsig.anthropic.com", "console.anthropic.com", "claude.ai"}
+}
+
+func (c *ClaudeAgent) DefaultArgs() []string {
+	return []string{"--dangerously-skip-permissions"}
+}
+
+func (c *ClaudeAgent) HeadlessArgs(prompt string, defaultArgs []string, structured bool) []string {
+	args := make([]string, 0, len(defaultArgs)+4)
+	args = append(args, defaultArgs...)
+	if structured {
+		args = append(args, "--output-format", "json")
+	}
+	return append(args, "-p", prompt)
+}
+
+// claudeResult is the result printed by "claude -p --output-format json".
+type claudeResult struct {
+	Subtype string `json:"subtype"`
+	IsError bool   `json:"is_error"`
+	Result  string `json:"result"`
+	Usage   *struct {
+		InputTokens              int64 `json:"input_tokens"`
+		CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+		OutputTokens             int64 `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func (c *ClaudeAgent) ParseHeadless(output []byte) (HeadlessResult, error) {
+	var r claudeResult
+	if err := json.Unmarshal(output, &r); err != nil {
+		return HeadlessResult{}, fmt.Errorf("parse claude output: %w", err)
+	}
+
+	result := HeadlessResult{Message: r.Result}
+	if r.Usage != nil {
+		result.Usage = &Usage{
+			InputTokens:  r.Usage.InputTokens + r.Usage.CacheCreationInputTokens + r.Usage.CacheReadInputTokens,
+			OutputTokens: r.Usage.OutputTokens,
+		}
+	}
+	if r.IsError {
+		// failed runs report the error as the result, or only in the subtype
+		result.Message = ""
+		result.Error = r.Result
+		if result.Error == "" {
+			result.Error = r.Subtype
+		}
+	}
+	return result, nil
+}
+
 func (c *ClaudeAgent) FetchLatestVersion(ctx context.Context) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, claudeBucketURL+"/latest", http.NoBody)
 	if err != nil {
@@ -4,6 +4,8 @@ import (
 	"archive/tar"
 	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -36,6 +38,79 @@ func (c *CodexAgent) BinaryName() string {
 	return "codex"
 }
 
+func (c *CodexAgent) Interpreter() []string {
+	return nil
+}
+
+func (c *CodexAgent) Domains() []string {
+	return []string{"api.openai.com", "auth.openai.com", "chatgpt.com"}
+}
+
+func (c *CodexAgent) DefaultArgs() []string {
+	return []string{"--full-auto"}
+}
+
+func (c *CodexAgent) HeadlessArgs(prompt string, defaultArgs []string, structured bool) []string {
+	// flags must follow the exec subcommand
+	args := make([]string, 0, len(defaultArgs)+3)
+	args = append(args, "exec")
+	args = append(args, defaultArgs...)
+	if structured {
+		args = append(args, "--json")
+	}
+	return append(args, prompt)
+}
+
+// codexEvent is a line of "codex exec --json" output.
+type codexEvent struct {
+	Type string `json:"type"`
+	Item struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"item"`
+	Usage struct {
+		InputTokens  int64 `json:"input_tokens"`
+		OutputTokens int64 `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Message string `json:"message"`
+}
+
+func (c *CodexAgent) ParseHeadless(output []byte) (HeadlessResult, error) {
+	var result HeadlessResult
+	events := 0
+	for line := range strings.Lines(string(output)) {
+		var event codexEvent
+		if json.Unmarshal([]byte(line), &event) != nil || event.Type == "" {
+			continue
+		}
+		events++
+
+		switch event.Type {
+		case "item.completed":
+			if event.Item.Type == "agent_message" {
+				result.Message = event.Item.Text
+			}
+		case "turn.completed":
+			if result.Usage == nil {
+				result.Usage = &Usage{}
+			}
+			result.Usage.InputTokens += event.Usage.InputTokens
+			result.Usage.OutputTokens += event.Usage.OutputTokens
+		case "turn.failed":
+			result.Error = event.Error.Message
+		case "error":
+			result.Error = event.Message
+		}
+	}
+	if events == 0 {
+		return HeadlessResult{}, errors.New("parse codex output: no events")
+	}
+	return result, nil
+}
+
 func (c *CodexAgent) FetchLatestVersion(ctx context.Context) (string, error) {
 	tag, err := FetchLatestGitHubTag(ctx, "openai", "codex")
 	if err != nil {
@@ -37,6 +37,30 @@ func (c *CopilotAgent) BinaryName() string {
 	return "copilot"
 }
 
+func (c *CopilotAgent) Interpreter() []string {
+	return nil
+}
+
+func (c *CopilotAgent) Domains() []string {
+	return []string{"api.github.com", "github.com", ".githubcopilot.com", "copilot-proxy.githubusercontent.com"}
+}
+
+func (c *CopilotAgent) DefaultArgs() []string {
+	return []string{"--allow-all-paths", "--allow-all-tools"}
+}
+
+// HeadlessArgs ignores structured, the copilot CLI has no machine-readable output.
+func (c *CopilotAgent) HeadlessArgs(prompt string, defaultArgs []string, structured bool) []string {
+	args := make([]string, 0, len(defaultArgs)+2)
+	args = append(args, defaultArgs...)
+	return append(args, "-p", prompt)
+}
+
+// ParseHeadless returns the plain output as the message, without usage.
+func (c *CopilotAgent) ParseHeadless(output []byte) (HeadlessResult, error) {
+	return HeadlessResult{Message: strings.TrimSpace(string(output))}, nil
+}
+
 func (c *CopilotAgent) FetchLatestVersion(ctx context.Context) (string, error) {
 	tag, err := FetchLatestGitHubTag(ctx, "github", "copilot-cli")
 	if err != nil {
@@ -2,6 +2,7 @@ package agents
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -28,6 +29,64 @@ func (g *GeminiAgent) BinaryName() string {
 	return "gemini.js"
 }
 
+func (g *GeminiAgent) Interpreter() []string {
+	return []string{"mise", "exec", "node", "--", "node"}
+}
+
+func (g *GeminiAgent) Domains() []string {
+	return []string{"generativelanguage.googleapis.com", "cloudcode-pa.googleapis.com", "oauth2.googleapis.com", "accounts.google.com", "www.googleapis.com"}
+}
+
+func (g *GeminiAgent) DefaultArgs() []string {
+	return []string{"--yolo"}
+}
+
+func (g *GeminiAgent) HeadlessArgs(prompt string, defaultArgs []string, structured bool) []string {
+	args := make([]string, 0, len(defaultArgs)+4)
+	args = append(args, defaultArgs...)
+	if structured {
+		args = append(args, "--output-format", "json")
+	}
+	return append(args, "-p", prompt)
+}
+
+// geminiResult is the result printed by "gemini -p --output-format json".
+type geminiResult struct {
+	Response string `json:"response"`
+	Stats    *struct {
+		Models map[string]struct {
+			Tokens struct {
+				Prompt     int64 `json:"prompt"`
+				Candidates int64 `json:"candidates"`
+			} `json:"tokens"`
+		} `json:"models"`
+	} `json:"stats"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (g *GeminiAgent) ParseHeadless(output []byte) (HeadlessResult, error) {
+	var r geminiResult
+	if err := json.Unmarshal(output, &r); err != nil {
+		return HeadlessResult{}, fmt.Errorf("parse gemini output: %w", err)
+	}
+
+	result := HeadlessResult{Message: r.Response}
+	if r.Stats != nil {
+		// a run may use several models, e.g. one for routing
+		result.Usage = &Usage{}
+		for _, model := range r.Stats.Models {
+			result.Usage.InputTokens += model.Tokens.Prompt
+			result.Usage.OutputTokens += model.Tokens.Candidates
+		}
+	}
+	if r.Error != nil {
+		result.Error = r.Error.Message
+	}
+	return result, nil
+}
+
 func (g *GeminiAgent) FetchLatestVersion(ctx context.Context) (string, error) {
 	tag, err := FetchLatestGitHubTag(ctx, "google-gemini", "gemini-cli")
 	if err != nil {
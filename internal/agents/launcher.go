@@ -0,0 +1,131 @@
+package agents
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ContainerBinDir is where agent binaries are mounted inside the container.
+	ContainerBinDir = "/opt/agentbox/bin"
+	// ContainerLaunchersDir is where launchers are mounted inside the container.
+	ContainerLaunchersDir = "/opt/agentbox/launchers"
+	// SafeEnvVar starts agents without their default arguments when set to 1.
+	SafeEnvVar = "AGENTBOX_SAFE"
+)
+
+// ArgsEnvVar returns the environment variable that replaces an agent's default arguments.
+// The value is a shell-quoted argument list, see ShellJoin.
+func ArgsEnvVar(name string) string {
+	return "AGENTBOX_ARGS_" + strings.ToUpper(name)
+}
+
+// LauncherPath returns the launcher location inside the container.
+func LauncherPath(name string) string {
+	return ContainerLaunchersDir + "/" + name
+}
+
+// Launcher renders the wrapper script that starts an agent inside the container.
+// Unlike shell aliases, the wrapper works in scripts, under `bash -c` and in any shell.
+func Launcher(agent Agent) string {
+	name := agent.Name()
+	envVar := ArgsEnvVar(name)
+
+	command := make([]string, 0, len(agent.Interpreter())+1)
+	for _, part := range agent.Interpreter() {
+		command = append(command, shellQuote(part))
+	}
+	command = append(command, fmt.Sprintf(`"$agent_dir/$version/%s"`, agent.BinaryName()))
+
+	var b strings.Builder
+	b.WriteString("#!/bin/bash\n")
+	b.WriteString("# Generated by agentbox, do not edit.\n")
+	fmt.Fprintf(&b, "# Default arguments can be replaced with %s, %s=1 disables them.\n", envVar, SafeEnvVar)
+	fmt.Fprintf(&b, "agent_dir=%s/%s\n", ContainerBinDir, name)
+	b.WriteString(`version=$(cat "$agent_dir/current") || exit 1` + "\n")
+	fmt.Fprintf(&b, "default_args=(%s)\n", ShellJoin(agent.DefaultArgs()))
+	fmt.Fprintf(&b, "if [[ -n \"${%s+x}\" ]]; then\n", envVar)
+	fmt.Fprintf(&b, "    eval \"default_args=($%s)\"\n", envVar)
+	b.WriteString("fi\n")
+	fmt.Fprintf(&b, "if [[ \"${%s:-}\" == \"1\" ]]; then\n", SafeEnvVar)
+	b.WriteString("    default_args=()\n")
+	b.WriteString("fi\n")
+	fmt.Fprintf(&b, "exec %s \"${default_args[@]}\" \"$@\"\n", strings.Join(command, " "))
+	return b.String()
+}
+
+// RunningAgents returns the agents, in AllAgentNames order, whose binaries
+// appear in the command lines of processes in a container.
+func RunningAgents(commands []string) []string {
+	var running []string
+	for _, name := range AllAgentNames() {
+		binDir := ContainerBinDir + "/" + name + "/"
+		for _, command := range commands {
+			if strings.Contains(command, binDir) {
+				running = append(running, name)
+				break
+			}
+		}
+	}
+	return running
+}
+
+// WriteLaunchers generates launchers for all agents into dir.
+// Files are rewritten only when their content changes.
+func (m *Manager) WriteLaunchers(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create launchers dir: %w", err)
+	}
+
+	for _, agent := range m.AllAgents() {
+		path := filepath.Join(dir, agent.Name())
+		content := []byte(Launcher(agent))
+
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+			continue
+		}
+
+		if err := os.WriteFile(path, content, 0o755); err != nil {
+			return fmt.Errorf("write launcher %s: %w", agent.Name(), err)
+		}
+		// WriteFile keeps the mode of an existing file
+		if err := os.Chmod(path, 0o755); err != nil {
+			return fmt.Errorf("chmod launcher %s: %w", agent.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// ShellJoin quotes args so that bash parses them back into the same list.
+func ShellJoin(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.IndexFunc(s, needsQuoting) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func needsQuoting(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	case strings.ContainsRune("-_./=:,@%+", r):
+		return false
+	default:
+		return true
+	}
+}
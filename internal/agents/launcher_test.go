@@ -0,0 +1,254 @@
+package agents
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aleksey925/agentbox/internal/config"
+)
+
+func TestShellJoin(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"empty", nil, ""},
+		{"plain flags", []string{"--allow-all-paths", "--allow-all-tools"}, "--allow-all-paths --allow-all-tools"},
+		{"spaces", []string{"--append-system-prompt", "be brief"}, "--append-system-prompt 'be brief'"},
+		{"single quote", []string{"it's"}, `'it'\''s'`},
+		{"empty arg", []string{""}, "''"},
+		{"shell chars", []string{"$HOME;rm"}, "'$HOME;rm'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result := ShellJoin(tt.args)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("ShellJoin(%q) = %s, want %s", tt.args, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestArgsEnvVar(t *testing.T) {
+	// act
+	result := ArgsEnvVar("claude")
+
+	// assert
+	if result != "AGENTBOX_ARGS_CLAUDE" {
+		t.Errorf("ArgsEnvVar = %s, want AGENTBOX_ARGS_CLAUDE", result)
+	}
+}
+
+func TestLauncher__native_binary(t *testing.T) {
+	// arrange
+	agent := &ClaudeAgent{arch: "x64"}
+
+	// act
+	script := Launcher(agent)
+
+	// assert
+	expectedSubstrings := []string{
+		"#!/bin/bash\n",
+		"agent_dir=/opt/agentbox/bin/claude\n",
+		"default_args=(--dangerously-skip-permissions)\n",
+		`eval "default_args=($AGENTBOX_ARGS_CLAUDE)"`,
+		`if [[ "${AGENTBOX_SAFE:-}" == "1" ]]; then`,
+		`exec "$agent_dir/$version/claude" "${default_args[@]}" "$@"`,
+	}
+	for _, expected := range expectedSubstrings {
+		if !strings.Contains(script, expected) {
+			t.Errorf("launcher missing %q, got:\n%s", expected, script)
+		}
+	}
+}
+
+func TestLauncher__interpreter(t *testing.T) {
+	// arrange
+	agent := NewGeminiAgent()
+
+	// act
+	script := Launcher(agent)
+
+	// assert
+	expected := `exec mise exec node -- node "$agent_dir/$version/gemini.js" "${default_args[@]}" "$@"`
+	if !strings.Contains(script, expected) {
+		t.Errorf("launcher missing %q, got:\n%s", expected, script)
+	}
+}
+
+func TestRunningAgents(t *testing.T) {
+	// arrange
+	commands := []string{
+		"/bin/bash",
+		"/opt/agentbox/bin/codex/0.46.0/codex --full-auto",
+		"node /opt/agentbox/bin/gemini/0.9.0/gemini.js --yolo",
+		"node /opt/agentbox/bin/gemini/0.9.0/worker.js",
+		"vim /opt/agentbox/launchers/claude",
+	}
+
+	// act
+	result := RunningAgents(commands)
+
+	// assert
+	expected := []string{"codex", "gemini"}
+	if !slices.Equal(result, expected) {
+		t.Errorf("RunningAgents = %v, want %v", result, expected)
+	}
+}
+
+func TestManager_WriteLaunchers(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	manager, err := NewManager(&config.Paths{BinDir: tmpDir})
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	launchersDir := filepath.Join(tmpDir, "launchers")
+
+	// act
+	err = manager.WriteLaunchers(launchersDir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("WriteLaunchers error: %v", err)
+	}
+
+	for _, name := range AllAgentNames() {
+		info, err := os.Stat(filepath.Join(launchersDir, name))
+		if err != nil {
+			t.Errorf("launcher %s not written: %v", name, err)
+			continue
+		}
+		if info.Mode().Perm()&0o111 == 0 {
+			t.Errorf("launcher %s is not executable: %v", name, info.Mode())
+		}
+	}
+}
+
+func TestHeadlessArgs(t *testing.T) {
+	tests := []struct {
+		agent      Agent
+		structured bool
+		expected   []string
+	}{
+		{&ClaudeAgent{}, false, []string{"--dangerously-skip-permissions", "-p", "hello"}},
+		{&ClaudeAgent{}, true, []string{"--dangerously-skip-permissions", "--output-format", "json", "-p", "hello"}},
+		{&CopilotAgent{}, false, []string{"--allow-all-paths", "--allow-all-tools", "-p", "hello"}},
+		{&CopilotAgent{}, true, []string{"--allow-all-paths", "--allow-all-tools", "-p", "hello"}},
+		{&CodexAgent{}, false, []string{"exec", "--full-auto", "hello"}},
+		{&CodexAgent{}, true, []string{"exec", "--full-auto", "--json", "hello"}},
+		{NewGeminiAgent(), false, []string{"--yolo", "-p", "hello"}},
+		{NewGeminiAgent(), true, []string{"--yolo", "--output-format", "json", "-p", "hello"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s structured=%v", tt.agent.Name(), tt.structured), func(t *testing.T) {
+			// act
+			result := tt.agent.HeadlessArgs("hello", tt.agent.DefaultArgs(), tt.structured)
+
+			// assert
+			if !slices.Equal(result, tt.expected) {
+				t.Errorf("HeadlessArgs = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseHeadless(t *testing.T) {
+	tests := []struct {
+		name     string
+		agent    Agent
+		output   string
+		expected HeadlessResult
+	}{
+		{
+			name:     "claude",
+			agent:    &ClaudeAgent{},
+			output:   `{"type":"result","subtype":"success","is_error":false,"result":"done","usage":{"input_tokens":10,"cache_read_input_tokens":90,"output_tokens":5}}`,
+			expected: HeadlessResult{Message: "done", Usage: &Usage{InputTokens: 100, OutputTokens: 5}},
+		},
+		{
+			name:     "claude error",
+			agent:    &ClaudeAgent{},
+			output:   `{"type":"result","subtype":"error_max_turns","is_error":true}`,
+			expected: HeadlessResult{Error: "error_max_turns"},
+		},
+		{
+			name:  "codex",
+			agent: &CodexAgent{},
+			output: `{"type":"thread.started","thread_id":"t1"}
+{"type":"item.completed","item":{"type":"reasoning","text":"thinking"}}
+{"type":"item.completed","item":{"type":"agent_message","text":"first"}}
+{"type":"turn.completed","usage":{"input_tokens":20,"output_tokens":3}}
+{"type":"item.completed","item":{"type":"agent_message","text":"done"}}
+{"type":"turn.completed","usage":{"input_tokens":30,"output_tokens":4}}
+`,
+			expected: HeadlessResult{Message: "done", Usage: &Usage{InputTokens: 50, OutputTokens: 7}},
+		},
+		{
+			name:  "codex error",
+			agent: &CodexAgent{},
+			output: `warning: not a json line
+{"type":"turn.failed","error":{"message":"quota exceeded"}}
+`,
+			expected: HeadlessResult{Error: "quota exceeded"},
+		},
+		{
+			name:     "gemini",
+			agent:    NewGeminiAgent(),
+			output:   `{"response":"done","stats":{"models":{"gemini-2.5-pro":{"tokens":{"prompt":40,"candidates":6}},"gemini-2.5-flash":{"tokens":{"prompt":2,"candidates":1}}}}}`,
+			expected: HeadlessResult{Message: "done", Usage: &Usage{InputTokens: 42, OutputTokens: 7}},
+		},
+		{
+			name:     "gemini error",
+			agent:    NewGeminiAgent(),
+			output:   `{"error":{"type":"Error","message":"invalid key","code":1}}`,
+			expected: HeadlessResult{Error: "invalid key"},
+		},
+		{
+			name:     "copilot",
+			agent:    &CopilotAgent{},
+			output:   "done\n",
+			expected: HeadlessResult{Message: "done"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result, err := tt.agent.ParseHeadless([]byte(tt.output))
+
+			// assert
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseHeadless = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseHeadless__invalid_output(t *testing.T) {
+	for _, agent := range []Agent{&ClaudeAgent{}, &CodexAgent{}, NewGeminiAgent()} {
+		t.Run(agent.Name(), func(t *testing.T) {
+			// act
+			_, err := agent.ParseHeadless([]byte("Error: not logged in\n"))
+
+			// assert
+			if err == nil {
+				t.Error("expected an error for output that is not JSON")
+			}
+		})
+	}
+}
@@ -13,8 +13,6 @@ import (
 	"github.com/vbauerster/mpb/v8/decor"
 )
 
-const maxVersionsToKeep = 5
-
 type Manager struct {
 	paths  *config.Paths
 	agents map[string]Agent
@@ -77,8 +75,8 @@ type AgentStatus struct {
 	Error     error
 }
 
-func (m *Manager) GetStatus() []AgentStatus {
-	ctx := context.Background()
+// GetStatus returns the installed and the latest version of each agent.
+func (m *Manager) GetStatus(timeouts config.HTTPSettings) []AgentStatus {
 	var wg sync.WaitGroup
 	results := make([]AgentStatus, len(m.agents))
 	names := AllAgentNames()
@@ -94,7 +92,7 @@ func (m *Manager) GetStatus() []AgentStatus {
 			_, installed, _ := m.ListVersions(agentName)
 			status.Installed = installed
 
-			latest, err := agent.FetchLatestVersion(ctx)
+			latest, err := fetchLatestVersion(agent, timeouts)
 			if err != nil {
 				status.Error = err
 			} else {
@@ -110,14 +108,13 @@ func (m *Manager) GetStatus() []AgentStatus {
 	return results
 }
 
-func (m *Manager) Install(name string, onProgress func(agent string, downloaded, total int64)) error {
-	ctx := context.Background()
+func (m *Manager) Install(name string, timeouts config.HTTPSettings, onProgress func(agent string, downloaded, total int64)) error {
 	agent, ok := m.agents[name]
 	if !ok {
 		return fmt.Errorf("unknown agent: %s", name)
 	}
 
-	version, err := agent.FetchLatestVersion(ctx)
+	version, err := fetchLatestVersion(agent, timeouts)
 	if err != nil {
 		return fmt.Errorf("fetch latest version: %w", err)
 	}
@@ -137,7 +134,7 @@ func (m *Manager) Install(name string, onProgress func(agent string, downloaded,
 		}
 	}
 
-	if err := agent.Download(ctx, version, destDir, progress); err != nil {
+	if err := download(agent, timeouts, version, destDir, progress); err != nil {
 		os.RemoveAll(destDir)
 		return fmt.Errorf("download: %w", err)
 	}
@@ -149,8 +146,7 @@ func (m *Manager) Install(name string, onProgress func(agent string, downloaded,
 	return nil
 }
 
-func (m *Manager) Update(names []string) ([]DownloadResult, error) {
-	ctx := context.Background()
+func (m *Manager) Update(names []string, timeouts config.HTTPSettings) ([]DownloadResult, error) {
 	if len(names) == 0 {
 		names = AllAgentNames()
 	}
@@ -175,7 +171,7 @@ func (m *Manager) Update(names []string) ([]DownloadResult, error) {
 				return
 			}
 
-			version, err := agent.FetchLatestVersion(ctx)
+			version, err := fetchLatestVersion(agent, timeouts)
 			if err != nil {
 				results[idx] = DownloadResult{
 					Agent: agentName,
@@ -221,7 +217,7 @@ func (m *Manager) Update(names []string) ([]DownloadResult, error) {
 				}
 			}
 
-			if err := agent.Download(ctx, version, destDir, progress); err != nil {
+			if err := download(agent, timeouts, version, destDir, progress); err != nil {
 				bar.Abort(true)
 				os.RemoveAll(destDir)
 				results[idx] = DownloadResult{
@@ -249,6 +245,21 @@ func (m *Manager) Update(names []string) ([]DownloadResult, error) {
 	return results, nil
 }
 
+// fetchLatestVersion asks the release server for the latest version of agent,
+// limited by the request timeout.
+func fetchLatestVersion(agent Agent, timeouts config.HTTPSettings) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.TimeoutDuration())
+	defer cancel()
+	return agent.FetchLatestVersion(ctx)
+}
+
+// download downloads a version of agent to destDir, limited by the download timeout.
+func download(agent Agent, timeouts config.HTTPSettings, version, destDir string, progress func(downloaded, total int64)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.DownloadTimeoutDuration())
+	defer cancel()
+	return agent.Download(ctx, version, destDir, progress)
+}
+
 func (m *Manager) applyResults(results []DownloadResult) {
 	for i := range results {
 		if results[i].Error == nil && results[i].Version != "" {
@@ -286,7 +297,8 @@ func (m *Manager) switchVersion(name, version string) error {
 	return nil
 }
 
-func (m *Manager) Cleanup(name string) (int, error) {
+// Cleanup removes the installed versions of the agent except the newest keep ones.
+func (m *Manager) Cleanup(name string, keep int) (int, error) {
 	agentDir := m.paths.AgentDir(name)
 
 	entries, err := os.ReadDir(agentDir)
@@ -307,15 +319,15 @@ func (m *Manager) Cleanup(name string) (int, error) {
 		}
 	}
 
-	if len(versions) <= maxVersionsToKeep {
+	if len(versions) <= keep {
 		return 0, nil
 	}
 
 	sort.Slice(versions, func(i, j int) bool {
-		return compareVersions(versions[i], versions[j]) > 0
+		return CompareVersions(versions[i], versions[j]) > 0
 	})
 
-	toRemove := versions[maxVersionsToKeep:]
+	toRemove := versions[keep:]
 	removed := 0
 
 	for _, v := range toRemove {
@@ -358,13 +370,14 @@ func (m *Manager) ListVersions(name string) ([]string, string, error) {
 	}
 
 	sort.Slice(versions, func(i, j int) bool {
-		return compareVersions(versions[i], versions[j]) > 0
+		return CompareVersions(versions[i], versions[j]) > 0
 	})
 
 	return versions, current, nil
 }
 
-func compareVersions(a, b string) int {
+// CompareVersions compares dotted numeric versions, returning -1, 0 or 1.
+func CompareVersions(a, b string) int {
 	partsA := strings.Split(a, ".")
 	partsB := strings.Split(b, ".")
 
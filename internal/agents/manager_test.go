@@ -1,7 +1,10 @@
 package agents
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/aleksey925/agentbox/internal/config"
 )
@@ -26,11 +29,11 @@ func TestCompareVersions(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.a+"_vs_"+tt.b, func(t *testing.T) {
 			// act
-			result := compareVersions(tt.a, tt.b)
+			result := CompareVersions(tt.a, tt.b)
 
 			// assert
 			if result != tt.expected {
-				t.Errorf("compareVersions(%s, %s) = %d, want %d", tt.a, tt.b, result, tt.expected)
+				t.Errorf("CompareVersions(%s, %s) = %d, want %d", tt.a, tt.b, result, tt.expected)
 			}
 		})
 	}
@@ -195,3 +198,42 @@ func TestManager_AllAgents(t *testing.T) {
 		}
 	}
 }
+
+// deadlineAgent reports the time left until the deadline of its requests.
+type deadlineAgent struct {
+	Agent
+	left time.Duration
+}
+
+func (a *deadlineAgent) FetchLatestVersion(ctx context.Context) (string, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return "", errors.New("no deadline")
+	}
+	a.left = time.Until(deadline)
+	return "1.0.0", nil
+}
+
+func (a *deadlineAgent) Download(ctx context.Context, version, destDir string, progress func(downloaded, total int64)) error {
+	_, err := a.FetchLatestVersion(ctx)
+	return err
+}
+
+func TestManager__timeouts(t *testing.T) {
+	// arrange
+	agent := &deadlineAgent{}
+	timeouts := config.HTTPSettings{Timeout: "10s", DownloadTimeout: "1h"}
+
+	// act
+	_, fetchErr := fetchLatestVersion(agent, timeouts)
+	fetchLeft := agent.left
+	downloadErr := download(agent, timeouts, "1.0.0", t.TempDir(), nil)
+
+	// assert
+	if fetchErr != nil || fetchLeft <= 0 || fetchLeft > 10*time.Second {
+		t.Errorf("fetch deadline in %s, error %v, want within 10s", fetchLeft, fetchErr)
+	}
+	if downloadErr != nil || agent.left <= 10*time.Second || agent.left > time.Hour {
+		t.Errorf("download deadline in %s, error %v, want within 1h", agent.left, downloadErr)
+	}
+}
@@ -8,21 +8,19 @@ import (
 	"net/http"
 	"runtime"
 	"strings"
-	"time"
 )
 
 const userAgent = "agentbox/1.0"
 
-var httpClient = &http.Client{
-	Timeout: 5 * time.Minute,
-}
+// httpClient makes the requests to release servers, they are limited by the
+// context the Manager passes.
+var httpClient = &http.Client{}
 
 // FetchLatestGitHubTag gets latest release tag via redirect (bypasses API rate limit)
 func FetchLatestGitHubTag(ctx context.Context, owner, repo string) (string, error) {
 	url := "https://github.com/" + owner + "/" + repo + "/releases/latest"
 
 	client := &http.Client{
-		Timeout: 5 * time.Minute,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse // don't follow redirects
 		},
@@ -64,6 +62,33 @@ type Agent interface {
 	FetchLatestVersion(ctx context.Context) (string, error)
 	Download(ctx context.Context, version, destDir string, progress func(downloaded, total int64)) error
 	BinaryName() string
+	// Interpreter returns the command prefix used to run the binary, or nil for native executables.
+	Interpreter() []string
+	// Domains returns the hosts the agent needs to reach. A leading dot matches subdomains.
+	Domains() []string
+	// DefaultArgs returns the permissive flags the launcher passes by default.
+	DefaultArgs() []string
+	// HeadlessArgs returns the arguments that run a single prompt non-interactively.
+	// With structured set the agent prints machine-readable output for ParseHeadless.
+	HeadlessArgs(prompt string, defaultArgs []string, structured bool) []string
+	// ParseHeadless extracts the result from the structured output of a headless run.
+	ParseHeadless(output []byte) (HeadlessResult, error)
+}
+
+// HeadlessResult is the outcome of a headless run, common to all agents.
+type HeadlessResult struct {
+	// Message is the final answer of the agent.
+	Message string
+	// Usage is nil if the agent does not report token usage.
+	Usage *Usage
+	// Error is the failure reported by the agent.
+	Error string
+}
+
+// Usage counts the tokens of a headless run.
+type Usage struct {
+	InputTokens  int64
+	OutputTokens int64
 }
 
 type DownloadResult struct {
@@ -3,8 +3,13 @@ package cli
 import (
 	"fmt"
 	"os"
+
+	"github.com/aleksey925/agentbox/internal/docker"
 )
 
+// RuntimeEnv selects the container runtime when --runtime is not given.
+const RuntimeEnv = "AGENTBOX_RUNTIME"
+
 type App struct {
 	Version string
 }
@@ -12,6 +17,18 @@ type App struct {
 func Run(args []string, version string) int {
 	app := &App{Version: version}
 
+	runtime, args, err := extractRuntimeFlag(args)
+	if err == nil {
+		if runtime == "" {
+			runtime = os.Getenv(RuntimeEnv)
+		}
+		err = docker.SelectRuntime(runtime)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
 	if len(args) == 0 {
 		app.printHelp()
 		return 0
@@ -23,18 +40,42 @@ func Run(args []string, version string) int {
 	switch cmd {
 	case "init":
 		return app.cmdInit(cmdArgs)
+	case "upgrade":
+		return app.cmdUpgrade(cmdArgs)
 	case "run":
 		return app.cmdRun(cmdArgs)
+	case "exec":
+		return app.cmdExec(cmdArgs)
 	case "attach":
 		return app.cmdAttach(cmdArgs)
 	case "ps":
 		return app.cmdPs(cmdArgs)
+	case "stop":
+		return app.cmdStop(cmdArgs)
+	case "kill":
+		return app.cmdKill(cmdArgs)
+	case "restart":
+		return app.cmdRestart(cmdArgs)
+	case "logs":
+		return app.cmdLogs(cmdArgs)
+	case "worktree":
+		return app.cmdWorktree(cmdArgs)
+	case "checkpoints":
+		return app.cmdCheckpoints(cmdArgs)
+	case "sessions":
+		return app.cmdSessions(cmdArgs)
+	case "image":
+		return app.cmdImage(cmdArgs)
 	case "agent":
 		return app.cmdAgent(cmdArgs)
 	case "self":
 		return app.cmdSelf(cmdArgs)
+	case "config":
+		return app.cmdConfig(cmdArgs)
 	case "clean":
 		return app.cmdClean(cmdArgs)
+	case "doctor":
+		return app.cmdDoctor(cmdArgs)
 	case "completion":
 		return app.cmdCompletion(cmdArgs)
 	case "help", "-h", "--help":
@@ -58,18 +99,56 @@ Usage:
 
 Commands:
   init                              Initialize sandbox in current directory
+  upgrade                           Upgrade project files to this agentbox version
   run                               Start a new container
+  exec                              Run an agent non-interactively (for CI)
   attach                            Attach to running container
   ps                                List running agentbox containers
+  stop                              Stop running containers
+  kill                              Kill running containers
+  restart                           Restart a running container
+  logs                              Show the output of a container
+  worktree                          Manage git worktrees of sandboxes
+  checkpoints                       List and restore git checkpoints of sessions
+  sessions                          List and replay recorded terminal sessions
+  image                             Manage the shared base image
   agent                             Manage AI agents
   self                              Update or uninstall agentbox
+  config                            View and edit settings
   clean                             Remove sandbox files from project
+  doctor                            Check the environment for common problems
   completion                        Generate shell completion script
 
 Global Flags:
+  --runtime <name>                  Container runtime: auto, docker, podman (default: auto,
+                                    or $AGENTBOX_RUNTIME)
   -h, --help                        Show help
   -v, --version                     Show version
 
 Use "agentbox <command> --help" for more information about a command.
 `, a.Version)
 }
+
+// extractRuntimeFlag removes the global --runtime flag from args, wherever it
+// is given before a "--" separator, and returns its value.
+func extractRuntimeFlag(args []string) (string, []string, error) {
+	runtime := ""
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if args[i] != "--runtime" {
+			rest = append(rest, args[i])
+			continue
+		}
+		value, err := flagValue(args, i)
+		if err != nil {
+			return "", nil, err
+		}
+		runtime = value
+		i++
+	}
+	return runtime, rest, nil
+}
@@ -12,12 +12,16 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aleksey925/agentbox/internal/agents"
 	"github.com/aleksey925/agentbox/internal/config"
+	"github.com/aleksey925/agentbox/internal/diff"
 	"github.com/aleksey925/agentbox/internal/docker"
 	"github.com/aleksey925/agentbox/internal/skeleton"
 )
@@ -48,6 +52,15 @@ Files created:
   - docker-compose.agentbox.yml
   - docker-compose.agentbox.local.yml
   - mise.toml (if not exists)
+
+Generated files carry a header with the agentbox version and a hash of their
+content. Files unchanged since generation are overwritten; for files edited by
+hand, init shows a diff and asks whether to keep, overwrite or back them up.
+
+The files are rendered from templates in ~/.agentbox/templates/, then from the
+team directory set in [templates] dir, then from the built-in ones. Templates use
+Go text/template syntax with .ProjectName, .UID, .GID, .Agents, .Packages and
+.Default, the file they override.
 `)
 		return 0
 	}
@@ -77,11 +90,7 @@ func (a *App) doInit(interactive bool) int {
 		return 1
 	}
 
-	if interactive && !a.confirmOverwrite(cwd) {
-		return 1
-	}
-
-	if code := a.copySkeletonFiles(cwd); code != 0 {
+	if code := a.copySkeletonFiles(cwd, interactive); code != 0 {
 		return code
 	}
 
@@ -92,6 +101,10 @@ func (a *App) doInit(interactive bool) int {
 		return code
 	}
 
+	if code := a.writeLaunchers(paths); code != 0 {
+		return code
+	}
+
 	if err := ensureAgentConfigs(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating agent configs: %v\n", err)
 		return 1
@@ -103,45 +116,32 @@ func (a *App) doInit(interactive bool) int {
 	return 0
 }
 
-func (a *App) confirmOverwrite(cwd string) bool {
-	var existing []string
-	for _, name := range skeleton.OverwriteFiles() {
-		path := filepath.Join(cwd, name)
-		if _, err := os.Stat(path); err == nil {
-			existing = append(existing, name)
-		}
-	}
+func (a *App) copySkeletonFiles(cwd string, interactive bool) int {
+	fmt.Println("Initializing agentbox...")
 
-	if len(existing) == 0 {
-		return true
+	renderer, vars, err := a.skeletonRenderer(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
 	}
 
-	fmt.Printf("Warning: files already exist: %s\n", strings.Join(existing, ", "))
-	fmt.Print("Overwrite? [y/N] ")
-
-	reader := bufio.NewReader(os.Stdin)
-	answer, _ := reader.ReadString('\n')
-	answer = strings.TrimSpace(strings.ToLower(answer))
-
-	if answer != "y" && answer != "yes" {
-		fmt.Println("Aborted")
-		return false
+	results, err := skeleton.CopyTo(cwd, renderer, vars, editedFileResolver(os.Stdin, os.Stdout, interactive))
+	for _, r := range results {
+		switch r.Status {
+		case skeleton.Kept:
+			fmt.Printf("  Kept: %s (edited by hand)\n", r.Name)
+		case skeleton.BackedUp:
+			fmt.Printf("  Updated: %s (your version saved to %s%s)\n", r.Name, r.Name, skeleton.BackupSuffix)
+		default:
+			fmt.Printf("  %s: %s\n", r.Status, r.Name)
+		}
 	}
-	return true
-}
-
-func (a *App) copySkeletonFiles(cwd string) int {
-	fmt.Println("Initializing agentbox...")
-
-	if err := skeleton.CopyTo(cwd); err != nil {
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error copying skeleton files: %v\n", err)
 		return 1
 	}
-	for _, name := range skeleton.OverwriteFiles() {
-		fmt.Printf("  Created: %s\n", name)
-	}
 
-	createdUserFiles, err := skeleton.CopyUserFilesIfMissing(cwd)
+	createdUserFiles, err := skeleton.CopyUserFilesIfMissing(cwd, renderer, vars)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error copying user files: %v\n", err)
 		return 1
@@ -153,6 +153,62 @@ func (a *App) copySkeletonFiles(cwd string) int {
 	return 0
 }
 
+// editedFileResolver shows how a hand-edited generated file differs from the
+// new one and asks whether to keep it, overwrite it or back it up first.
+// Without interaction, edited files are backed up, and at the end of input kept.
+func editedFileResolver(in io.Reader, out io.Writer, interactive bool) skeleton.Resolver {
+	reader := bufio.NewReader(in)
+	return func(name string, current, generated []byte) (skeleton.Action, error) {
+		if !interactive {
+			fmt.Fprintf(out, "Warning: %s was edited by hand, saving it to %s%s\n", name, name, skeleton.BackupSuffix)
+			return skeleton.Backup, nil
+		}
+
+		fmt.Fprintf(out, "\n%s was edited by hand since it was generated. Changes init would make:\n", name)
+		fmt.Fprint(out, diff.Unified("a/"+name, "b/"+name, current, generated))
+		for {
+			fmt.Fprintf(out, "Overwrite %s? [k]eep yours, [o]verwrite, [b]ackup to %s%s and overwrite: ", name, name, skeleton.BackupSuffix)
+			answer, err := readAnswer(reader)
+			if err != nil {
+				fmt.Fprintln(out)
+				return skeleton.Keep, nil
+			}
+			switch answer {
+			case "k", "keep":
+				return skeleton.Keep, nil
+			case "o", "overwrite":
+				return skeleton.Overwrite, nil
+			case "b", "backup":
+				return skeleton.Backup, nil
+			}
+		}
+	}
+}
+
+// skeletonRenderer returns the renderer of the skeleton files, with the user and
+// team templates, and the template variables of the project.
+func (a *App) skeletonRenderer(projectDir string) (*skeleton.Renderer, skeleton.Vars, error) {
+	paths, err := config.NewPaths()
+	if err != nil {
+		return nil, skeleton.Vars{}, fmt.Errorf("get paths: %w", err)
+	}
+
+	settings, err := config.LoadSettings(paths, projectDir)
+	if err != nil {
+		return nil, skeleton.Vars{}, fmt.Errorf("load settings: %w", err)
+	}
+
+	vars := skeleton.Vars{
+		Version:     a.Version,
+		ProjectName: filepath.Base(projectDir),
+		UID:         os.Getuid(),
+		GID:         os.Getgid(),
+		Agents:      agents.AllAgentNames(),
+		Packages:    settings.Templates.Packages,
+	}
+	return skeleton.NewRenderer(settings.TemplateDirs(paths, projectDir)...), vars, nil
+}
+
 func (a *App) setupGitExclude(cwd string) {
 	added, err := addToGitExcludeVerbose(cwd)
 	if err != nil {
@@ -176,11 +232,30 @@ func (a *App) createMiseToml(cwd string) {
 }
 
 type runOptions struct {
-	build   bool
-	noCache bool
+	build    bool
+	noCache  bool
+	safe     bool
+	name     string
+	worktree string
+	egress   string
+	review   bool
+	record   bool
+	// resources are limits from the flags, unset ones come from settings
+	resources docker.Resources
+	// noAutoBuild keeps an image that does not match its build inputs
+	noAutoBuild bool
+	// ports are published in addition to sandbox.ports
+	ports       []string
+	hostGateway bool
+	// forwardGit forwards the SSH agent and git identity in addition to git.forward
+	forwardGit bool
 }
 
-var runAllowedFlags = []string{"--build", "--build-no-cache"}
+var runAllowedFlags = []string{
+	"--build", "--build-no-cache", "--no-auto-build", "--safe", "--name", "--worktree", "--egress", "--review", "--record",
+	"--cpus", "--memory", "--pids-limit", "-p", "--publish", "--host-gateway",
+	"--forward-git",
+}
 
 func (a *App) cmdRun(args []string) int {
 	if hasHelpFlag(args) {
@@ -192,6 +267,19 @@ Usage:
 Flags:
   --build                           Rebuild image before running
   --build-no-cache                  Rebuild image without Docker cache
+  --no-auto-build                   Do not rebuild the image when its build inputs change
+  --safe                            Start agents without their default permission flags
+  --name <session>                  Name the container, to use it instead of the ID from any directory
+  --worktree <name>                 Mount git worktree <name> as the project (created if missing)
+  --egress <mode>                   Network egress policy: open, allowlist (default: from config)
+  --review                          Work on a copy of the project and review changes before applying them
+  --record                          Record the terminal session (see 'agentbox sessions')
+  --cpus <n>                        Limit the number of CPUs, e.g. 2 or 1.5 (default: from config)
+  --memory <size>                   Limit memory, e.g. 512m or 4g (default: from config)
+  --pids-limit <n>                  Limit the number of processes (default: from config)
+  -p, --publish <port>              Publish a port, [[ip:]host:]container, e.g. 127.0.0.1:3000:3000 (repeatable)
+  --host-gateway                    Reach host services at host.docker.internal (default: from config)
+  --forward-git                     Forward the SSH agent and git user name and email (default: from config)
 `)
 		return 0
 	}
@@ -200,73 +288,282 @@ Flags:
 		return code
 	}
 
-	opts := a.parseRunFlags(args)
-
 	cwd, err := os.Getwd()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return 1
 	}
 
+	defaults, err := defaultRunFlags(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	opts, err := a.parseRunFlags(append(defaults, args...))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
 	if code := a.ensureProjectReady(cwd); code != 0 {
 		return code
 	}
+	a.warnOutdatedSkeleton(cwd)
 
-	if opts.build {
+	sb, err := a.prepareSandbox(cwd, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer sb.cleanup()
+
+	build := opts.build
+	if !build && !opts.noAutoBuild {
+		upToDate, err := docker.ImageUpToDate(cwd, sb.buildHash)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot check the image: %v\n", err)
+		} else if !upToDate {
+			fmt.Println("The image is missing or its build inputs changed (disable with --no-auto-build)")
+			build = true
+		}
+	}
+
+	// built after the override is generated, it passes the base image and labels to the build
+	if build {
 		fmt.Println("Building Docker image...")
-		if err := docker.Build(cwd, opts.noCache); err != nil {
+		if err := docker.Build(cwd, sb.Files, opts.noCache); err != nil {
 			fmt.Fprintf(os.Stderr, "Error building image: %v\n", err)
 			return 1
 		}
 	}
 
-	fmt.Println("Starting agentbox...")
-	if err := docker.Run(cwd); err != nil {
-		fmt.Fprintf(os.Stderr, "Error running container: %v\n", err)
+	sessionName := opts.name
+	if sessionName == "" {
+		sessionName = opts.worktree
+	}
+	checkpoints, err := newCheckpointer(sb.workDir, sessionName, sb.settings.Checkpoints)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if checkpoints != nil {
+		checkpoints.start()
+	}
+
+	title := "agentbox run"
+	if opts.name != "" {
+		title += " --name " + opts.name
+	}
+	recorder, err := newSessionRecorder(cwd, title, opts.record, sb.settings.Recording)
+	if err != nil {
+		if checkpoints != nil {
+			checkpoints.finish()
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return 1
 	}
+	if recorder != nil {
+		sb.Terminal = recorder.Run
+	}
+
+	fmt.Println("Starting agentbox...")
+	started := time.Now()
+	runErr := docker.Run(cwd, sb.RunOptions)
+	if recorder != nil {
+		recorder.finish()
+	}
+	if checkpoints != nil {
+		checkpoints.finish()
+	}
+	printDenied(os.Stderr, finishSandbox(cwd, sb, started))
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "Error running container: %v\n", runErr)
+	}
+
+	if session := sb.review; session != nil {
+		// reviewChanges decides whether the copy is kept
+		sb.review = nil
+		if err := reviewChanges(session, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
 
+	if runErr != nil {
+		return 1
+	}
 	return 0
 }
 
+// defaultRunFlags returns the run.flags setting of the project, which go before
+// the command-line flags so that those take precedence.
+func defaultRunFlags(projectDir string) ([]string, error) {
+	paths, err := config.NewPaths()
+	if err != nil {
+		return nil, fmt.Errorf("get paths: %w", err)
+	}
+	settings, err := config.LoadSettings(paths, projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("load settings: %w", err)
+	}
+	if err := ValidateNoUnknownFlags(settings.Run.Flags, runAllowedFlags); err != nil {
+		return nil, fmt.Errorf("invalid run.flags setting: %w", err)
+	}
+	return settings.Run.Flags, nil
+}
+
 // parseRunFlags parses run command flags.
 // Assumes validation was already done by RejectUnknownFlagsWithAllowed.
-func (a *App) parseRunFlags(args []string) runOptions {
+func (a *App) parseRunFlags(args []string) (runOptions, error) {
 	var opts runOptions
-	for _, arg := range args {
-		switch arg {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
 		case "--build":
 			opts.build = true
 		case "--build-no-cache":
 			opts.build = true
 			opts.noCache = true
+		case "--no-auto-build":
+			opts.noAutoBuild = true
+		case "--safe":
+			opts.safe = true
+		case "--name":
+			value, err := flagValue(args, i)
+			if err != nil {
+				return opts, err
+			}
+			i++
+			if err := validateSessionName(value); err != nil {
+				return opts, err
+			}
+			opts.name = value
+		case "--worktree":
+			value, err := flagValue(args, i)
+			if err != nil {
+				return opts, err
+			}
+			i++
+			if err := validateWorktreeName(value); err != nil {
+				return opts, err
+			}
+			opts.worktree = value
+		case "--egress":
+			value, err := flagValue(args, i)
+			if err != nil {
+				return opts, err
+			}
+			i++
+			if err := config.ValidateEgress(value); err != nil {
+				return opts, err
+			}
+			opts.egress = value
+		case "--review":
+			opts.review = true
+		case "--record":
+			opts.record = true
+		case "--cpus", "--memory", "--pids-limit":
+			value, err := flagValue(args, i)
+			if err != nil {
+				return opts, err
+			}
+			i++
+			if err := parseResourceFlag(&opts.resources, arg, value); err != nil {
+				return opts, err
+			}
+		case "-p", "--publish":
+			value, err := flagValue(args, i)
+			if err != nil {
+				return opts, err
+			}
+			i++
+			if err := config.ValidatePort(value); err != nil {
+				return opts, err
+			}
+			opts.ports = append(opts.ports, value)
+		case "--host-gateway":
+			opts.hostGateway = true
+		case "--forward-git":
+			opts.forwardGit = true
+		default:
+			return opts, fmt.Errorf("unexpected argument: %s", arg)
 		}
 	}
-	return opts
+	if opts.review && opts.worktree != "" {
+		return opts, errors.New("--review cannot be combined with --worktree")
+	}
+	return opts, nil
+}
+
+var sessionNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// validateSessionName checks that a session name is a valid container name.
+func validateSessionName(name string) error {
+	if !sessionNameRe.MatchString(name) {
+		return fmt.Errorf("invalid session name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// parseResourceFlag sets the resource limit of a run flag.
+func parseResourceFlag(resources *docker.Resources, flag, value string) error {
+	var err error
+	switch flag {
+	case "--cpus":
+		resources.CPUs, err = docker.ParseCPUs(value)
+	case "--memory":
+		resources.Memory, err = config.ParseMemory(value)
+	case "--pids-limit":
+		resources.PidsLimit, err = docker.ParsePidsLimit(value)
+	}
+	return err
+}
+
+// attachOptions holds parsed attach command flags.
+type attachOptions struct {
+	containerID string
+	record      bool
+	agent       string
+	docker.AttachOptions
 }
 
 func (a *App) cmdAttach(args []string) int {
 	if hasHelpFlag(args) {
-		fmt.Print(`Attach to running container
+		fmt.Printf(`Attach to running container
 
 Usage:
-  agentbox attach [container-id]
+  agentbox attach [container-id] [flags] [-- command...]
 
 Arguments:
-  container-id                      Container ID (optional, interactive if omitted)
+  container-id                      Container ID or session name (optional, interactive if omitted)
+  command                           Command to run instead of bash, or arguments of --agent
+
+Flags:
+  --agent <name>                    Start an agent (%s) with its default flags
+  --user <user>                     Run as another user, e.g. root
+  --workdir <dir>                   Working directory in the container
+  --record                          Record the terminal session (see 'agentbox sessions')
 
 If no container ID is provided and multiple containers are running,
 you will be prompted to select one.
-`)
+
+Examples:
+  agentbox attach --agent claude             # start a second agent in the sandbox
+  agentbox attach --agent codex -- resume    # pass arguments to the agent
+  agentbox attach --user root -- apt list    # run any command
+`, availableAgentsStr())
 		return 0
 	}
 
-	if code := RejectUnknownFlags(args); code != 0 {
+	flags, command := splitArgs(args)
+	if code := RejectUnknownFlagsWithAllowed(flags, CommandFlags()["attach"]); code != 0 {
 		return code
 	}
 
-	if len(args) > 0 {
-		return a.attachToContainer(args[0])
+	opts, err := parseAttachFlags(flags, command)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
 	}
 
 	cwd, err := os.Getwd()
@@ -275,6 +572,19 @@ you will be prompted to select one.
 		return 1
 	}
 
+	if opts.containerID != "" {
+		// session names work from any directory, other ids are passed as is
+		c, ok, err := findContainer(cwd, opts.containerID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if ok {
+			opts.containerID = c.ID
+		}
+		return a.attachToContainer(cwd, opts)
+	}
+
 	containers, err := docker.ListContainers(cwd, false)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -287,30 +597,119 @@ you will be prompted to select one.
 	}
 
 	if len(containers) == 1 {
-		return a.attachToContainer(containers[0].ID)
+		opts.containerID = containers[0].ID
+		return a.attachToContainer(cwd, opts)
 	}
 
-	return a.selectAndAttach(containers)
+	return a.selectAndAttach(cwd, containers, opts)
 }
 
-func (a *App) selectAndAttach(containers []docker.Container) int {
+// parseAttachFlags parses attach flags and the command after "--".
+// Assumes validation was already done by RejectUnknownFlagsWithAllowed.
+func parseAttachFlags(args, command []string) (attachOptions, error) {
+	var opts attachOptions
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--record":
+			opts.record = true
+		case "--agent", "--user", "--workdir":
+			value, err := flagValue(args, i)
+			if err != nil {
+				return opts, err
+			}
+			i++
+			switch arg {
+			case "--agent":
+				if !slices.Contains(agents.AllAgentNames(), value) {
+					return opts, fmt.Errorf("unknown agent: %s (available: %s)", value, availableAgentsStr())
+				}
+				opts.agent = value
+			case "--user":
+				opts.User = value
+			case "--workdir":
+				opts.WorkDir = value
+			}
+		default:
+			if opts.containerID != "" {
+				return opts, fmt.Errorf("unexpected argument: %s", arg)
+			}
+			opts.containerID = arg
+		}
+	}
+
+	// the launcher applies the agent's default flags, as when started from bash
+	if opts.agent != "" {
+		opts.Command = append([]string{agents.LauncherPath(opts.agent)}, command...)
+	} else {
+		opts.Command = command
+	}
+	return opts, nil
+}
+
+func (a *App) selectAndAttach(cwd string, containers []docker.Container, opts attachOptions) int {
+	c, ok := selectContainer(containers)
+	if !ok {
+		return 1
+	}
+	opts.containerID = c.ID
+	return a.attachToContainer(cwd, opts)
+}
+
+// selectContainer asks the user to choose one of the containers.
+func selectContainer(containers []docker.Container) (docker.Container, bool) {
 	fmt.Println("Multiple running containers found:")
 	for i, c := range containers {
-		fmt.Printf("  %d) %s (started %s)\n", i+1, c.ID, c.Started)
+		label := c.ID
+		if c.Session != "" {
+			label += " " + c.Session
+		}
+		if c.Worktree != "" {
+			fmt.Printf("  %d) %s (worktree %s, started %s)\n", i+1, label, c.Worktree, c.Started)
+		} else {
+			fmt.Printf("  %d) %s (started %s)\n", i+1, label, c.Started)
+		}
 	}
 	fmt.Printf("Select [1-%d]: ", len(containers))
 
 	var selection int
 	if _, err := fmt.Scanf("%d", &selection); err != nil || selection < 1 || selection > len(containers) {
 		fmt.Fprintln(os.Stderr, "Invalid selection")
+		return docker.Container{}, false
+	}
+	return containers[selection-1], true
+}
+
+func (a *App) attachToContainer(cwd string, opts attachOptions) int {
+	paths, err := config.NewPaths()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return 1
 	}
 
-	return a.attachToContainer(containers[selection-1].ID)
-}
+	settings, err := config.LoadSettings(paths, cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	title := "agentbox attach " + opts.containerID
+	if opts.agent != "" {
+		title += " --agent " + opts.agent
+	}
+	recorder, err := newSessionRecorder(cwd, title, opts.record, settings.Recording)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
 
-func (a *App) attachToContainer(containerID string) int {
-	if err := docker.Attach(containerID); err != nil {
+	if recorder != nil {
+		opts.Terminal = recorder.Run
+	}
+	err = docker.Attach(opts.containerID, opts.AttachOptions)
+	if recorder != nil {
+		recorder.finish()
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error attaching to container: %v\n", err)
 		return 1
 	}
@@ -342,11 +741,20 @@ func (a *App) ensureProjectReady(cwd string) int {
 		return code
 	}
 
+	if code := a.writeLaunchers(paths); code != 0 {
+		return code
+	}
+
 	if err := ensureAgentConfigs(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating agent configs: %v\n", err)
 		return 1
 	}
 
+	if err := a.ensureBaseImage(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error building base image: %v\n", err)
+		return 1
+	}
+
 	return 0
 }
 
@@ -394,8 +802,14 @@ Use "agentbox agent <command> --help" for more information about a command.
 		return 1
 	}
 
+	settings, err := loadGlobalSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
 	if len(args) == 0 {
-		return a.showAgentStatus(manager)
+		return a.showAgentStatus(manager, settings.HTTP)
 	}
 
 	subcmd := args[0]
@@ -403,7 +817,7 @@ Use "agentbox agent <command> --help" for more information about a command.
 
 	switch subcmd {
 	case "update":
-		return a.agentUpdate(manager, subargs)
+		return a.agentUpdate(manager, settings.HTTP, settings.Updates.KeptVersions(), subargs)
 	case "use":
 		return a.agentUse(manager, subargs)
 	default:
@@ -412,9 +826,9 @@ Use "agentbox agent <command> --help" for more information about a command.
 	}
 }
 
-func (a *App) showAgentStatus(manager *agents.Manager) int {
+func (a *App) showAgentStatus(manager *agents.Manager, timeouts config.HTTPSettings) int {
 	fmt.Println("\nFetching agent versions...")
-	statuses := manager.GetStatus()
+	statuses := manager.GetStatus(timeouts)
 
 	table := NewTable("Agent", "Installed", "Latest", "Status")
 
@@ -450,7 +864,8 @@ func (a *App) showAgentStatus(manager *agents.Manager) int {
 	return 0
 }
 
-func (a *App) agentUpdate(manager *agents.Manager, args []string) int {
+// agentUpdate updates agents and keeps the given number of installed versions of each.
+func (a *App) agentUpdate(manager *agents.Manager, timeouts config.HTTPSettings, keep int, args []string) int {
 	if hasHelpFlag(args) {
 		fmt.Printf(`Update agents to latest version
 
@@ -479,7 +894,7 @@ Examples:
 
 	fmt.Println("Updating agents...")
 
-	results, err := manager.Update(agentsToUpdate)
+	results, err := manager.Update(agentsToUpdate, timeouts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error updating agents: %v\n", err)
 		return 1
@@ -503,7 +918,7 @@ Examples:
 	// cleanup old versions
 	totalRemoved := 0
 	for _, name := range agents.AllAgentNames() {
-		removed, _ := manager.Cleanup(name)
+		removed, _ := manager.Cleanup(name, keep)
 		totalRemoved += removed
 	}
 
@@ -568,6 +983,9 @@ Flags:
   -a, --all                         Show containers from all projects
 
 By default, only containers from the current project directory are shown.
+Each container is listed with its resource usage, published ports and the agents
+running in it.
+Named sessions (see 'agentbox run --name') can be attached from any directory.
 `)
 		return 0
 	}
@@ -608,15 +1026,75 @@ By default, only containers from the current project directory are shown.
 		return 0
 	}
 
-	table := NewTable("CONTAINER ID", "NAME", "STARTED")
-	for _, c := range containers {
-		table.AddRow(c.ID, c.Name, c.Started)
+	home, _ := os.UserHomeDir()
+	usage := collectUsage(containers)
+	table := NewTable("CONTAINER ID", "NAME", "PROJECT", "IMAGE", "WORKTREE", "UPTIME", "CPU %", "MEM USAGE / LIMIT", "PORTS", "AGENTS")
+	for i, c := range containers {
+		table.AddRow(psRow(c, usage[i], home)...)
 	}
 	table.Render()
 
 	return 0
 }
 
+// containerUsage is the live state of a container shown by ps.
+type containerUsage struct {
+	// stats is nil if the usage could not be read
+	stats  *docker.Stats
+	agents []string
+}
+
+// collectUsage reads the resource usage and running agents of the containers in parallel.
+func collectUsage(containers []docker.Container) []containerUsage {
+	usage := make([]containerUsage, len(containers))
+	var wg sync.WaitGroup
+	for i, c := range containers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if stats, err := docker.ContainerStats(c.ID); err == nil {
+				usage[i].stats = &stats
+			}
+			if commands, err := docker.Processes(c.ID); err == nil {
+				usage[i].agents = agents.RunningAgents(commands)
+			}
+		}()
+	}
+	wg.Wait()
+	return usage
+}
+
+// psRow returns the ps table row of a container, shortening paths under home to "~".
+func psRow(c docker.Container, usage containerUsage, home string) []string {
+	// a session name is the container name, unnamed containers get one from compose
+	name := c.Name
+	if c.Session != "" {
+		name = c.Session
+	}
+	project := c.ProjectDir
+	if home != "" && (project == home || strings.HasPrefix(project, home+"/")) {
+		project = "~" + strings.TrimPrefix(project, home)
+	}
+	uptime := strings.TrimPrefix(c.Status, "Up ")
+
+	cpu, memory := "-", "-"
+	if usage.stats != nil {
+		cpu = fmt.Sprintf("%.1f%%", usage.stats.CPUPercent)
+		memory = docker.HumanSize(usage.stats.MemoryUsage) + " / " + docker.HumanSize(usage.stats.MemoryLimit)
+	}
+	running := strings.Join(usage.agents, ",")
+
+	return []string{c.ID, dash(name), dash(project), dash(c.Image), dash(c.Worktree), dash(uptime), cpu, memory, dash(c.Ports), dash(running)}
+}
+
+// dash returns s, or "-" for empty table cells.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
 func (a *App) cmdClean(args []string) int {
 	if hasHelpFlag(args) {
 		fmt.Print(`Remove sandbox files from project
@@ -756,6 +1234,12 @@ func removeFromGitExclude(projectDir, filename string) error {
 }
 
 func (a *App) ensureAgentsInstalled(paths *config.Paths) int {
+	settings, err := loadGlobalSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
 	manager, err := agents.NewManager(paths)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -769,7 +1253,7 @@ func (a *App) ensureAgentsInstalled(paths *config.Paths) int {
 	fmt.Println()
 	fmt.Println("No agents installed. Downloading all agents...")
 
-	results, err := manager.Update(nil)
+	results, err := manager.Update(nil, settings.HTTP)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error updating agents: %v\n", err)
 		return 1
@@ -794,30 +1278,56 @@ func (a *App) ensureAgentsInstalled(paths *config.Paths) int {
 	return 0
 }
 
+func (a *App) writeLaunchers(paths *config.Paths) int {
+	manager, err := agents.NewManager(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if err := manager.WriteLaunchers(paths.LaunchersDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing agent launchers: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// agentConfigPath is a host path with agent settings mounted into the sandbox.
+type agentConfigPath struct {
+	path  string
+	dir   bool
+	agent string
+}
+
+// agentConfigPaths returns the agent settings mounted by docker-compose.agentbox.yml.
+func agentConfigPaths(home string) []agentConfigPath {
+	return []agentConfigPath{
+		{path: filepath.Join(home, ".claude.json"), agent: "claude"},
+		{path: filepath.Join(home, ".claude"), dir: true, agent: "claude"},
+		{path: filepath.Join(home, ".copilot"), dir: true, agent: "copilot"},
+		{path: filepath.Join(home, ".codex"), dir: true, agent: "codex"},
+		{path: filepath.Join(home, ".gemini"), dir: true, agent: "gemini"},
+	}
+}
+
 func ensureAgentConfigs() error {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return fmt.Errorf("get home dir: %w", err)
 	}
 
-	// create ~/.claude.json if not exists (prevents Docker from creating it as directory)
-	claudeJSON := filepath.Join(home, ".claude.json")
-	if _, err := os.Stat(claudeJSON); os.IsNotExist(err) {
-		if err := os.WriteFile(claudeJSON, []byte("{}"), 0o644); err != nil {
-			return fmt.Errorf("write claude.json: %w", err)
+	// create missing files and directories (prevents Docker from creating files as directories)
+	for _, p := range agentConfigPaths(home) {
+		if p.dir {
+			if err := os.MkdirAll(p.path, 0o755); err != nil {
+				return fmt.Errorf("create dir %s: %w", p.path, err)
+			}
+			continue
 		}
-	}
-
-	// create config directories if not exist
-	dirs := []string{
-		filepath.Join(home, ".claude"),
-		filepath.Join(home, ".copilot"),
-		filepath.Join(home, ".codex"),
-		filepath.Join(home, ".gemini"),
-	}
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0o755); err != nil {
-			return fmt.Errorf("create dir %s: %w", dir, err)
+		if _, err := os.Stat(p.path); os.IsNotExist(err) {
+			if err := os.WriteFile(p.path, []byte("{}"), 0o644); err != nil {
+				return fmt.Errorf("write %s: %w", filepath.Base(p.path), err)
+			}
 		}
 	}
 
@@ -898,6 +1408,12 @@ Examples:
 		return code
 	}
 
+	settings, err := loadGlobalSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
 	var targetVersion string
 	for _, arg := range args {
 		if !strings.HasPrefix(arg, "-") {
@@ -908,7 +1424,7 @@ Examples:
 
 	if targetVersion == "" {
 		fmt.Println("Fetching latest version...")
-		latest, err := fetchLatestVersion()
+		latest, err := fetchLatestVersion(settings.HTTP.TimeoutDuration())
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error fetching latest version: %v\n", err)
 			return 1
@@ -951,7 +1467,7 @@ Examples:
 	}
 	defer os.RemoveAll(tmpDir)
 
-	resp, cancel, err := httpDownload(downloadURL)
+	resp, cancel, err := httpGet(downloadURL, settings.HTTP.DownloadTimeoutDuration())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error downloading: %v\n", err)
 		return 1
@@ -1080,7 +1596,13 @@ Usage:
 		return code
 	}
 
-	versions, err := fetchVersions()
+	settings, err := loadGlobalSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	versions, err := fetchVersions(settings.HTTP.TimeoutDuration())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error fetching versions: %v\n", err)
 		return 1
@@ -1092,9 +1614,9 @@ Usage:
 	return 0
 }
 
-func fetchVersions() ([]string, error) {
+func fetchVersions(timeout time.Duration) ([]string, error) {
 	url := fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=30", githubRepo)
-	resp, cancel, err := httpGet(url)
+	resp, cancel, err := httpGet(url, timeout)
 	if err != nil {
 		return nil, fmt.Errorf("fetch releases: %w", err)
 	}
@@ -1119,9 +1641,9 @@ func fetchVersions() ([]string, error) {
 	return versions, nil
 }
 
-func fetchLatestVersion() (string, error) {
+func fetchLatestVersion(timeout time.Duration) (string, error) {
 	url := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", githubRepo)
-	resp, cancel, err := httpGet(url)
+	resp, cancel, err := httpGet(url, timeout)
 	if err != nil {
 		return "", fmt.Errorf("fetch releases: %w", err)
 	}
@@ -1142,22 +1664,22 @@ func fetchLatestVersion() (string, error) {
 	return strings.TrimPrefix(release.TagName, "v"), nil
 }
 
-const (
-	httpTimeout         = 30 * time.Second
-	httpDownloadTimeout = 5 * time.Minute
-)
-
-// httpGet performs a GET request with standard timeout.
-func httpGet(url string) (resp *http.Response, cancel context.CancelFunc, err error) {
-	return httpGetWithTimeout(url, httpTimeout)
-}
-
-// httpDownload performs a GET request with extended timeout for file downloads.
-func httpDownload(url string) (resp *http.Response, cancel context.CancelFunc, err error) {
-	return httpGetWithTimeout(url, httpDownloadTimeout)
+// loadGlobalSettings loads the settings of ~/.agentbox/config.toml and the
+// environment.
+func loadGlobalSettings() (*config.Settings, error) {
+	paths, err := config.NewPaths()
+	if err != nil {
+		return nil, fmt.Errorf("get paths: %w", err)
+	}
+	settings, err := config.LoadSettings(paths, "")
+	if err != nil {
+		return nil, fmt.Errorf("load settings: %w", err)
+	}
+	return settings, nil
 }
 
-func httpGetWithTimeout(url string, timeout time.Duration) (resp *http.Response, cancel context.CancelFunc, err error) {
+// httpGet performs a GET request that is canceled after timeout, including reading the body.
+func httpGet(url string, timeout time.Duration) (resp *http.Response, cancel context.CancelFunc, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
@@ -0,0 +1,244 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aleksey925/agentbox/internal/config"
+	"github.com/aleksey925/agentbox/internal/git"
+)
+
+// checkpointer snapshots a work tree when a session starts, periodically
+// while it runs and when it ends.
+type checkpointer struct {
+	dir      string
+	session  string
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// newCheckpointer returns nil if checkpoints are disabled or dir is not a git work tree.
+// The session is named after name, the session or worktree name if any.
+func newCheckpointer(dir, name string, settings config.CheckpointSettings) (*checkpointer, error) {
+	if dir == "" || !settings.IsEnabled() {
+		return nil, nil
+	}
+	if _, err := git.TopLevel(dir); err != nil {
+		return nil, nil
+	}
+
+	interval, err := settings.IntervalDuration()
+	if err != nil {
+		return nil, err
+	}
+
+	return &checkpointer{
+		dir:      dir,
+		session:  git.NewSessionID(name),
+		interval: interval,
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// start takes the first checkpoint and schedules periodic ones.
+func (c *checkpointer) start() {
+	checkpoint, _, err := git.Snapshot(c.dir, c.session, "session start")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: checkpoint failed: %v\n", err)
+	} else if checkpoint.Commit != "" {
+		fmt.Printf("Checkpoint %s saved, restore with 'agentbox checkpoints restore %s'\n", checkpoint.ID(), checkpoint.ID())
+	}
+
+	if c.interval == 0 {
+		return
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// errors are not printed, the agent owns the terminal
+				_, _, _ = git.Snapshot(c.dir, c.session, "periodic")
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// finish stops periodic checkpoints and takes the last one.
+func (c *checkpointer) finish() {
+	close(c.stop)
+	c.wg.Wait()
+
+	if _, _, err := git.Snapshot(c.dir, c.session, "session end"); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: checkpoint failed: %v\n", err)
+	}
+}
+
+func (a *App) cmdCheckpoints(args []string) int {
+	if len(args) > 0 && hasHelpFlag(args[:1]) {
+		fmt.Print(`Manage git checkpoints taken during agent sessions
+
+Usage:
+  agentbox checkpoints [command]
+
+Commands:
+  ls                                List checkpoints (default)
+  restore <id>                      Restore the work tree to a checkpoint
+
+'agentbox run' snapshots the work tree, including untracked files, when a session
+starts, every 5 minutes while it runs and when it ends. Checkpoints are stored in
+refs/agentbox/checkpoints/<session> and do not touch branches or the index.
+
+Configure them in ~/.agentbox/config.toml or .agentbox.toml:
+  [checkpoints]
+  enabled = true
+  interval = "10m"
+
+Use "agentbox checkpoints <command> --help" for more information about a command.
+`)
+		return 0
+	}
+
+	if len(args) > 0 {
+		if code := RejectUnknownFlags(args[:1]); code != 0 {
+			return code
+		}
+	}
+
+	if len(args) == 0 {
+		return a.checkpointsLs(nil)
+	}
+
+	subcmd := args[0]
+	subargs := args[1:]
+
+	switch subcmd {
+	case "ls":
+		return a.checkpointsLs(subargs)
+	case "restore":
+		return a.checkpointsRestore(subargs)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown checkpoints subcommand: %s\n", subcmd)
+		return 1
+	}
+}
+
+func (a *App) checkpointsLs(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`List checkpoints
+
+Usage:
+  agentbox checkpoints ls [flags]
+
+Flags:
+  -q, --quiet                       Print only checkpoint ids
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlagsWithAllowed(args, CheckpointsLsFlags()); code != 0 {
+		return code
+	}
+
+	quiet := slices.Contains(args, "-q") || slices.Contains(args, "--quiet")
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	checkpoints, err := git.ListCheckpoints(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if quiet {
+		for _, c := range checkpoints {
+			fmt.Println(c.ID())
+		}
+		return 0
+	}
+
+	if len(checkpoints) == 0 {
+		fmt.Println("No checkpoints")
+		return 0
+	}
+
+	table := NewTable("ID", "SESSION", "CREATED", "MESSAGE")
+	for _, c := range checkpoints {
+		table.AddRow(c.ID(), c.Session, c.Time.Format(time.DateTime), c.Message)
+	}
+	table.Render()
+	return 0
+}
+
+func (a *App) checkpointsRestore(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Restore the work tree to a checkpoint
+
+Usage:
+  agentbox checkpoints restore <id>
+
+Arguments:
+  id                                Checkpoint id or its prefix (see 'agentbox checkpoints ls')
+
+Files are restored to their content at the checkpoint and files created after it
+are removed. Ignored files, the index and branches are not changed. The current
+state is saved as a checkpoint first, so a restore can be undone.
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlags(args); code != 0 {
+		return code
+	}
+
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: agentbox checkpoints restore <id>\n")
+		return 1
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	checkpoint, err := git.FindCheckpoint(cwd, args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if errors.Is(err, git.ErrCheckpointNotFound) {
+			fmt.Fprintln(os.Stderr, "Use 'agentbox checkpoints ls' to list checkpoints")
+		}
+		return 1
+	}
+
+	session := git.NewSessionID("restore")
+	backup, _, err := git.Snapshot(cwd, session, "before restoring "+checkpoint.ID())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving current state: %v\n", err)
+		return 1
+	}
+
+	if err := git.RestoreCheckpoint(cwd, checkpoint); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Restored checkpoint %s (%s, %s)\n", checkpoint.ID(), checkpoint.Message, checkpoint.Time.Format(time.DateTime))
+	fmt.Printf("Previous state saved as checkpoint %s\n", backup.ID())
+	return 0
+}
@@ -0,0 +1,320 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aleksey925/agentbox/internal/config"
+)
+
+// Sources of the value of a setting shown by config list.
+const (
+	sourceDefault = "default"
+	sourceGlobal  = "global"
+	sourceProject = "project"
+	sourceEnv     = "env"
+)
+
+// settingSource returns where the value of the key comes from. The environment
+// wins over the project config, which wins over the global one.
+func settingSource(k config.Key, globalFile, projectFile string) (string, error) {
+	if _, ok := os.LookupEnv(k.Env()); ok {
+		return sourceEnv, nil
+	}
+	for _, f := range []struct{ path, source string }{{projectFile, sourceProject}, {globalFile, sourceGlobal}} {
+		set, err := config.IsSet(f.path, k)
+		if err != nil {
+			return "", err
+		}
+		if set {
+			return f.source, nil
+		}
+	}
+	return sourceDefault, nil
+}
+
+// splitConfigArgs separates the leading flags of a config subcommand from its
+// arguments, so that values such as "--safe" in run.flags are not taken for flags.
+func splitConfigArgs(args, allowed []string) (flags, rest []string, code int) {
+	i := 0
+	for i < len(args) && strings.HasPrefix(args[i], "-") {
+		i++
+	}
+	if code := RejectUnknownFlagsWithAllowed(args[:i], allowed); code != 0 {
+		return nil, nil, code
+	}
+	return args[:i], args[i:], 0
+}
+
+// configFile returns the global config file, or the project one with --project.
+func configFile(paths *config.Paths, flags []string) (string, error) {
+	if !slices.Contains(flags, "--project") {
+		return paths.ConfigFile, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cwd, config.ProjectConfigFile), nil
+}
+
+func (a *App) cmdConfig(args []string) int {
+	if len(args) > 0 && hasHelpFlag(args[:1]) {
+		fmt.Print(`View and edit settings
+
+Usage:
+  agentbox config [command]
+
+Commands:
+  list                              List settings with their values and sources (default)
+  get <key>                         Print the value of a setting
+  set <key> <value>                 Set a setting in ~/.agentbox/config.toml
+  unset <key>                       Remove a setting from ~/.agentbox/config.toml
+
+Settings are read from ~/.agentbox/config.toml, then from .agentbox.toml of the
+project, then from AGENTBOX_<KEY> environment variables, e.g.
+AGENTBOX_NETWORK_EGRESS for network.egress. Lists are comma-separated.
+
+Use "agentbox config <command> --help" for more information about a command.
+`)
+		return 0
+	}
+
+	if len(args) > 0 {
+		if code := RejectUnknownFlags(args[:1]); code != 0 {
+			return code
+		}
+	}
+
+	if len(args) == 0 {
+		return a.configList(nil)
+	}
+
+	subcmd := args[0]
+	subargs := args[1:]
+
+	switch subcmd {
+	case "list":
+		return a.configList(subargs)
+	case "get":
+		return a.configGet(subargs)
+	case "set":
+		return a.configSet(subargs)
+	case "unset":
+		return a.configUnset(subargs)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown config subcommand: %s\n", subcmd)
+		return 1
+	}
+}
+
+func (a *App) configList(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`List settings with their values and sources
+
+Usage:
+  agentbox config list
+
+The source is default, global (~/.agentbox/config.toml), project (.agentbox.toml)
+or env (an AGENTBOX_<KEY> environment variable).
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlags(args); code != 0 {
+		return code
+	}
+
+	paths, err := config.NewPaths()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	settings, err := config.LoadSettings(paths, cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	projectFile := filepath.Join(cwd, config.ProjectConfigFile)
+	table := NewTable("KEY", "VALUE", "SOURCE", "DESCRIPTION")
+	for _, k := range config.Keys() {
+		source, err := settingSource(k, paths.ConfigFile, projectFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		value := k.Get(settings)
+		if value == "" {
+			value = "-"
+		}
+		table.AddRow(k.Name, value, source, k.Description)
+	}
+	table.Render()
+	return 0
+}
+
+func (a *App) configGet(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Print the value of a setting
+
+Usage:
+  agentbox config get <key>
+
+Prints the value in effect in the current directory, or the default.
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlags(args); code != 0 {
+		return code
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: agentbox config get <key>\n")
+		return 1
+	}
+
+	k, err := config.LookupKey(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	paths, err := config.NewPaths()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	settings, err := config.LoadSettings(paths, cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(k.Get(settings))
+	return 0
+}
+
+func (a *App) configSet(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Set a setting
+
+Usage:
+  agentbox config set [flags] <key> <value>
+
+Flags:
+  --project                         Write .agentbox.toml of the current project
+
+Lists are comma-separated. The value is validated before the file is written;
+the file is rewritten, so comments in it are not kept.
+
+Examples:
+  agentbox config set network.egress allowlist
+  agentbox config set run.flags --safe,--record
+  agentbox config set --project resources.memory 4g
+`)
+		return 0
+	}
+
+	flags, rest, code := splitConfigArgs(args, ConfigSetFlags())
+	if code != 0 {
+		return code
+	}
+	if len(rest) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: agentbox config set [--project] <key> <value>\n")
+		return 1
+	}
+
+	k, err := config.LookupKey(rest[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	value, err := k.Parse(rest[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	// a project must not trust itself
+	if k.Name == "trust.projects" && slices.Contains(flags, "--project") {
+		fmt.Fprintf(os.Stderr, "Error: %s can only be set in the global config\n", k.Name)
+		return 1
+	}
+
+	paths, err := config.NewPaths()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	path, err := configFile(paths, flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := config.SetValue(path, k, value); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if _, ok := os.LookupEnv(k.Env()); ok {
+		fmt.Fprintf(os.Stderr, "Warning: %s is overridden by %s\n", k.Name, k.Env())
+	}
+	return 0
+}
+
+func (a *App) configUnset(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Remove a setting, so that its default is used
+
+Usage:
+  agentbox config unset [flags] <key>
+
+Flags:
+  --project                         Edit .agentbox.toml of the current project
+`)
+		return 0
+	}
+
+	flags, rest, code := splitConfigArgs(args, ConfigSetFlags())
+	if code != 0 {
+		return code
+	}
+	if len(rest) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: agentbox config unset [--project] <key>\n")
+		return 1
+	}
+
+	k, err := config.LookupKey(rest[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	paths, err := config.NewPaths()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	path, err := configFile(paths, flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := config.UnsetValue(path, k); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/aleksey925/agentbox/internal/config"
+)
+
+func TestSplitConfigArgs(t *testing.T) {
+	// act
+	flags, rest, code := splitConfigArgs([]string{"--project", "run.flags", "--safe,--record"}, ConfigSetFlags())
+
+	// assert
+	if code != 0 {
+		t.Fatalf("code = %d, want 0", code)
+	}
+	if !slices.Equal(flags, []string{"--project"}) {
+		t.Errorf("flags = %v, want [--project]", flags)
+	}
+	if !slices.Equal(rest, []string{"run.flags", "--safe,--record"}) {
+		t.Errorf("rest = %v, want [run.flags --safe,--record]", rest)
+	}
+}
+
+func TestSettingSource(t *testing.T) {
+	// arrange
+	dir := t.TempDir()
+	globalFile := filepath.Join(dir, "config.toml")
+	projectFile := filepath.Join(dir, config.ProjectConfigFile)
+	if err := os.WriteFile(globalFile, []byte("[network]\negress = \"allowlist\"\n[http]\ntimeout = \"1m\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(projectFile, []byte("[network]\negress = \"open\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AGENTBOX_RESOURCES_MEMORY", "4g")
+
+	expected := map[string]string{
+		"network.egress":   sourceProject,
+		"http.timeout":     sourceGlobal,
+		"resources.memory": sourceEnv,
+		"run.flags":        sourceDefault,
+	}
+	for name, want := range expected {
+		t.Run(name, func(t *testing.T) {
+			k, err := config.LookupKey(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// act
+			source, err := settingSource(k, globalFile, projectFile)
+
+			// assert
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if source != want {
+				t.Errorf("settingSource(%s) = %q, want %q", name, source, want)
+			}
+		})
+	}
+}
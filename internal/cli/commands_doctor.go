@@ -0,0 +1,399 @@
+package cli
+
+import (
+	"context"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aleksey925/agentbox/internal/agents"
+	"github.com/aleksey925/agentbox/internal/config"
+	"github.com/aleksey925/agentbox/internal/docker"
+	"github.com/aleksey925/agentbox/internal/skeleton"
+)
+
+// doctorTimeout limits each call to the container runtime.
+const doctorTimeout = 10 * time.Second
+
+// minFreeSpace is the free disk space below which doctor warns: the base
+// image and a project image take a few GiB.
+const minFreeSpace = 5 << 30
+
+type checkStatus string
+
+const (
+	checkOK   checkStatus = "ok"
+	checkWarn checkStatus = "warn"
+	checkFail checkStatus = "fail"
+)
+
+// checkResult is the outcome of a doctor check with the fix of a problem.
+type checkResult struct {
+	name    string
+	status  checkStatus
+	message string
+	fix     string
+}
+
+func okResult(name, format string, args ...any) checkResult {
+	return checkResult{name: name, status: checkOK, message: fmt.Sprintf(format, args...)}
+}
+
+// elfMachines maps DetectArch names to the machine of Linux executables.
+var elfMachines = map[string]elf.Machine{
+	"x64":   elf.EM_X86_64,
+	"arm64": elf.EM_AARCH64,
+}
+
+// daemonFix tells how to start the daemon of the runtime.
+func daemonFix(runtime string) string {
+	if runtime == docker.RuntimePodman {
+		return "start the podman API service: 'systemctl --user enable --now podman.socket' " +
+			"('podman machine start' on macOS)"
+	}
+	return "start Docker: 'sudo systemctl start docker' or open Docker Desktop"
+}
+
+// socketResult checks the Engine API socket of the runtime from the result of a ping.
+func socketResult(runtime, socket string, pingErr error) checkResult {
+	const name = "API socket"
+	switch {
+	case socket == "":
+		return checkResult{name: name, status: checkWarn,
+			message: "no Engine API socket found, agentbox falls back to the slower CLI",
+			fix:     daemonFix(runtime)}
+	case errors.Is(pingErr, os.ErrPermission):
+		fix := "add your user to the docker group: 'sudo usermod -aG docker $USER', then log in again"
+		if runtime == docker.RuntimePodman {
+			fix = "use the socket of your user: unset CONTAINER_HOST or set it to " +
+				"unix://$XDG_RUNTIME_DIR/podman/podman.sock"
+		}
+		return checkResult{name: name, status: checkFail,
+			message: fmt.Sprintf("permission denied on %s", socket), fix: fix}
+	case pingErr != nil:
+		return checkResult{name: name, status: checkFail,
+			message: fmt.Sprintf("%s does not respond: %v", socket, pingErr), fix: daemonFix(runtime)}
+	default:
+		return okResult(name, "%s", socket)
+	}
+}
+
+// composeResult checks the output of "compose version --short".
+func composeResult(composeName, output string, err error) checkResult {
+	const name = "Compose"
+	if err != nil {
+		fix := "install the compose plugin, https://docs.docker.com/compose/install/"
+		if strings.HasPrefix(composeName, docker.RuntimePodman) {
+			fix = "install podman-compose or docker-compose, https://github.com/containers/podman-compose"
+		}
+		return checkResult{name: name, status: checkFail,
+			message: fmt.Sprintf("%s is not available: %v", composeName, err), fix: fix}
+	}
+
+	version := strings.TrimPrefix(strings.TrimSpace(output), "v")
+	if v, ok := docker.ParseComposeVersion(version); ok && v[0] < 2 {
+		return checkResult{name: name, status: checkWarn,
+			message: fmt.Sprintf("%s %s is outdated", composeName, version),
+			fix:     "install compose v2, https://docs.docker.com/compose/install/"}
+	}
+	if !docker.SupportsOverrideTag(version) {
+		return checkResult{name: name, status: checkWarn,
+			message: fmt.Sprintf("%s %s does not support allowlist egress mode", composeName, version),
+			fix:     "install Docker Compose 2.24.4 or newer, https://docs.docker.com/compose/install/"}
+	}
+	return okResult(name, "%s %s", composeName, version)
+}
+
+// runtimeResults checks the container runtime, its daemon and compose.
+func runtimeResults() []checkResult {
+	rt, err := docker.CurrentRuntime()
+	if err != nil {
+		return []checkResult{{name: "Runtime", status: checkFail, message: err.Error(),
+			fix: "install Docker (https://docs.docker.com/get-docker/) or Podman (https://podman.io)"}}
+	}
+	results := []checkResult{okResult("Runtime", "%s", rt.Name())}
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+
+	socket := rt.Socket()
+	var pingErr error
+	if socket != "" {
+		pingErr = docker.NewClient(socket).Ping(ctx)
+	}
+	results = append(results, socketResult(rt.Name(), socket, pingErr))
+
+	info, err := rt.Info(ctx)
+	if err != nil {
+		results = append(results, checkResult{name: "Daemon", status: checkFail,
+			message: fmt.Sprintf("not reachable: %v", err), fix: daemonFix(rt.Name())})
+	} else {
+		results = append(results, okResult("Daemon", "running, %d CPUs, %s memory",
+			info.NCPU, docker.HumanSize(info.MemTotal)))
+	}
+
+	out, err := rt.ComposeCommand(ctx, "version", "--short").Output()
+	results = append(results, composeResult(rt.ComposeName(), string(out), err))
+	return results
+}
+
+// archResult checks that agents are available for the host architecture.
+func archResult() checkResult {
+	arch, err := agents.DetectArch()
+	if err != nil {
+		return checkResult{name: "Architecture", status: checkFail, message: err.Error(),
+			fix: "run agentbox on an amd64 or arm64 host"}
+	}
+	return okResult("Architecture", "%s", arch)
+}
+
+// writableResult checks that agentbox can write to its directory.
+func writableResult(dir string) checkResult {
+	const name = "Agentbox directory"
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return okResult(name, "%s is created on the first 'agentbox init'", dir)
+	}
+	f, err := os.CreateTemp(dir, ".doctor-")
+	if err != nil {
+		return checkResult{name: name, status: checkFail, message: fmt.Sprintf("%s is not writable: %v", dir, err),
+			fix: fmt.Sprintf("make it yours: 'sudo chown -R $USER %s'", dir)}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return okResult(name, "%s is writable", dir)
+}
+
+// diskResult checks the free space of the file system with agentbox files.
+func diskResult(dir string, free uint64) checkResult {
+	const name = "Disk space"
+	if free < minFreeSpace {
+		return checkResult{name: name, status: checkWarn,
+			message: fmt.Sprintf("only %s free for %s", docker.HumanSize(int64(free)), dir),
+			fix:     "free up space, e.g. with 'agentbox image prune' and 'docker system prune'"}
+	}
+	return okResult(name, "%s free", docker.HumanSize(int64(free)))
+}
+
+// freeSpace returns the space available to the user on the file system of
+// path, or of its closest existing parent.
+func freeSpace(path string) (uint64, error) {
+	for {
+		var stat syscall.Statfs_t
+		err := syscall.Statfs(path, &stat)
+		if err == nil {
+			return stat.Bavail * uint64(stat.Bsize), nil
+		}
+		parent := filepath.Dir(path)
+		if !os.IsNotExist(err) || parent == path {
+			return 0, fmt.Errorf("statfs %s: %w", path, err)
+		}
+		path = parent
+	}
+}
+
+// binaryResult checks that an installed agent can run in the sandbox: a Linux
+// executable for the host architecture, or a script run by an interpreter.
+func binaryResult(name, path string, interpreted bool, arch string) checkResult {
+	reinstall := fmt.Sprintf("remove %s and run 'agentbox agent update %s'", filepath.Dir(path), name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return checkResult{name: name, status: checkFail, message: fmt.Sprintf("%s is missing", path), fix: reinstall}
+	}
+	if interpreted {
+		return okResult(name, "%s", path)
+	}
+	if info.Mode()&0o111 == 0 {
+		return checkResult{name: name, status: checkFail, message: fmt.Sprintf("%s is not executable", path),
+			fix: fmt.Sprintf("chmod +x %s", path)}
+	}
+
+	f, err := elf.Open(path)
+	if err != nil {
+		return checkResult{name: name, status: checkFail,
+			message: fmt.Sprintf("%s is not a Linux executable", path), fix: reinstall}
+	}
+	defer f.Close()
+	if want, ok := elfMachines[arch]; ok && f.Machine != want {
+		return checkResult{name: name, status: checkFail,
+			message: fmt.Sprintf("%s is built for %s, the host needs %s", path, f.Machine, want), fix: reinstall}
+	}
+	return okResult(name, "%s", path)
+}
+
+// agentResults checks the current version of the installed agents.
+func agentResults(paths *config.Paths) []checkResult {
+	arch, err := agents.DetectArch()
+	if err != nil {
+		return nil
+	}
+	manager, err := agents.NewManager(paths)
+	if err != nil {
+		return []checkResult{{name: "Agents", status: checkFail, message: err.Error()}}
+	}
+
+	var results []checkResult
+	for _, agent := range manager.AllAgents() {
+		_, current, err := manager.ListVersions(agent.Name())
+		if err != nil {
+			results = append(results, checkResult{name: agent.Name(), status: checkFail, message: err.Error()})
+			continue
+		}
+		if current == "" {
+			continue
+		}
+		path := filepath.Join(paths.AgentVersionDir(agent.Name(), current), agent.BinaryName())
+		results = append(results, binaryResult(agent.Name(), path, agent.Interpreter() != nil, arch))
+	}
+	if len(results) == 0 {
+		return []checkResult{{name: "Agents", status: checkWarn, message: "no agents installed",
+			fix: "install them with 'agentbox agent update'"}}
+	}
+	return results
+}
+
+// configPathResult checks that an agent settings path has the type the sandbox
+// mounts, e.g. that Docker did not create ~/.claude.json as a directory.
+func configPathResult(p agentConfigPath) checkResult {
+	const name = "Agent settings"
+	info, err := os.Stat(p.path)
+	switch {
+	case os.IsNotExist(err):
+		return okResult(name, "%s is created on the next run", p.path)
+	case err != nil:
+		return checkResult{name: name, status: checkFail, message: err.Error()}
+	case p.dir && !info.IsDir():
+		return checkResult{name: name, status: checkFail, message: fmt.Sprintf("%s is a file, not a directory", p.path),
+			fix: fmt.Sprintf("move it away with 'mv %s %s.bak', the directory is created on the next run", p.path, p.path)}
+	case !p.dir && info.IsDir():
+		return checkResult{name: name, status: checkFail, message: fmt.Sprintf("%s is a directory, not a file", p.path),
+			fix: fmt.Sprintf("remove it with 'rm -r %s' (Docker creates it when the file is missing), "+
+				"the file is created on the next run", p.path)}
+	default:
+		return okResult(name, "%s", p.path)
+	}
+}
+
+// skeletonResults checks the project files in projectDir.
+func skeletonResults(projectDir, binaryVersion string) []checkResult {
+	const name = "Project"
+	if _, err := os.Stat(filepath.Join(projectDir, "docker-compose.agentbox.yml")); os.IsNotExist(err) {
+		return []checkResult{{name: name, status: checkWarn,
+			message: fmt.Sprintf("%s is not an agentbox project", projectDir),
+			fix:     "run 'agentbox init' in the project directory"}}
+	}
+
+	var results []checkResult
+	for _, file := range skeleton.Files() {
+		data, err := os.ReadFile(filepath.Join(projectDir, file))
+		switch {
+		case os.IsNotExist(err):
+			results = append(results, checkResult{name: name, status: checkFail,
+				message: fmt.Sprintf("%s is missing", file), fix: "run 'agentbox init'"})
+		case err != nil:
+			results = append(results, checkResult{name: name, status: checkFail, message: err.Error()})
+		case file == skeleton.LocalFile:
+			if _, applied := skeleton.Migrate(data); len(applied) > 0 {
+				results = append(results, checkResult{name: name, status: checkWarn,
+					message: fmt.Sprintf("%s needs %d migration(s)", file, len(applied)),
+					fix:     "run 'agentbox upgrade'"})
+			}
+		case skeleton.Edited(data):
+			results = append(results, checkResult{name: name, status: checkWarn,
+				message: fmt.Sprintf("%s was edited by hand, init and upgrade back it up and regenerate it", file),
+				fix:     fmt.Sprintf("move your changes to %s or to a template in ~/.agentbox/templates", skeleton.LocalFile)})
+		}
+	}
+
+	version, err := skeleton.ProjectVersion(projectDir)
+	if err != nil {
+		return append(results, checkResult{name: name, status: checkFail, message: err.Error()})
+	}
+	if skeletonOutdated(version, binaryVersion) {
+		if version == "" {
+			version = "an older version"
+		}
+		results = append(results, checkResult{name: name, status: checkWarn,
+			message: fmt.Sprintf("files were generated by agentbox %s", version),
+			fix:     "run 'agentbox upgrade'"})
+	}
+
+	if len(results) == 0 {
+		results = append(results, okResult(name, "files are up to date"))
+	}
+	return results
+}
+
+func (a *App) cmdDoctor(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Check the environment for common problems
+
+Usage:
+  agentbox doctor
+
+Checks the container runtime, its daemon, socket and compose, the host
+architecture, the ~/.agentbox directory and free disk space, the installed
+agents, the agent settings mounted into the sandbox and the project files in
+the current directory, and prints how to fix the problems found.
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlags(args); code != 0 {
+		return code
+	}
+
+	paths, err := config.NewPaths()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	results := runtimeResults()
+	results = append(results, archResult(), writableResult(paths.AgentboxDir))
+	if free, err := freeSpace(paths.AgentboxDir); err != nil {
+		results = append(results, checkResult{name: "Disk space", status: checkWarn, message: err.Error()})
+	} else {
+		results = append(results, diskResult(paths.AgentboxDir, free))
+	}
+	results = append(results, agentResults(paths)...)
+	for _, p := range agentConfigPaths(paths.HomeDir) {
+		results = append(results, configPathResult(p))
+	}
+	results = append(results, skeletonResults(cwd, a.Version)...)
+
+	failed, warned := 0, 0
+	for _, r := range results {
+		fmt.Printf("[%-4s] %s: %s\n", r.status, r.name, r.message)
+		if r.fix != "" {
+			fmt.Printf("       fix: %s\n", r.fix)
+		}
+		switch r.status {
+		case checkFail:
+			failed++
+		case checkWarn:
+			warned++
+		}
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf("%d problem(s) and %d warning(s) found\n", failed, warned)
+		return 1
+	}
+	if warned > 0 {
+		fmt.Printf("No problems found, %d warning(s)\n", warned)
+		return 0
+	}
+	fmt.Println("No problems found")
+	return 0
+}
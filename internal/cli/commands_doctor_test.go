@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/aleksey925/agentbox/internal/docker"
+	"github.com/aleksey925/agentbox/internal/skeleton"
+)
+
+func TestSocketResult(t *testing.T) {
+	permissionErr := &os.SyscallError{Syscall: "connect", Err: syscall.EACCES}
+	tests := []struct {
+		name        string
+		runtime     string
+		socket      string
+		pingErr     error
+		wantStatus  checkStatus
+		fixContains string
+	}{
+		{"reachable", docker.RuntimeDocker, "/var/run/docker.sock", nil, checkOK, ""},
+		{"no socket", docker.RuntimePodman, "", nil, checkWarn, "podman.socket"},
+		{"docker permission denied", docker.RuntimeDocker, "/var/run/docker.sock", permissionErr, checkFail, "usermod -aG docker"},
+		{"podman permission denied", docker.RuntimePodman, "/run/podman/podman.sock", permissionErr, checkFail, "CONTAINER_HOST"},
+		{"daemon down", docker.RuntimeDocker, "/var/run/docker.sock", errors.New("connection refused"), checkFail, "systemctl start docker"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result := socketResult(tt.runtime, tt.socket, tt.pingErr)
+
+			// assert
+			if result.status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", result.status, tt.wantStatus)
+			}
+			if !strings.Contains(result.fix, tt.fixContains) {
+				t.Errorf("fix = %q, want it to contain %q", result.fix, tt.fixContains)
+			}
+		})
+	}
+}
+
+func TestComposeResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		compose     string
+		output      string
+		err         error
+		wantStatus  checkStatus
+		wantMessage string
+	}{
+		{"plugin v2", "docker compose", "v2.27.0\n", nil, checkOK, "docker compose 2.27.0"},
+		{"compose v1", "docker-compose", "1.29.2\n", nil, checkWarn, "docker-compose 1.29.2 is outdated"},
+		{"before 2.24", "docker compose", "v2.23.3\n", nil, checkWarn, "docker compose 2.23.3 does not support allowlist egress mode"},
+		{"2.24.4", "docker compose", "2.24.4\n", nil, checkOK, "docker compose 2.24.4"},
+		{"missing", "podman compose", "", errors.New("exit status 125"), checkFail, "podman compose is not available"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result := composeResult(tt.compose, tt.output, tt.err)
+
+			// assert
+			if result.status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", result.status, tt.wantStatus)
+			}
+			if !strings.HasPrefix(result.message, tt.wantMessage) {
+				t.Errorf("message = %q, want prefix %q", result.message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestDiskResult(t *testing.T) {
+	// act
+	low := diskResult("/home/user/.agentbox", 1<<30)
+	enough := diskResult("/home/user/.agentbox", 20<<30)
+
+	// assert
+	if low.status != checkWarn || low.fix == "" {
+		t.Errorf("diskResult(1GiB) = %+v, want a warning with a fix", low)
+	}
+	if enough.status != checkOK {
+		t.Errorf("diskResult(20GiB) = %+v, want ok", enough)
+	}
+}
+
+func TestBinaryResult(t *testing.T) {
+	// arrange
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostArch := map[string]string{"amd64": "x64", "arm64": "arm64"}[runtime.GOARCH]
+	otherArch := "arm64"
+	if hostArch == "arm64" {
+		otherArch = "x64"
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "agent.js")
+	if err := os.WriteFile(script, []byte("console.log()"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	notExecutable := filepath.Join(dir, "agent")
+	if err := os.WriteFile(notExecutable, []byte("binary"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	notELF := filepath.Join(dir, "wrapper")
+	if err := os.WriteFile(notELF, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		path        string
+		interpreted bool
+		arch        string
+		wantStatus  checkStatus
+	}
+	tests := []testCase{
+		{"missing", filepath.Join(dir, "missing"), false, hostArch, checkFail},
+		{"script", script, true, hostArch, checkOK},
+		{"not executable", notExecutable, false, hostArch, checkFail},
+		{"not a Linux executable", notELF, false, hostArch, checkFail},
+		{"wrong architecture", executable, false, otherArch, checkFail},
+	}
+	if runtime.GOOS == "linux" && hostArch != "" {
+		tests = append(tests, testCase{"matching executable", executable, false, hostArch, checkOK})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result := binaryResult("claude", tt.path, tt.interpreted, tt.arch)
+
+			// assert
+			if result.status != tt.wantStatus {
+				t.Errorf("status = %q (%s), want %q", result.status, result.message, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestConfigPathResult(t *testing.T) {
+	// arrange
+	home := t.TempDir()
+	if err := os.Mkdir(filepath.Join(home, ".claude.json"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".codex"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(home, ".claude"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	statuses := map[string]checkStatus{}
+	for _, p := range agentConfigPaths(home) {
+		statuses[filepath.Base(p.path)] = configPathResult(p).status
+	}
+
+	// assert
+	expected := map[string]checkStatus{
+		".claude.json": checkFail,
+		".claude":      checkOK,
+		".copilot":     checkOK,
+		".codex":       checkFail,
+		".gemini":      checkOK,
+	}
+	for name, want := range expected {
+		if statuses[name] != want {
+			t.Errorf("%s status = %q, want %q", name, statuses[name], want)
+		}
+	}
+}
+
+func TestSkeletonResults(t *testing.T) {
+	// arrange
+	renderer := skeleton.NewRenderer()
+	vars := skeleton.Vars{Version: "1.2.0"}
+	initialized := t.TempDir()
+	if _, err := skeleton.CopyTo(initialized, renderer, vars, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := skeleton.CopyUserFilesIfMissing(initialized, renderer, vars); err != nil {
+		t.Fatal(err)
+	}
+	edited := t.TempDir()
+	if _, err := skeleton.CopyTo(edited, renderer, vars, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(edited, "Dockerfile.agentbox"), []byte("FROM debian\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		projectDir   string
+		version      string
+		wantStatuses []checkStatus
+		wantMessage  string
+	}{
+		{"not a project", t.TempDir(), "1.2.0", []checkStatus{checkWarn}, "is not an agentbox project"},
+		{"up to date", initialized, "1.2.0", []checkStatus{checkOK}, "files are up to date"},
+		{"outdated", initialized, "1.3.0", []checkStatus{checkWarn}, "generated by agentbox 1.2.0"},
+		{"edited and local file missing", edited, "1.2.0", []checkStatus{checkWarn, checkFail}, "edited by hand"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			results := skeletonResults(tt.projectDir, tt.version)
+
+			// assert
+			if len(results) != len(tt.wantStatuses) {
+				t.Fatalf("got %d results %+v, want %d", len(results), results, len(tt.wantStatuses))
+			}
+			for i, want := range tt.wantStatuses {
+				if results[i].status != want {
+					t.Errorf("results[%d].status = %q, want %q", i, results[i].status, want)
+				}
+			}
+			if !strings.Contains(results[0].message, tt.wantMessage) {
+				t.Errorf("message = %q, want it to contain %q", results[0].message, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aleksey925/agentbox/internal/agents"
+	"github.com/aleksey925/agentbox/internal/config"
+	"github.com/aleksey925/agentbox/internal/docker"
+	"github.com/aleksey925/agentbox/internal/egress"
+)
+
+const (
+	defaultExecTimeout = 30 * time.Minute
+
+	// exit codes follow timeout(1) and docker run conventions
+	execTimeoutExitCode = 124
+	execErrorExitCode   = 125
+)
+
+type execOptions struct {
+	agent   string
+	args    []string
+	timeout time.Duration
+	prompt  bool
+	json    bool
+	safe    bool
+	egress  string
+}
+
+// execResult is printed by `exec --json`.
+type execResult struct {
+	Agent      string `json:"agent"`
+	ExitCode   int    `json:"exit_code"`
+	TimedOut   bool   `json:"timed_out"`
+	DurationMs int64  `json:"duration_ms"`
+	// Message is the final answer of the agent.
+	Message string     `json:"message"`
+	Usage   *execUsage `json:"usage,omitempty"`
+	// Output is the structured output of the agent as printed.
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+	// BlockedHosts are hosts denied by the egress policy.
+	BlockedHosts []string `json:"blocked_hosts,omitempty"`
+}
+
+// execUsage is the token usage reported by the agent.
+type execUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+func (a *App) cmdExec(args []string) int {
+	flagArgs, _ := splitArgs(args)
+	if len(args) == 0 || hasHelpFlag(flagArgs) {
+		fmt.Printf(`Run an agent non-interactively in a fresh container
+
+Usage:
+  agentbox exec <agent> [flags] [-- <args...>]
+
+Arguments:
+  agent                             Agent name
+  args                              Agent arguments, or the prompt with --prompt/--json
+
+Flags:
+  --timeout <duration>              Stop the agent after duration (default: %s, 0 disables)
+  --prompt                          Run args as a prompt in the agent's non-interactive mode
+  --json                            Print a structured JSON result (implies --prompt)
+  --safe                            Start the agent without its default permission flags
+  --egress <mode>                   Network egress policy: open, allowlist (default: from config)
+
+The container has no TTY and is removed afterwards. The command exits with the
+agent's exit code, %d on timeout and %d if the container could not be started.
+
+Available agents: %s
+
+Examples:
+  agentbox exec claude --prompt -- "fix the failing tests"
+  agentbox exec codex --json --timeout 10m -- "summarize recent changes"
+  agentbox exec gemini -- --version
+`, defaultExecTimeout, execTimeoutExitCode, execErrorExitCode, availableAgentsStr())
+		if len(args) == 0 {
+			return 1
+		}
+		return 0
+	}
+
+	if code := RejectUnknownFlagsWithAllowed(flagArgs, CommandFlags()["exec"]); code != 0 {
+		return code
+	}
+
+	opts, err := parseExecFlags(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return execErrorExitCode
+	}
+
+	if code := a.ensureProjectReady(cwd); code != 0 {
+		return execErrorExitCode
+	}
+
+	sb, err := a.prepareSandbox(cwd, runOptions{safe: opts.safe, egress: opts.egress})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return execErrorExitCode
+	}
+	defer sb.cleanup()
+
+	command, agent, err := execCommand(cwd, opts, sb.Env)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return execErrorExitCode
+	}
+
+	return runExec(cwd, opts, command, agent, sb)
+}
+
+// parseExecFlags parses exec command flags.
+// Assumes validation was already done by RejectUnknownFlagsWithAllowed.
+func parseExecFlags(args []string) (execOptions, error) {
+	opts := execOptions{timeout: defaultExecTimeout}
+	flagArgs, passArgs := splitArgs(args)
+
+	for i := 0; i < len(flagArgs); i++ {
+		switch arg := flagArgs[i]; arg {
+		case "--timeout":
+			value, err := flagValue(flagArgs, i)
+			if err != nil {
+				return opts, err
+			}
+			i++
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout < 0 {
+				return opts, fmt.Errorf("invalid timeout: %s", value)
+			}
+			opts.timeout = timeout
+		case "--prompt":
+			opts.prompt = true
+		case "--json":
+			opts.json = true
+			opts.prompt = true
+		case "--safe":
+			opts.safe = true
+		case "--egress":
+			value, err := flagValue(flagArgs, i)
+			if err != nil {
+				return opts, err
+			}
+			i++
+			if err := config.ValidateEgress(value); err != nil {
+				return opts, err
+			}
+			opts.egress = value
+		default:
+			if opts.agent != "" {
+				return opts, fmt.Errorf("unexpected argument: %s (agent arguments go after --)", arg)
+			}
+			opts.agent = arg
+		}
+	}
+
+	if opts.agent == "" {
+		return opts, errors.New("agent name is required")
+	}
+	if !slices.Contains(agents.AllAgentNames(), opts.agent) {
+		return opts, fmt.Errorf("unknown agent: %s (available: %s)", opts.agent, availableAgentsStr())
+	}
+	if opts.prompt && len(passArgs) == 0 {
+		return opts, errors.New("prompt is required after --")
+	}
+
+	opts.args = passArgs
+	return opts, nil
+}
+
+// execCommand returns the container command for opts and, in prompt mode, the
+// agent that runs it. In prompt mode the launcher's default arguments are disabled
+// in env and passed explicitly, because some agents expect them after a subcommand.
+func execCommand(projectDir string, opts execOptions, env map[string]string) ([]string, agents.Agent, error) {
+	command := []string{agents.LauncherPath(opts.agent)}
+	if !opts.prompt {
+		return append(command, opts.args...), nil, nil
+	}
+
+	paths, err := config.NewPaths()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get paths: %w", err)
+	}
+	settings, err := config.LoadSettings(paths, projectDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load settings: %w", err)
+	}
+	manager, err := agents.NewManager(paths)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create agent manager: %w", err)
+	}
+	agent, _ := manager.GetAgent(opts.agent)
+
+	var defaultArgs []string
+	if !opts.safe {
+		defaultArgs = settings.AgentArgs(opts.agent, agent.DefaultArgs())
+	}
+	env[agents.SafeEnvVar] = "1"
+
+	prompt := strings.Join(opts.args, " ")
+	return append(command, agent.HeadlessArgs(prompt, defaultArgs, opts.json)...), agent, nil
+}
+
+func runExec(projectDir string, opts execOptions, command []string, agent agents.Agent, sb *sandbox) int {
+	ctx := context.Background()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	var stdout io.Writer = os.Stdout
+	var output bytes.Buffer
+	if opts.json {
+		stdout = &output
+	}
+
+	started := time.Now()
+	exitCode, err := docker.RunCommand(ctx, projectDir, sb.RunOptions, command, stdout, os.Stderr)
+	denied := finishSandbox(projectDir, sb, started)
+
+	result := newExecResult(opts, agent, exitCode, err, output.Bytes(), denied)
+	result.DurationMs = time.Since(started).Milliseconds()
+
+	if opts.json {
+		data, jsonErr := json.MarshalIndent(result, "", "  ")
+		if jsonErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", jsonErr)
+			return execErrorExitCode
+		}
+		fmt.Println(string(data))
+		return result.ExitCode
+	}
+
+	printDenied(os.Stderr, denied)
+	if result.Error != "" {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", result.Error)
+	}
+
+	return result.ExitCode
+}
+
+// newExecResult builds the result of a run that exited with exitCode and err.
+// In JSON mode output is the structured output of agent, which gives the message,
+// the usage and the error of a run the container finished.
+func newExecResult(opts execOptions, agent agents.Agent, exitCode int, err error, output []byte, denied []egress.Denied) execResult {
+	result := execResult{
+		Agent:    opts.agent,
+		ExitCode: exitCode,
+		Output:   string(output),
+	}
+	switch {
+	case errors.Is(err, docker.ErrTimeout):
+		result.TimedOut = true
+		result.ExitCode = execTimeoutExitCode
+		result.Error = fmt.Sprintf("timed out after %s", opts.timeout)
+	case err != nil:
+		result.ExitCode = execErrorExitCode
+		result.Error = err.Error()
+	case opts.json && agent != nil:
+		parsed, parseErr := agent.ParseHeadless(output)
+		if parseErr != nil {
+			result.Error = parseErr.Error()
+			break
+		}
+		result.Message = parsed.Message
+		result.Error = parsed.Error
+		if parsed.Usage != nil {
+			result.Usage = &execUsage{InputTokens: parsed.Usage.InputTokens, OutputTokens: parsed.Usage.OutputTokens}
+		}
+	}
+	if opts.json && result.Error == "" && result.ExitCode != 0 {
+		result.Error = fmt.Sprintf("agent exited with code %d", result.ExitCode)
+	}
+	for _, d := range denied {
+		result.BlockedHosts = append(result.BlockedHosts, d.Host)
+	}
+	return result
+}
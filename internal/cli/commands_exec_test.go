@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aleksey925/agentbox/internal/agents"
+	"github.com/aleksey925/agentbox/internal/docker"
+	"github.com/aleksey925/agentbox/internal/egress"
+)
+
+func TestParseExecFlags(t *testing.T) {
+	// act
+	opts, err := parseExecFlags([]string{"claude", "--timeout", "10m", "--json", "--", "fix", "tests"})
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.agent != "claude" {
+		t.Errorf("agent = %s, want claude", opts.agent)
+	}
+	if opts.timeout != 10*time.Minute {
+		t.Errorf("timeout = %s, want 10m", opts.timeout)
+	}
+	if !opts.json || !opts.prompt {
+		t.Errorf("json = %v, prompt = %v, want both true", opts.json, opts.prompt)
+	}
+	if !slices.Equal(opts.args, []string{"fix", "tests"}) {
+		t.Errorf("args = %v, want [fix tests]", opts.args)
+	}
+}
+
+func TestParseExecFlags__defaults(t *testing.T) {
+	// act
+	opts, err := parseExecFlags([]string{"gemini", "--", "--version"})
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.timeout != defaultExecTimeout {
+		t.Errorf("timeout = %s, want %s", opts.timeout, defaultExecTimeout)
+	}
+	if opts.prompt || opts.json || opts.safe {
+		t.Errorf("unexpected flags set: %+v", opts)
+	}
+	if !slices.Equal(opts.args, []string{"--version"}) {
+		t.Errorf("args = %v, want [--version]", opts.args)
+	}
+}
+
+func TestParseExecFlags__errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"missing agent", []string{"--json", "--", "hi"}},
+		{"unknown agent", []string{"unknown", "--", "hi"}},
+		{"missing timeout value", []string{"claude", "--timeout"}},
+		{"invalid timeout", []string{"claude", "--timeout", "soon"}},
+		{"negative timeout", []string{"claude", "--timeout", "-1m"}},
+		{"prompt without text", []string{"claude", "--prompt"}},
+		{"extra positional", []string{"claude", "hello"}},
+		{"invalid egress mode", []string{"claude", "--egress", "closed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			_, err := parseExecFlags(tt.args)
+
+			// assert
+			if err == nil {
+				t.Errorf("parseExecFlags(%v) should fail", tt.args)
+			}
+		})
+	}
+}
+
+func TestNewExecResult(t *testing.T) {
+	claudeOutput := `{"type":"result","subtype":"success","is_error":false,"result":"done","usage":{"input_tokens":10,"output_tokens":5}}`
+	tests := []struct {
+		name     string
+		opts     execOptions
+		exitCode int
+		err      error
+		output   string
+		denied   []egress.Denied
+		expected execResult
+	}{
+		{
+			name:     "success",
+			opts:     execOptions{agent: "claude", json: true},
+			output:   claudeOutput,
+			expected: execResult{Agent: "claude", Message: "done", Usage: &execUsage{InputTokens: 10, OutputTokens: 5}, Output: claudeOutput},
+		},
+		{
+			name:     "timeout",
+			opts:     execOptions{agent: "claude", json: true, timeout: 10 * time.Minute},
+			exitCode: -1,
+			err:      fmt.Errorf("run: %w", docker.ErrTimeout),
+			expected: execResult{Agent: "claude", ExitCode: execTimeoutExitCode, TimedOut: true, Error: "timed out after 10m0s"},
+		},
+		{
+			name:     "container error",
+			opts:     execOptions{agent: "claude", json: true},
+			exitCode: -1,
+			err:      errors.New("no such image"),
+			expected: execResult{Agent: "claude", ExitCode: execErrorExitCode, Error: "no such image"},
+		},
+		{
+			name:     "agent exit code",
+			opts:     execOptions{agent: "claude", json: true},
+			exitCode: 1,
+			output:   `{"type":"result","subtype":"error_during_execution","is_error":true,"result":"tool failed"}`,
+			expected: execResult{Agent: "claude", ExitCode: 1, Error: "tool failed",
+				Output: `{"type":"result","subtype":"error_during_execution","is_error":true,"result":"tool failed"}`},
+		},
+		{
+			name:     "exit code without agent error",
+			opts:     execOptions{agent: "claude", json: true},
+			exitCode: 2,
+			output:   claudeOutput,
+			expected: execResult{Agent: "claude", ExitCode: 2, Message: "done", Usage: &execUsage{InputTokens: 10, OutputTokens: 5},
+				Output: claudeOutput, Error: "agent exited with code 2"},
+		},
+		{
+			name:   "blocked hosts",
+			opts:   execOptions{agent: "claude", json: true},
+			output: claudeOutput,
+			denied: []egress.Denied{{Host: "evil.example", Count: 3}, {Host: "pypi.org", Count: 1}},
+			expected: execResult{Agent: "claude", Message: "done", Usage: &execUsage{InputTokens: 10, OutputTokens: 5}, Output: claudeOutput,
+				BlockedHosts: []string{"evil.example", "pypi.org"}},
+		},
+		{
+			name:     "unparsable output",
+			opts:     execOptions{agent: "claude", json: true},
+			exitCode: 1,
+			output:   "Error: not logged in\n",
+			expected: execResult{Agent: "claude", ExitCode: 1, Output: "Error: not logged in\n",
+				Error: "parse claude output: invalid character 'E' looking for beginning of value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result := newExecResult(tt.opts, &agents.ClaudeAgent{}, tt.exitCode, tt.err, []byte(tt.output), tt.denied)
+
+			// assert
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("newExecResult =\n%+v\nwant:\n%+v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/aleksey925/agentbox/internal/docker"
+	"github.com/aleksey925/agentbox/internal/skeleton"
+)
+
+// latestTag is the tag of the most recently built base image, the default of
+// the project Dockerfile.
+const latestTag = "latest"
+
+// buildBaseImage builds the shared base image of this agentbox version from
+// the embedded Dockerfile.
+func (a *App) buildBaseImage(noCache bool) error {
+	dir, err := os.MkdirTemp("", "agentbox-base-")
+	if err != nil {
+		return fmt.Errorf("create build context: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(dockerfile, skeleton.BaseDockerfile(), 0o644); err != nil {
+		return fmt.Errorf("write base Dockerfile: %w", err)
+	}
+
+	return docker.BuildImage(dir, docker.ImageBuildOptions{
+		Tags:       []string{docker.BaseImage(a.Version), docker.BaseRepository + ":" + latestTag},
+		Labels:     map[string]string{docker.LabelVersion: a.Version},
+		Dockerfile: dockerfile,
+		NoCache:    noCache,
+	})
+}
+
+// ensureBaseImage builds the base image of this agentbox version if it is missing.
+func (a *App) ensureBaseImage() error {
+	image := docker.BaseImage(a.Version)
+	exists, err := docker.ImageExists(image)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	fmt.Printf("Building base image %s...\n", image)
+	return a.buildBaseImage(false)
+}
+
+func (a *App) cmdImage(args []string) int {
+	if len(args) > 0 && hasHelpFlag(args[:1]) {
+		fmt.Print(`Manage the shared base image
+
+Usage:
+  agentbox image [command]
+
+Commands:
+  ls                                List base images (default)
+  build                             Build the base image of this agentbox version
+  prune                             Remove base images of other agentbox versions
+
+The base image contains the system packages, mise and the box user and is shared
+by all projects. It is built once per agentbox version and tagged
+agentbox-base:<version>; each project only adds its mise.toml tools on top.
+
+Use "agentbox image <command> --help" for more information about a command.
+`)
+		return 0
+	}
+
+	if len(args) > 0 {
+		if code := RejectUnknownFlags(args[:1]); code != 0 {
+			return code
+		}
+	}
+
+	if len(args) == 0 {
+		return a.imageLs(nil)
+	}
+
+	subcmd := args[0]
+	subargs := args[1:]
+
+	switch subcmd {
+	case "ls":
+		return a.imageLs(subargs)
+	case "build":
+		return a.imageBuild(subargs)
+	case "prune":
+		return a.imagePrune(subargs)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown image subcommand: %s\n", subcmd)
+		return 1
+	}
+}
+
+func (a *App) imageLs(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`List base images
+
+Usage:
+  agentbox image ls
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlags(args); code != 0 {
+		return code
+	}
+
+	images, err := docker.ListImages(docker.BaseRepository)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if len(images) == 0 {
+		fmt.Println("No base images, build one with 'agentbox image build'")
+		return 0
+	}
+
+	current := docker.BaseImage(a.Version)
+	table := NewTable("IMAGE", "ID", "CREATED", "SIZE")
+	for _, image := range images {
+		ref := image.Ref()
+		if ref == current {
+			ref += " (current)"
+		}
+		table.AddRow(ref, image.ID, image.Created, image.Size)
+	}
+	table.Render()
+	return 0
+}
+
+func (a *App) imageBuild(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Build the base image of this agentbox version
+
+Usage:
+  agentbox image build [flags]
+
+Flags:
+  --no-cache                        Build without Docker cache, e.g. to get system updates
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlagsWithAllowed(args, ImageBuildFlags()); code != 0 {
+		return code
+	}
+
+	fmt.Printf("Building base image %s...\n", docker.BaseImage(a.Version))
+	if err := a.buildBaseImage(slices.Contains(args, "--no-cache")); err != nil {
+		fmt.Fprintf(os.Stderr, "Error building image: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func (a *App) imagePrune(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Remove base images of other agentbox versions
+
+Usage:
+  agentbox image prune
+
+The image of this agentbox version and the latest tag are kept.
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlags(args); code != 0 {
+		return code
+	}
+
+	images, err := docker.ListImages(docker.BaseRepository)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	stale := staleBaseImages(images, docker.BaseImage(a.Version))
+	if len(stale) == 0 {
+		fmt.Println("No base images to remove")
+		return 0
+	}
+
+	if err := docker.RemoveImages(stale...); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, ref := range stale {
+		fmt.Printf("Removed %s\n", ref)
+	}
+	return 0
+}
+
+// staleBaseImages returns the base images other than current and the latest tag.
+// Untagged images are left to 'docker image prune'.
+func staleBaseImages(images []docker.Image, current string) []string {
+	var stale []string
+	for _, image := range images {
+		ref := image.Ref()
+		if ref == current || image.Tag == latestTag || image.Tag == "<none>" {
+			continue
+		}
+		stale = append(stale, ref)
+	}
+	return stale
+}
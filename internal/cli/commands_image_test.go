@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aleksey925/agentbox/internal/docker"
+)
+
+func TestStaleBaseImages(t *testing.T) {
+	// arrange
+	images := []docker.Image{
+		{ID: "a1", Repository: docker.BaseRepository, Tag: "1.4.0"},
+		{ID: "a1", Repository: docker.BaseRepository, Tag: "latest"},
+		{ID: "b2", Repository: docker.BaseRepository, Tag: "1.3.0"},
+		{ID: "c3", Repository: docker.BaseRepository, Tag: "1.2.1"},
+		{ID: "d4", Repository: docker.BaseRepository, Tag: "<none>"},
+	}
+
+	// act
+	result := staleBaseImages(images, docker.BaseImage("1.4.0"))
+
+	// assert
+	expected := []string{"agentbox-base:1.3.0", "agentbox-base:1.2.1"}
+	if !slices.Equal(result, expected) {
+		t.Errorf("staleBaseImages() = %v, want %v", result, expected)
+	}
+}
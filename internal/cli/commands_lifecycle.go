@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/aleksey925/agentbox/internal/docker"
+)
+
+// projectContainers returns the running containers chosen by args: the
+// container with the given id or name, all containers of the current project
+// if all is set, the only one, or one selected interactively. A session name
+// also finds a container of another project.
+func projectContainers(args []string, all bool) ([]docker.Container, int) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return nil, 1
+	}
+
+	if id := firstPositional(args); id != "" {
+		c, ok, err := findContainer(cwd, id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return nil, 1
+		}
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: no running agentbox container %s in this project\n", id)
+			fmt.Fprintln(os.Stderr, "Use 'agentbox ps' to list containers")
+			return nil, 1
+		}
+		return []docker.Container{c}, 0
+	}
+
+	containers, err := docker.ListContainers(cwd, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return nil, 1
+	}
+
+	if len(containers) == 0 {
+		fmt.Println("No running agentbox containers in this project")
+		return nil, 1
+	}
+
+	if all || len(containers) == 1 {
+		return containers, 0
+	}
+
+	c, ok := selectContainer(containers)
+	if !ok {
+		return nil, 1
+	}
+	return []docker.Container{c}, 0
+}
+
+// findContainer looks up a container of the project by id or name, then a
+// named session of any project.
+func findContainer(projectDir, id string) (docker.Container, bool, error) {
+	containers, err := docker.ListContainers(projectDir, false)
+	if err != nil {
+		return docker.Container{}, false, err
+	}
+	if c, ok, err := docker.FindContainer(containers, id); ok || err != nil {
+		return c, ok, err
+	}
+
+	containers, err = docker.ListContainers("", true)
+	if err != nil {
+		return docker.Container{}, false, err
+	}
+	for _, c := range containers {
+		if c.Session == id {
+			return c, true, nil
+		}
+	}
+	return docker.Container{}, false, nil
+}
+
+func containerIDs(containers []docker.Container) []string {
+	ids := make([]string, 0, len(containers))
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+// validateContainerArgs rejects unknown flags and more than one container id.
+func validateContainerArgs(args, allowedFlags []string) int {
+	if code := RejectUnknownFlagsWithAllowed(args, allowedFlags); code != 0 {
+		return code
+	}
+	positional := 0
+	for _, arg := range args {
+		if arg != "" && arg[0] != '-' {
+			positional++
+		}
+	}
+	if positional > 1 {
+		fmt.Fprintln(os.Stderr, "Error: only one container id can be given")
+		return 1
+	}
+	return 0
+}
+
+func (a *App) cmdStop(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Stop running containers
+
+Usage:
+  agentbox stop [container-id] [flags]
+
+Arguments:
+  container-id                      Container ID, name or session name (optional, interactive if omitted)
+
+Flags:
+  --all                             Stop all containers of the project
+
+Containers are given a few seconds to exit and are killed after that.
+`)
+		return 0
+	}
+
+	return a.containerAction(args, CommandFlags()["stop"], "Stopped", docker.Stop)
+}
+
+func (a *App) cmdKill(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Kill running containers immediately
+
+Usage:
+  agentbox kill [container-id] [flags]
+
+Arguments:
+  container-id                      Container ID, name or session name (optional, interactive if omitted)
+
+Flags:
+  --all                             Kill all containers of the project
+`)
+		return 0
+	}
+
+	return a.containerAction(args, CommandFlags()["kill"], "Killed", docker.Kill)
+}
+
+func (a *App) cmdRestart(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Restart a running container
+
+Usage:
+  agentbox restart [container-id]
+
+Arguments:
+  container-id                      Container ID, name or session name (optional, interactive if omitted)
+
+The terminal session attached to the container ends. Use 'agentbox attach'
+to connect to the restarted container.
+`)
+		return 0
+	}
+
+	return a.containerAction(args, CommandFlags()["restart"], "Restarted", docker.Restart)
+}
+
+// containerAction applies action to the containers chosen by args.
+func (a *App) containerAction(args, allowedFlags []string, done string, action func(ids ...string) error) int {
+	if code := validateContainerArgs(args, allowedFlags); code != 0 {
+		return code
+	}
+
+	containers, code := projectContainers(args, slices.Contains(args, "--all"))
+	if code != 0 {
+		return code
+	}
+
+	ids := containerIDs(containers)
+	if err := action(ids...); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, id := range ids {
+		fmt.Printf("%s %s\n", done, id)
+	}
+	return 0
+}
+
+func (a *App) cmdLogs(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Show the output of a container
+
+Usage:
+  agentbox logs [container-id] [flags]
+
+Arguments:
+  container-id                      Container ID, name or session name (optional, interactive if omitted)
+
+Flags:
+  -f, --follow                      Follow the output until the container exits
+`)
+		return 0
+	}
+
+	if code := validateContainerArgs(args, CommandFlags()["logs"]); code != 0 {
+		return code
+	}
+
+	containers, code := projectContainers(args, false)
+	if code != 0 {
+		return code
+	}
+
+	follow := slices.Contains(args, "-f") || slices.Contains(args, "--follow")
+	if err := docker.Logs(containers[0].ID, follow, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
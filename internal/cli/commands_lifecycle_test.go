@@ -0,0 +1,29 @@
+package cli
+
+import "testing"
+
+func TestValidateContainerArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"no args", nil, 0},
+		{"container id", []string{"abc123"}, 0},
+		{"id and flag", []string{"abc123", "--all"}, 0},
+		{"two ids", []string{"abc123", "def456"}, 1},
+		{"unknown flag", []string{"--force"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			code := validateContainerArgs(tt.args, []string{"--all"})
+
+			// assert
+			if code != tt.expected {
+				t.Errorf("validateContainerArgs(%v) = %d, want %d", tt.args, code, tt.expected)
+			}
+		})
+	}
+}
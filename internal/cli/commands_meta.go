@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/aleksey925/agentbox/internal/config"
 )
 
 // Command metadata for consistency between help, completions, and validation.
@@ -14,12 +16,24 @@ import (
 func AllCommands() []string {
 	return []string{
 		"init",
+		"upgrade",
 		"run",
+		"exec",
 		"attach",
 		"ps",
+		"stop",
+		"kill",
+		"restart",
+		"logs",
+		"worktree",
+		"checkpoints",
+		"sessions",
+		"image",
 		"agent",
 		"self",
+		"config",
 		"clean",
+		"doctor",
 		"completion",
 		"help",
 		"version",
@@ -30,14 +44,26 @@ func AllCommands() []string {
 // Empty slice means no flags (except global -h/--help).
 func CommandFlags() map[string][]string {
 	return map[string][]string{
-		"init":       {}, // no flags
-		"run":        {"--build", "--build-no-cache"},
-		"attach":     {}, // no flags, only positional args
-		"ps":         {"-a", "--all"},
-		"agent":      {}, // has subcommands, not flags
-		"self":       {}, // has subcommands, not flags
-		"clean":      {}, // no flags
-		"completion": {}, // no flags, only positional args
+		"init":        {}, // no flags
+		"upgrade":     {"-y", "--yes", "--dry-run"},
+		"run":         {"--build", "--build-no-cache", "--no-auto-build", "--safe", "--name", "--worktree", "--egress", "--review", "--record", "--cpus", "--memory", "--pids-limit", "-p", "--publish", "--host-gateway", "--forward-git"},
+		"exec":        {"--timeout", "--prompt", "--json", "--safe", "--egress"},
+		"attach":      {"--agent", "--user", "--workdir", "--record"},
+		"ps":          {"-a", "--all"},
+		"stop":        {"--all"},
+		"kill":        {"--all"},
+		"restart":     {}, // no flags, only positional args
+		"logs":        {"-f", "--follow"},
+		"worktree":    {}, // has subcommands, not flags
+		"checkpoints": {}, // has subcommands, not flags
+		"sessions":    {}, // has subcommands, not flags
+		"image":       {}, // has subcommands, not flags
+		"agent":       {}, // has subcommands, not flags
+		"self":        {}, // has subcommands, not flags
+		"config":      {}, // has subcommands, not flags
+		"clean":       {}, // no flags
+		"doctor":      {}, // no flags
+		"completion":  {}, // no flags, only positional args
 	}
 }
 
@@ -56,6 +82,80 @@ func SelfUninstallFlags() []string {
 	return []string{"--purge"}
 }
 
+// WorktreeSubcommands returns valid worktree subcommands.
+func WorktreeSubcommands() []string {
+	return []string{"ls", "rm", "merge"}
+}
+
+// WorktreeLsFlags returns valid flags for worktree ls subcommand.
+func WorktreeLsFlags() []string {
+	return []string{"-q", "--quiet"}
+}
+
+// WorktreeRmFlags returns valid flags for worktree rm subcommand.
+func WorktreeRmFlags() []string {
+	return []string{"--force"}
+}
+
+// WorktreeMergeFlags returns valid flags for worktree merge subcommand.
+func WorktreeMergeFlags() []string {
+	return []string{"--squash"}
+}
+
+// CheckpointsSubcommands returns valid checkpoints subcommands.
+func CheckpointsSubcommands() []string {
+	return []string{"ls", "restore"}
+}
+
+// CheckpointsLsFlags returns valid flags for checkpoints ls subcommand.
+func CheckpointsLsFlags() []string {
+	return []string{"-q", "--quiet"}
+}
+
+// SessionsSubcommands returns valid sessions subcommands.
+func SessionsSubcommands() []string {
+	return []string{"ls", "play"}
+}
+
+// SessionsLsFlags returns valid flags for sessions ls subcommand.
+func SessionsLsFlags() []string {
+	return []string{"-q", "--quiet"}
+}
+
+// SessionsPlayFlags returns valid flags for sessions play subcommand.
+func SessionsPlayFlags() []string {
+	return []string{"--speed", "--idle-limit"}
+}
+
+// ImageSubcommands returns valid image subcommands.
+func ImageSubcommands() []string {
+	return []string{"ls", "build", "prune"}
+}
+
+// ImageBuildFlags returns valid flags for image build subcommand.
+func ImageBuildFlags() []string {
+	return []string{"--no-cache"}
+}
+
+// ConfigSubcommands returns valid config subcommands.
+func ConfigSubcommands() []string {
+	return []string{"list", "get", "set", "unset"}
+}
+
+// ConfigSetFlags returns valid flags for config set and unset subcommands.
+func ConfigSetFlags() []string {
+	return []string{"--project"}
+}
+
+// ConfigKeys returns the names of the documented settings.
+func ConfigKeys() []string {
+	keys := make([]string, 0, len(config.Keys()))
+	for _, k := range config.Keys() {
+		keys = append(keys, k.Name)
+	}
+	return keys
+}
+
 // CompletionShells returns valid shells for completion command.
 func CompletionShells() []string {
 	return []string{"bash", "zsh"}
@@ -71,6 +171,20 @@ func AllSubcommandPaths() [][]string {
 		{"self", "update"},
 		{"self", "uninstall"},
 		{"self", "versions"},
+		{"worktree", "ls"},
+		{"worktree", "rm", "dummy"},
+		{"worktree", "merge", "dummy"},
+		{"checkpoints", "ls"},
+		{"checkpoints", "restore", "dummy"},
+		{"sessions", "ls"},
+		{"sessions", "play", "dummy"},
+		{"image", "ls"},
+		{"image", "build"},
+		{"image", "prune"},
+		{"config", "list"},
+		{"config", "get", "network.egress"},
+		{"config", "set", "network.egress", "open"},
+		{"config", "unset", "network.egress"},
 	}
 }
 
@@ -133,3 +247,22 @@ func RejectUnknownFlagsWithAllowed(args, allowedFlags []string) int {
 	}
 	return 0
 }
+
+// splitArgs splits args at the first "--" separator.
+// Arguments after the separator are passed through and never treated as flags.
+func splitArgs(args []string) (before, after []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	return args, nil
+}
+
+// flagValue returns the value of the flag at args[i], which is the next argument.
+func flagValue(args []string, i int) (string, error) {
+	if i+1 >= len(args) {
+		return "", fmt.Errorf("flag %s requires a value", args[i])
+	}
+	return args[i+1], nil
+}
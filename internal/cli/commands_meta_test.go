@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		expectedBefore []string
+		expectedAfter  []string
+	}{
+		{"no separator", []string{"claude", "--json"}, []string{"claude", "--json"}, nil},
+		{"separator", []string{"claude", "--", "-p", "hi"}, []string{"claude"}, []string{"-p", "hi"}},
+		{"only first separator splits", []string{"a", "--", "b", "--", "c"}, []string{"a"}, []string{"b", "--", "c"}},
+		{"trailing separator", []string{"a", "--"}, []string{"a"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			before, after := splitArgs(tt.args)
+
+			// assert
+			if !slices.Equal(before, tt.expectedBefore) {
+				t.Errorf("before = %v, want %v", before, tt.expectedBefore)
+			}
+			if !slices.Equal(after, tt.expectedAfter) {
+				t.Errorf("after = %v, want %v", after, tt.expectedAfter)
+			}
+		})
+	}
+}
+
+func TestFlagValue(t *testing.T) {
+	// arrange
+	args := []string{"--timeout", "5m", "--name"}
+
+	// act
+	value, err := flagValue(args, 0)
+	_, missingErr := flagValue(args, 2)
+
+	// assert
+	if err != nil || value != "5m" {
+		t.Errorf("flagValue = %q, %v, want 5m", value, err)
+	}
+	if missingErr == nil {
+		t.Error("expected error for flag without value")
+	}
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aleksey925/agentbox/internal/review"
+)
+
+// reviewChanges shows the changes made in the working copy of a review session
+// and applies the ones the user accepts to the project.
+func reviewChanges(s *review.Session, in io.Reader, out io.Writer) error {
+	changes, err := s.Changes()
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No changes made in the sandbox")
+		return s.Remove()
+	}
+
+	fmt.Fprintln(out, "\nChanges made in the sandbox:")
+	for _, c := range changes {
+		fmt.Fprintf(out, "  %s\n", describeChange(s, c))
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Fprint(out, "Apply changes? [a]ll, [s]elect, [d]iff, [n]one: ")
+		answer, err := readAnswer(reader)
+		if err != nil {
+			fmt.Fprintf(out, "\nNo answer, changes are kept in %s\n", s.WorkDir())
+			return nil
+		}
+
+		switch answer {
+		case "a", "all":
+			return finishReview(s, applyChanges(s, changes, out), out)
+		case "s", "select":
+			pending, ok := selectChanges(s, changes, reader, out)
+			if !ok {
+				fmt.Fprintf(out, "\nNo answer, changes are kept in %s\n", s.WorkDir())
+				return nil
+			}
+			return finishReview(s, pending, out)
+		case "d", "diff":
+			for _, c := range changes {
+				printChangeDiff(s, c, out)
+			}
+		case "n", "none":
+			fmt.Fprintln(out, "Changes discarded")
+			return s.Remove()
+		}
+	}
+}
+
+// finishReview removes the working copy if every change was applied or
+// declined, and keeps it when pending changes were skipped, failed or left unanswered.
+func finishReview(s *review.Session, pending int, out io.Writer) error {
+	if pending > 0 {
+		fmt.Fprintf(out, "%d changes were not applied, they are kept in %s\n", pending, s.WorkDir())
+		return nil
+	}
+	return s.Remove()
+}
+
+// applyChanges applies all changes except files that were also changed on the
+// host. Returns the number of changes that were skipped or failed.
+func applyChanges(s *review.Session, changes []review.Change, out io.Writer) int {
+	applied, pending := 0, 0
+	for _, c := range changes {
+		if c.Conflict {
+			fmt.Fprintf(out, "Skipped %s: changed on the host during the session\n", c.Path)
+			pending++
+			continue
+		}
+		if err := s.Apply(c); err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+			pending++
+			continue
+		}
+		applied++
+	}
+	fmt.Fprintf(out, "Applied %d of %d changes\n", applied, len(changes))
+	return pending
+}
+
+// selectChanges asks about every change. Returns the number of changes that
+// failed or were left unanswered after quit, and false if input ended.
+func selectChanges(s *review.Session, changes []review.Change, reader *bufio.Reader, out io.Writer) (int, bool) {
+	applied, pending := 0, 0
+	for i := 0; i < len(changes); i++ {
+		c := changes[i]
+		fmt.Fprintf(out, "Apply %s? [y]es, [n]o, [d]iff, [q]uit: ", describeChange(s, c))
+		answer, err := readAnswer(reader)
+		if err != nil {
+			return 0, false
+		}
+
+		switch answer {
+		case "y", "yes":
+			if err := s.Apply(c); err != nil {
+				fmt.Fprintf(out, "Error: %v\n", err)
+				pending++
+				continue
+			}
+			applied++
+		case "d", "diff":
+			printChangeDiff(s, c, out)
+			i-- // ask again
+		case "q", "quit":
+			fmt.Fprintf(out, "Applied %d of %d changes\n", applied, len(changes))
+			return pending + len(changes) - i, true
+		case "n", "no":
+		default:
+			i--
+		}
+	}
+	fmt.Fprintf(out, "Applied %d of %d changes\n", applied, len(changes))
+	return pending, true
+}
+
+func describeChange(s *review.Session, c review.Change) string {
+	line := string(c.Kind) + " " + c.Path
+	if c.Kind == review.Modified {
+		inserted, deleted := s.Stats(c)
+		line += fmt.Sprintf(" (+%d -%d)", inserted, deleted)
+	}
+	if c.Conflict {
+		line += " [changed on host]"
+	}
+	return line
+}
+
+func printChangeDiff(s *review.Session, c review.Change, out io.Writer) {
+	text, err := s.Diff(c)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return
+	}
+	fmt.Fprint(out, text)
+}
+
+// readAnswer reads a trimmed lowercase line. Returns an error at the end of input.
+func readAnswer(reader *bufio.Reader) (string, error) {
+	answer, err := reader.ReadString('\n')
+	if err != nil && answer == "" {
+		return "", err
+	}
+	return strings.TrimSpace(strings.ToLower(answer)), nil
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aleksey925/agentbox/internal/review"
+)
+
+// newReviewSession creates a project with main.go and a session where main.go was changed.
+func newReviewSession(t *testing.T) *review.Session {
+	t.Helper()
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := review.Create(t.TempDir(), projectDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.WorkDir(), "main.go"), []byte("package app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func readProjectFile(t *testing.T, s *review.Session, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(s.ProjectDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestReviewChanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		sessionKept bool
+	}{
+		{"apply all", "a\n", "package app\n", false},
+		{"apply none", "n\n", "package main\n", false},
+		{"diff then all", "d\na\n", "package app\n", false},
+		{"select yes", "s\ny\n", "package app\n", false},
+		{"select no", "s\nn\n", "package main\n", false},
+		{"select quit", "s\nq\n", "package main\n", true},
+		{"no input", "", "package main\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			s := newReviewSession(t)
+			var out bytes.Buffer
+
+			// act
+			err := reviewChanges(s, strings.NewReader(tt.input), &out)
+
+			// assert
+			if err != nil {
+				t.Fatalf("reviewChanges error: %v", err)
+			}
+			if content := readProjectFile(t, s, "main.go"); content != tt.expected {
+				t.Errorf("main.go = %q, want %q", content, tt.expected)
+			}
+			_, statErr := os.Stat(s.Dir)
+			if kept := statErr == nil; kept != tt.sessionKept {
+				t.Errorf("session kept = %v, want %v", kept, tt.sessionKept)
+			}
+			if !strings.Contains(out.String(), "M main.go (+1 -1)") {
+				t.Errorf("output missing change summary:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestReviewChanges__conflict_keeps_copy(t *testing.T) {
+	// arrange
+	s := newReviewSession(t)
+	if err := os.WriteFile(filepath.Join(s.ProjectDir, "main.go"), []byte("package host\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+
+	// act
+	err := reviewChanges(s, strings.NewReader("a\n"), &out)
+
+	// assert
+	if err != nil {
+		t.Fatalf("reviewChanges error: %v", err)
+	}
+	if content := readProjectFile(t, s, "main.go"); content != "package host\n" {
+		t.Errorf("main.go = %q, want the host change kept", content)
+	}
+	if _, err := os.Stat(filepath.Join(s.WorkDir(), "main.go")); err != nil {
+		t.Errorf("working copy removed: %v", err)
+	}
+	if !strings.Contains(out.String(), "kept in "+s.WorkDir()) {
+		t.Errorf("output does not name the kept copy:\n%s", out.String())
+	}
+}
+
+func TestReviewChanges__no_changes(t *testing.T) {
+	// arrange
+	projectDir := t.TempDir()
+	s, err := review.Create(t.TempDir(), projectDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+
+	// act
+	err = reviewChanges(s, strings.NewReader(""), &out)
+
+	// assert
+	if err != nil {
+		t.Fatalf("reviewChanges error: %v", err)
+	}
+	if !strings.Contains(out.String(), "No changes") {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestParseRunFlags__review_with_worktree(t *testing.T) {
+	// arrange
+	app := &App{Version: "test"}
+
+	// act
+	_, err := app.parseRunFlags([]string{"--review", "--worktree", "feature-x"})
+
+	// assert
+	if err == nil {
+		t.Error("--review with --worktree should fail")
+	}
+}
@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aleksey925/agentbox/internal/config"
+	"github.com/aleksey925/agentbox/internal/recording"
+)
+
+// defaultIdleLimit caps pauses when a session is replayed.
+const defaultIdleLimit = 2 * time.Second
+
+// sessionRecorder records an interactive session into the project's sessions directory.
+type sessionRecorder struct {
+	*recording.Recorder
+	id string
+}
+
+// newSessionRecorder returns nil if recording is enabled neither by the flag nor in settings.
+func newSessionRecorder(projectDir, title string, record bool, settings config.RecordingSettings) (*sessionRecorder, error) {
+	if !record && !settings.Enabled {
+		return nil, nil
+	}
+
+	paths, err := config.NewPaths()
+	if err != nil {
+		return nil, fmt.Errorf("get paths: %w", err)
+	}
+
+	redactor, err := recording.NewRedactor(settings.Redact)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := paths.ProjectSessionsDir(projectDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create sessions dir: %w", err)
+	}
+
+	width, height := recording.TerminalSize()
+	path := recording.NewPath(dir, time.Now())
+	recorder, err := recording.Create(path, recording.Header{
+		Width:  width,
+		Height: height,
+		Title:  title,
+		Env:    map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+	}, redactor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sessionRecorder{Recorder: recorder, id: recording.ID(path)}, nil
+}
+
+// finish closes the recording and tells how to replay it.
+func (s *sessionRecorder) finish() {
+	if err := s.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: recording failed: %v\n", err)
+		return
+	}
+	fmt.Printf("Session recorded, replay with 'agentbox sessions play %s'\n", s.id)
+}
+
+func (a *App) cmdSessions(args []string) int {
+	if len(args) > 0 && hasHelpFlag(args[:1]) {
+		fmt.Print(`Manage recorded terminal sessions
+
+Usage:
+  agentbox sessions [command]
+
+Commands:
+  ls                                List recorded sessions (default)
+  play <id>                         Replay a recorded session
+
+Sessions of 'agentbox run --record' and 'agentbox attach --record' are saved as
+asciicast v2 files in ~/.agentbox/sessions/<project>/ and can also be played with
+asciinema. Well-known API keys are redacted; add your own patterns in
+~/.agentbox/config.toml or .agentbox.toml:
+  [recording]
+  enabled = true                    # record every session
+  redact = ['corp_[a-z0-9]{32}']
+
+Use "agentbox sessions <command> --help" for more information about a command.
+`)
+		return 0
+	}
+
+	if len(args) > 0 {
+		if code := RejectUnknownFlags(args[:1]); code != 0 {
+			return code
+		}
+	}
+
+	if len(args) == 0 {
+		return a.sessionsLs(nil)
+	}
+
+	subcmd := args[0]
+	subargs := args[1:]
+
+	switch subcmd {
+	case "ls":
+		return a.sessionsLs(subargs)
+	case "play":
+		return a.sessionsPlay(subargs)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown sessions subcommand: %s\n", subcmd)
+		return 1
+	}
+}
+
+// projectSessionsDir returns the sessions directory of the current project.
+func projectSessionsDir() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	paths, err := config.NewPaths()
+	if err != nil {
+		return "", fmt.Errorf("get paths: %w", err)
+	}
+	return paths.ProjectSessionsDir(cwd), nil
+}
+
+func (a *App) sessionsLs(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`List recorded sessions
+
+Usage:
+  agentbox sessions ls [flags]
+
+Flags:
+  -q, --quiet                       Print only session ids
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlagsWithAllowed(args, SessionsLsFlags()); code != 0 {
+		return code
+	}
+
+	quiet := slices.Contains(args, "-q") || slices.Contains(args, "--quiet")
+
+	dir, err := projectSessionsDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	infos, err := recording.List(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if quiet {
+		for _, info := range infos {
+			fmt.Println(info.ID)
+		}
+		return 0
+	}
+
+	if len(infos) == 0 {
+		fmt.Println("No recorded sessions")
+		return 0
+	}
+
+	table := NewTable("ID", "STARTED", "DURATION", "TITLE")
+	for _, info := range infos {
+		table.AddRow(info.ID, info.Started().Format(time.DateTime), info.Duration.Round(time.Second).String(), info.Header.Title)
+	}
+	table.Render()
+	return 0
+}
+
+func (a *App) sessionsPlay(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Replay a recorded session
+
+Usage:
+  agentbox sessions play <id> [flags]
+
+Arguments:
+  id                                Session id or its prefix (see 'agentbox sessions ls')
+
+Flags:
+  --speed <factor>                  Playback speed (default: 1)
+  --idle-limit <duration>           Cap pauses between output, 0 disables (default: 2s)
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlagsWithAllowed(args, SessionsPlayFlags()); code != 0 {
+		return code
+	}
+
+	id, opts, err := parsePlayFlags(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if id == "" {
+		fmt.Fprintf(os.Stderr, "Usage: agentbox sessions play <id> [flags]\n")
+		return 1
+	}
+
+	dir, err := projectSessionsDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	info, err := recording.Find(dir, id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if errors.Is(err, recording.ErrNotFound) {
+			fmt.Fprintln(os.Stderr, "Use 'agentbox sessions ls' to list recorded sessions")
+		}
+		return 1
+	}
+
+	f, err := os.Open(info.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	if err := recording.Play(f, os.Stdout, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error playing %s: %v\n", info.ID, err)
+		return 1
+	}
+	return 0
+}
+
+// parsePlayFlags parses sessions play arguments and returns the session id, if any.
+func parsePlayFlags(args []string) (string, recording.PlayOptions, error) {
+	opts := recording.PlayOptions{Speed: 1, IdleLimit: defaultIdleLimit}
+	var id string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--speed":
+			value, err := flagValue(args, i)
+			if err != nil {
+				return "", opts, err
+			}
+			i++
+			speed, err := strconv.ParseFloat(value, 64)
+			if err != nil || speed <= 0 {
+				return "", opts, fmt.Errorf("invalid speed: %s", value)
+			}
+			opts.Speed = speed
+		case "--idle-limit":
+			value, err := flagValue(args, i)
+			if err != nil {
+				return "", opts, err
+			}
+			i++
+			limit, err := time.ParseDuration(value)
+			if err != nil || limit < 0 {
+				return "", opts, fmt.Errorf("invalid idle limit: %s", value)
+			}
+			opts.IdleLimit = limit
+		default:
+			if id != "" {
+				return "", opts, fmt.Errorf("unexpected argument: %s", arg)
+			}
+			id = arg
+		}
+	}
+	return id, opts, nil
+}
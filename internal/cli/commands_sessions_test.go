@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePlayFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		id        string
+		speed     float64
+		idleLimit time.Duration
+		wantErr   bool
+	}{
+		{"defaults", []string{"20261018-153000"}, "20261018-153000", 1, defaultIdleLimit, false},
+		{"speed and idle limit", []string{"2026", "--speed", "2.5", "--idle-limit", "500ms"}, "2026", 2.5, 500 * time.Millisecond, false},
+		{"no idle limit", []string{"--idle-limit", "0", "2026"}, "2026", 1, 0, false},
+		{"no id", []string{"--speed", "2"}, "", 2, defaultIdleLimit, false},
+		{"invalid speed", []string{"2026", "--speed", "0"}, "", 0, 0, true},
+		{"invalid idle limit", []string{"2026", "--idle-limit", "soon"}, "", 0, 0, true},
+		{"missing value", []string{"2026", "--speed"}, "", 0, 0, true},
+		{"two ids", []string{"2026", "2025"}, "", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			id, opts, err := parsePlayFlags(tt.args)
+
+			// assert
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsePlayFlags(%v) should fail", tt.args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePlayFlags error: %v", err)
+			}
+			if id != tt.id || opts.Speed != tt.speed || opts.IdleLimit != tt.idleLimit {
+				t.Errorf("parsePlayFlags(%v) = %q, %+v", tt.args, id, opts)
+			}
+		})
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/aleksey925/agentbox/internal/agents"
+	"github.com/aleksey925/agentbox/internal/docker"
+	"github.com/aleksey925/agentbox/internal/skeleton"
 )
 
 func captureOutput(f func()) string {
@@ -236,6 +239,7 @@ func TestHelpExitCode(t *testing.T) {
 	}{
 		{"init --help", app.cmdInit, []string{"--help"}},
 		{"run --help", app.cmdRun, []string{"--help"}},
+		{"exec --help", app.cmdExec, []string{"--help"}},
 		{"attach --help", app.cmdAttach, []string{"--help"}},
 		{"ps --help", app.cmdPs, []string{"--help"}},
 		{"agent --help", app.cmdAgent, []string{"--help"}},
@@ -326,6 +330,62 @@ func TestBashCompletionContainsAllSelfSubcommands(t *testing.T) {
 	}
 }
 
+// TestBashCompletionContainsAllWorktreeSubcommands verifies that bash completion
+// includes all worktree subcommands.
+func TestBashCompletionContainsAllWorktreeSubcommands(t *testing.T) {
+	// act
+	completion := generateBashCompletion("agentbox")
+
+	// assert
+	for _, sub := range WorktreeSubcommands() {
+		if !strings.Contains(completion, sub) {
+			t.Errorf("bash completion missing worktree subcommand: %s", sub)
+		}
+	}
+}
+
+// TestBashCompletionContainsAllCheckpointsSubcommands verifies that bash completion
+// includes all checkpoints subcommands.
+func TestBashCompletionContainsAllCheckpointsSubcommands(t *testing.T) {
+	// act
+	completion := generateBashCompletion("agentbox")
+
+	// assert
+	for _, sub := range CheckpointsSubcommands() {
+		if !strings.Contains(completion, sub) {
+			t.Errorf("bash completion missing checkpoints subcommand: %s", sub)
+		}
+	}
+}
+
+// TestBashCompletionContainsAllSessionsSubcommands verifies that bash completion
+// includes all sessions subcommands.
+func TestBashCompletionContainsAllSessionsSubcommands(t *testing.T) {
+	// act
+	completion := generateBashCompletion("agentbox")
+
+	// assert
+	for _, sub := range SessionsSubcommands() {
+		if !strings.Contains(completion, sub) {
+			t.Errorf("bash completion missing sessions subcommand: %s", sub)
+		}
+	}
+}
+
+// TestBashCompletionContainsAllImageSubcommands verifies that bash completion
+// includes all image subcommands and flags.
+func TestBashCompletionContainsAllImageSubcommands(t *testing.T) {
+	// act
+	completion := generateBashCompletion("agentbox")
+
+	// assert
+	for _, sub := range append(ImageSubcommands(), ImageBuildFlags()...) {
+		if !strings.Contains(completion, sub) {
+			t.Errorf("bash completion missing image subcommand or flag: %s", sub)
+		}
+	}
+}
+
 // TestBashCompletionContainsAllSelfUninstallFlags verifies that bash completion
 // includes all self uninstall flags.
 func TestBashCompletionContainsAllSelfUninstallFlags(t *testing.T) {
@@ -440,6 +500,62 @@ func TestZshCompletionContainsAllSelfSubcommands(t *testing.T) {
 	}
 }
 
+// TestZshCompletionContainsAllWorktreeSubcommands verifies that zsh completion
+// includes all worktree subcommands.
+func TestZshCompletionContainsAllWorktreeSubcommands(t *testing.T) {
+	// act
+	completion := generateZshCompletion("agentbox")
+
+	// assert
+	for _, sub := range WorktreeSubcommands() {
+		if !strings.Contains(completion, "'"+sub+":") {
+			t.Errorf("zsh completion missing worktree subcommand: %s", sub)
+		}
+	}
+}
+
+// TestZshCompletionContainsAllCheckpointsSubcommands verifies that zsh completion
+// includes all checkpoints subcommands.
+func TestZshCompletionContainsAllCheckpointsSubcommands(t *testing.T) {
+	// act
+	completion := generateZshCompletion("agentbox")
+
+	// assert
+	for _, sub := range CheckpointsSubcommands() {
+		if !strings.Contains(completion, "'"+sub+":") {
+			t.Errorf("zsh completion missing checkpoints subcommand: %s", sub)
+		}
+	}
+}
+
+// TestZshCompletionContainsAllSessionsSubcommands verifies that zsh completion
+// includes all sessions subcommands.
+func TestZshCompletionContainsAllSessionsSubcommands(t *testing.T) {
+	// act
+	completion := generateZshCompletion("agentbox")
+
+	// assert
+	for _, sub := range SessionsSubcommands() {
+		if !strings.Contains(completion, "'"+sub+":") {
+			t.Errorf("zsh completion missing sessions subcommand: %s", sub)
+		}
+	}
+}
+
+// TestZshCompletionContainsAllImageSubcommands verifies that zsh completion
+// includes all image subcommands and flags.
+func TestZshCompletionContainsAllImageSubcommands(t *testing.T) {
+	// act
+	completion := generateZshCompletion("agentbox")
+
+	// assert
+	for _, sub := range append(ImageSubcommands(), ImageBuildFlags()...) {
+		if !strings.Contains(completion, "'"+sub+":") {
+			t.Errorf("zsh completion missing image subcommand or flag: %s", sub)
+		}
+	}
+}
+
 // TestZshCompletionContainsAllSelfUninstallFlags verifies that zsh completion
 // includes all self uninstall flags.
 func TestZshCompletionContainsAllSelfUninstallFlags(t *testing.T) {
@@ -488,14 +604,26 @@ func TestAllCommandsRejectUnknownFlags(t *testing.T) {
 	app := &App{Version: "test"}
 
 	commandFuncs := map[string]func([]string) int{
-		"init":       app.cmdInit,
-		"run":        app.cmdRun,
-		"attach":     app.cmdAttach,
-		"ps":         app.cmdPs,
-		"clean":      app.cmdClean,
-		"agent":      app.cmdAgent,
-		"self":       app.cmdSelf,
-		"completion": app.cmdCompletion,
+		"init":        app.cmdInit,
+		"upgrade":     app.cmdUpgrade,
+		"run":         app.cmdRun,
+		"exec":        app.cmdExec,
+		"attach":      app.cmdAttach,
+		"ps":          app.cmdPs,
+		"stop":        app.cmdStop,
+		"kill":        app.cmdKill,
+		"restart":     app.cmdRestart,
+		"logs":        app.cmdLogs,
+		"worktree":    app.cmdWorktree,
+		"checkpoints": app.cmdCheckpoints,
+		"sessions":    app.cmdSessions,
+		"image":       app.cmdImage,
+		"clean":       app.cmdClean,
+		"doctor":      app.cmdDoctor,
+		"agent":       app.cmdAgent,
+		"self":        app.cmdSelf,
+		"config":      app.cmdConfig,
+		"completion":  app.cmdCompletion,
 	}
 
 	for cmd, fn := range commandFuncs {
@@ -549,3 +677,229 @@ func TestAllSubcommandsRejectUnknownFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestParseAttachFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		command     []string
+		containerID string
+		expected    []string
+		user        string
+		workDir     string
+	}{
+		{"default shell", nil, nil, "", nil, "", ""},
+		{"container id", []string{"abc123"}, nil, "abc123", nil, "", ""},
+		{
+			"agent with args",
+			[]string{"--agent", "claude", "abc123"},
+			[]string{"--resume"},
+			"abc123",
+			[]string{"/opt/agentbox/launchers/claude", "--resume"},
+			"", "",
+		},
+		{
+			"command as user in workdir",
+			[]string{"--user", "root", "--workdir", "/tmp"},
+			[]string{"apt", "list"},
+			"",
+			[]string{"apt", "list"},
+			"root", "/tmp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			opts, err := parseAttachFlags(tt.args, tt.command)
+
+			// assert
+			if err != nil {
+				t.Fatalf("parseAttachFlags error: %v", err)
+			}
+			if opts.containerID != tt.containerID {
+				t.Errorf("containerID = %q, want %q", opts.containerID, tt.containerID)
+			}
+			if !slices.Equal(opts.Command, tt.expected) {
+				t.Errorf("Command = %v, want %v", opts.Command, tt.expected)
+			}
+			if opts.User != tt.user || opts.WorkDir != tt.workDir {
+				t.Errorf("User, WorkDir = %q, %q; want %q, %q", opts.User, opts.WorkDir, tt.user, tt.workDir)
+			}
+		})
+	}
+}
+
+func TestParseAttachFlags__errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"unknown agent", []string{"--agent", "unknown"}},
+		{"missing value", []string{"--user"}},
+		{"two container ids", []string{"abc123", "def456"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			_, err := parseAttachFlags(tt.args, nil)
+
+			// assert
+			if err == nil {
+				t.Errorf("parseAttachFlags(%v) should fail", tt.args)
+			}
+		})
+	}
+}
+
+func TestExtractRuntimeFlag(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		expectedRuntime string
+		expectedRest    []string
+	}{
+		{"no flag", []string{"ps", "-a"}, "", []string{"ps", "-a"}},
+		{"before command", []string{"--runtime", "podman", "ps"}, "podman", []string{"ps"}},
+		{"after command", []string{"run", "--runtime", "docker", "--safe"}, "docker", []string{"run", "--safe"}},
+		{"after separator", []string{"attach", "--", "tool", "--runtime", "x"}, "", []string{"attach", "--", "tool", "--runtime", "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			runtime, rest, err := extractRuntimeFlag(tt.args)
+
+			// assert
+			if err != nil {
+				t.Fatalf("extractRuntimeFlag error: %v", err)
+			}
+			if runtime != tt.expectedRuntime {
+				t.Errorf("runtime = %q, want %q", runtime, tt.expectedRuntime)
+			}
+			if !slices.Equal(rest, tt.expectedRest) {
+				t.Errorf("rest = %v, want %v", rest, tt.expectedRest)
+			}
+		})
+	}
+}
+
+func TestExtractRuntimeFlag__missing_value(t *testing.T) {
+	// act
+	_, _, err := extractRuntimeFlag([]string{"ps", "--runtime"})
+
+	// assert
+	if err == nil {
+		t.Error("extractRuntimeFlag accepted --runtime without a value")
+	}
+}
+
+func TestParseRunFlags__name(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		wantErr  bool
+	}{
+		{"session name", []string{"--name", "review-1"}, "review-1", false},
+		{"with other flags", []string{"--safe", "--name", "fix.db", "--record"}, "fix.db", false},
+		{"invalid name", []string{"--name", "my session"}, "", true},
+		{"leading dash", []string{"--name", "-x"}, "", true},
+		{"missing value", []string{"--name"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			app := &App{Version: "test"}
+
+			// act
+			opts, err := app.parseRunFlags(tt.args)
+
+			// assert
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRunFlags(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if opts.name != tt.expected && !tt.wantErr {
+				t.Errorf("name = %q, want %q", opts.name, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPsRow(t *testing.T) {
+	tests := []struct {
+		name      string
+		container docker.Container
+		usage     containerUsage
+		expected  []string
+	}{
+		{
+			name: "named session with agents",
+			container: docker.Container{
+				ID: "abc123def456", Name: "review", Session: "review", ProjectDir: "/home/user/src/app",
+				Image: "app-agentbox", Status: "Up 2 hours", Ports: "127.0.0.1:3000->3000/tcp",
+			},
+			usage: containerUsage{
+				stats:  &docker.Stats{CPUPercent: 12.345, MemoryUsage: 300 << 20, MemoryLimit: 2 << 30},
+				agents: []string{"claude", "codex"},
+			},
+			expected: []string{"abc123def456", "review", "~/src/app", "app-agentbox", "-", "2 hours", "12.3%", "300.0MiB / 2.0GiB", "127.0.0.1:3000->3000/tcp", "claude,codex"},
+		},
+		{
+			name:      "unknown usage",
+			container: docker.Container{ID: "789abc012def", Name: "app-agentbox-run-1", ProjectDir: "/srv/app", Worktree: "feature-x"},
+			expected:  []string{"789abc012def", "app-agentbox-run-1", "/srv/app", "-", "feature-x", "-", "-", "-", "-", "-"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			row := psRow(tt.container, tt.usage, "/home/user")
+
+			// assert
+			if !slices.Equal(row, tt.expected) {
+				t.Errorf("psRow = %q, want %q", row, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEditedFileResolver(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		interactive bool
+		expected    skeleton.Action
+	}{
+		{"keep", "k\n", true, skeleton.Keep},
+		{"overwrite after invalid answer", "x\noverwrite\n", true, skeleton.Overwrite},
+		{"backup", "b\n", true, skeleton.Backup},
+		{"end of input keeps", "", true, skeleton.Keep},
+		{"non-interactive backs up", "", false, skeleton.Backup},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			var out bytes.Buffer
+			resolve := editedFileResolver(strings.NewReader(tt.input), &out, tt.interactive)
+
+			// act
+			action, err := resolve("Dockerfile.agentbox", []byte("FROM mine\n"), []byte("FROM agentbox-base\n"))
+
+			// assert
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+			if action != tt.expected {
+				t.Errorf("action = %v, want %v", action, tt.expected)
+			}
+			if tt.interactive && !strings.Contains(out.String(), "-FROM mine\n+FROM agentbox-base\n") {
+				t.Errorf("output does not contain the diff:\n%s", out.String())
+			}
+		})
+	}
+}
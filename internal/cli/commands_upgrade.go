@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/aleksey925/agentbox/internal/agents"
+	"github.com/aleksey925/agentbox/internal/diff"
+	"github.com/aleksey925/agentbox/internal/skeleton"
+)
+
+// devVersion is the version of builds without release information.
+const devVersion = "dev"
+
+// skeletonOutdated reports whether a project skeleton generated by projectVersion
+// is older than the running binary. Skeletons without a version predate versioned
+// files; development builds never report outdated skeletons.
+func skeletonOutdated(projectVersion, binaryVersion string) bool {
+	if binaryVersion == devVersion {
+		return false
+	}
+	return projectVersion == "" || agents.CompareVersions(projectVersion, binaryVersion) < 0
+}
+
+// warnOutdatedSkeleton tells to run upgrade when the project files are older than agentbox.
+func (a *App) warnOutdatedSkeleton(projectDir string) {
+	version, err := skeleton.ProjectVersion(projectDir)
+	if err != nil || !skeletonOutdated(version, a.Version) {
+		return
+	}
+	if version == "" {
+		version = "an older version"
+	}
+	fmt.Fprintf(os.Stderr, "Warning: project files were generated by agentbox %s, run 'agentbox upgrade' to update them to %s\n",
+		version, a.Version)
+}
+
+// upgradePlan is the set of changes upgrade makes to a project.
+type upgradePlan struct {
+	// files are the regenerated files that differ from the project ones.
+	files []plannedFile
+	// local is the migrated local compose file, nil if no migration applies.
+	local      []byte
+	localOld   []byte
+	migrations []skeleton.Migration
+}
+
+type plannedFile struct {
+	name      string
+	current   []byte
+	generated []byte
+	edited    bool
+}
+
+func (p *upgradePlan) empty() bool {
+	return len(p.files) == 0 && p.local == nil
+}
+
+// planUpgrade compares the project files with the ones this version generates.
+func planUpgrade(projectDir string, renderer *skeleton.Renderer, vars skeleton.Vars) (*upgradePlan, error) {
+	plan := &upgradePlan{}
+	for _, name := range skeleton.OverwriteFiles() {
+		generated, err := skeleton.Generate(renderer, name, vars)
+		if err != nil {
+			return nil, err
+		}
+		current, err := os.ReadFile(filepath.Join(projectDir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		if bytes.Equal(current, generated) {
+			continue
+		}
+		plan.files = append(plan.files, plannedFile{
+			name:      name,
+			current:   current,
+			generated: generated,
+			edited:    current != nil && skeleton.Edited(current),
+		})
+	}
+
+	current, err := os.ReadFile(filepath.Join(projectDir, skeleton.LocalFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read %s: %w", skeleton.LocalFile, err)
+	}
+	if migrated, applied := skeleton.Migrate(current); len(applied) > 0 {
+		plan.local, plan.localOld, plan.migrations = migrated, current, applied
+	}
+	return plan, nil
+}
+
+// preview prints the changes of the plan as unified diffs.
+func (p *upgradePlan) preview(out io.Writer) {
+	for _, f := range p.files {
+		note := ""
+		if f.edited {
+			note = fmt.Sprintf(" (edited by hand, your version will be saved to %s%s)", f.name, skeleton.BackupSuffix)
+		}
+		fmt.Fprintf(out, "\nRegenerate %s%s:\n", f.name, note)
+		fmt.Fprint(out, diff.Unified("a/"+f.name, "b/"+f.name, f.current, f.generated))
+	}
+	if p.local != nil {
+		fmt.Fprintf(out, "\nMigrate %s (your version will be saved to %s%s):\n",
+			skeleton.LocalFile, skeleton.LocalFile, skeleton.BackupSuffix)
+		for _, m := range p.migrations {
+			fmt.Fprintf(out, "  - %s\n", m.Description)
+		}
+		fmt.Fprint(out, diff.Unified("a/"+skeleton.LocalFile, "b/"+skeleton.LocalFile, p.localOld, p.local))
+	}
+}
+
+// apply writes the plan. Hand-edited files and the local compose file are backed up first.
+func (p *upgradePlan) apply(projectDir string) error {
+	for _, f := range p.files {
+		path := filepath.Join(projectDir, f.name)
+		if f.edited {
+			if err := os.WriteFile(path+skeleton.BackupSuffix, f.current, 0o644); err != nil {
+				return fmt.Errorf("back up %s: %w", f.name, err)
+			}
+		}
+		if err := os.WriteFile(path, f.generated, 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", f.name, err)
+		}
+	}
+
+	if p.local != nil {
+		path := filepath.Join(projectDir, skeleton.LocalFile)
+		if err := os.WriteFile(path+skeleton.BackupSuffix, p.localOld, 0o644); err != nil {
+			return fmt.Errorf("back up %s: %w", skeleton.LocalFile, err)
+		}
+		if err := os.WriteFile(path, p.local, 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", skeleton.LocalFile, err)
+		}
+	}
+	return nil
+}
+
+func (a *App) cmdUpgrade(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Upgrade the project files to this agentbox version
+
+Usage:
+  agentbox upgrade [flags]
+
+Flags:
+  -y, --yes                         Apply without asking
+  --dry-run                         Only show the changes
+
+Regenerates Dockerfile.agentbox and docker-compose.agentbox.yml and migrates
+docker-compose.agentbox.local.yml, showing the changes first. Hand-edited files
+and the local compose file are saved with the .orig suffix before they change.
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlagsWithAllowed(args, CommandFlags()["upgrade"]); code != 0 {
+		return code
+	}
+
+	yes := slices.Contains(args, "-y") || slices.Contains(args, "--yes")
+	dryRun := slices.Contains(args, "--dry-run")
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if _, err := os.Stat(filepath.Join(cwd, "docker-compose.agentbox.yml")); os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Error: project is not initialized, run 'agentbox init'\n")
+		return 1
+	}
+
+	renderer, vars, err := a.skeletonRenderer(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	plan, err := planUpgrade(cwd, renderer, vars)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	version, err := skeleton.ProjectVersion(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if version == "" {
+		version = "unknown"
+	}
+
+	if plan.empty() {
+		fmt.Printf("Project files are up to date with agentbox %s\n", a.Version)
+		return 0
+	}
+
+	fmt.Printf("Upgrading project files from agentbox %s to %s\n", version, a.Version)
+	plan.preview(os.Stdout)
+	if dryRun {
+		return 0
+	}
+
+	if !yes {
+		fmt.Print("\nApply these changes? [y/N]: ")
+		answer, _ := readAnswer(bufio.NewReader(os.Stdin))
+		if answer != "y" && answer != "yes" {
+			fmt.Println("Aborted")
+			return 1
+		}
+	}
+
+	if err := plan.apply(cwd); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Println("Project files upgraded, the image is rebuilt on the next 'agentbox run'")
+	return 0
+}
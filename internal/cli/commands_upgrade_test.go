@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aleksey925/agentbox/internal/skeleton"
+)
+
+func TestSkeletonOutdated(t *testing.T) {
+	tests := []struct {
+		name     string
+		project  string
+		binary   string
+		expected bool
+	}{
+		{"same version", "1.4.0", "1.4.0", false},
+		{"older project", "1.3.2", "1.4.0", true},
+		{"newer project", "1.5.0", "1.4.0", false},
+		{"unversioned project", "", "1.4.0", true},
+		{"development build", "", "dev", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result := skeletonOutdated(tt.project, tt.binary)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("skeletonOutdated(%q, %q) = %v, want %v", tt.project, tt.binary, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPlanUpgrade(t *testing.T) {
+	// arrange
+	dir := t.TempDir()
+	renderer := skeleton.NewRenderer()
+	if _, err := skeleton.CopyTo(dir, renderer, skeleton.Vars{Version: "1.3.0"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	compose := filepath.Join(dir, "docker-compose.agentbox.yml")
+	editedCompose := []byte("services: {}\n")
+	if err := os.WriteFile(compose, editedCompose, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(dir, skeleton.LocalFile)
+	oldLocal := []byte("version: \"3\"\nservices: {}\n")
+	if err := os.WriteFile(local, oldLocal, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	vars := skeleton.Vars{Version: "1.4.0"}
+
+	// act
+	plan, err := planUpgrade(dir, renderer, vars)
+	if err != nil {
+		t.Fatalf("planUpgrade() error = %v", err)
+	}
+	var preview bytes.Buffer
+	plan.preview(&preview)
+	applyErr := plan.apply(dir)
+
+	// assert
+	if applyErr != nil {
+		t.Fatalf("apply() error = %v", applyErr)
+	}
+	if len(plan.files) != 2 || plan.files[0].edited || !plan.files[1].edited {
+		t.Errorf("planned files = %+v, want the Dockerfile and the edited compose file", plan.files)
+	}
+	for _, want := range []string{"Regenerate Dockerfile.agentbox:", "edited by hand", "remove the obsolete top-level 'version' key", "-version: \"3\""} {
+		if !strings.Contains(preview.String(), want) {
+			t.Errorf("preview does not contain %q:\n%s", want, preview.String())
+		}
+	}
+	if version, _ := skeleton.ProjectVersion(dir); version != "1.4.0" {
+		t.Errorf("project version = %q after upgrade, want 1.4.0", version)
+	}
+	if backup, _ := os.ReadFile(compose + skeleton.BackupSuffix); !bytes.Equal(backup, editedCompose) {
+		t.Errorf("compose backup = %q, want the edited file", backup)
+	}
+	if migrated, _ := os.ReadFile(local); string(migrated) != "services: {}\n" {
+		t.Errorf("local compose file = %q, want it migrated", migrated)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Dockerfile.agentbox"+skeleton.BackupSuffix)); err == nil {
+		t.Errorf("unedited Dockerfile was backed up")
+	}
+
+	again, err := planUpgrade(dir, renderer, vars)
+	if err != nil || !again.empty() {
+		t.Errorf("second planUpgrade() = %+v, %v, want an empty plan", again, err)
+	}
+}
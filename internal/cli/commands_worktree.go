@@ -0,0 +1,414 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/aleksey925/agentbox/internal/config"
+	"github.com/aleksey925/agentbox/internal/docker"
+	"github.com/aleksey925/agentbox/internal/git"
+)
+
+const (
+	worktreeBranchPrefix = "agentbox/"
+	containerHomeDir     = "/home/box"
+	containerProjectDir  = containerHomeDir + "/app"
+)
+
+var worktreeNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func validateWorktreeName(name string) error {
+	if !worktreeNameRe.MatchString(name) {
+		return fmt.Errorf("invalid worktree name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// managedWorktree is a git worktree created by `run --worktree`.
+type managedWorktree struct {
+	Name   string
+	Path   string
+	Branch string
+}
+
+// worktreeRepo returns the repository root for projectDir and the directory
+// holding its managed worktrees.
+func worktreeRepo(paths *config.Paths, projectDir string) (repoDir, worktreesDir string, err error) {
+	repoDir, err = git.TopLevel(projectDir)
+	if err != nil {
+		return "", "", fmt.Errorf("worktrees require a git repository: %w", err)
+	}
+	return repoDir, paths.ProjectWorktreesDir(repoDir), nil
+}
+
+// ensureWorktree returns the managed worktree with the given name, creating
+// it on branch agentbox/<name> if it does not exist.
+func ensureWorktree(paths *config.Paths, projectDir, name string) (managedWorktree, error) {
+	repoDir, worktreesDir, err := worktreeRepo(paths, projectDir)
+	if err != nil {
+		return managedWorktree{}, err
+	}
+
+	wt := managedWorktree{
+		Name:   name,
+		Path:   filepath.Join(worktreesDir, name),
+		Branch: worktreeBranchPrefix + name,
+	}
+
+	if _, err := os.Stat(wt.Path); err == nil {
+		fmt.Printf("Using worktree %s (%s)\n", name, wt.Path)
+		return wt, nil
+	}
+
+	if err := os.MkdirAll(worktreesDir, 0o755); err != nil {
+		return managedWorktree{}, fmt.Errorf("create worktrees dir: %w", err)
+	}
+	if err := git.AddWorktree(repoDir, wt.Path, wt.Branch); err != nil {
+		return managedWorktree{}, fmt.Errorf("create worktree: %w", err)
+	}
+	fmt.Printf("Created worktree %s on branch %s (%s)\n", name, wt.Branch, wt.Path)
+
+	return wt, nil
+}
+
+// worktreeVolumes returns compose volumes that mount the worktree in place of
+// the project directory, and the worktree path. The repository's .git directory
+// is mounted at its host path because the worktree refers to it by absolute path.
+func worktreeVolumes(paths *config.Paths, projectDir, name string) ([]string, string, error) {
+	wt, err := ensureWorktree(paths, projectDir, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	repoDir, err := git.TopLevel(projectDir)
+	if err != nil {
+		return nil, "", err
+	}
+	commonDir, err := git.CommonDir(repoDir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// mount the same subdirectory when running from inside the repository
+	source := wt.Path
+	if resolved, err := filepath.EvalSymlinks(projectDir); err == nil {
+		if rel, err := filepath.Rel(repoDir, resolved); err == nil && rel != "." {
+			source = filepath.Join(wt.Path, rel)
+		}
+	}
+
+	volumes := []string{
+		source + ":" + containerProjectDir,
+		commonDir + ":" + commonDir,
+	}
+	return volumes, wt.Path, nil
+}
+
+// listManagedWorktrees returns worktrees located in the project's managed directory.
+func listManagedWorktrees(paths *config.Paths, projectDir string) (string, []managedWorktree, error) {
+	repoDir, worktreesDir, err := worktreeRepo(paths, projectDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	all, err := git.ListWorktrees(repoDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var managed []managedWorktree
+	for _, wt := range all {
+		if filepath.Dir(wt.Path) != worktreesDir {
+			continue
+		}
+		managed = append(managed, managedWorktree{
+			Name:   filepath.Base(wt.Path),
+			Path:   wt.Path,
+			Branch: wt.Branch,
+		})
+	}
+	return repoDir, managed, nil
+}
+
+func findManagedWorktree(paths *config.Paths, projectDir, name string) (string, managedWorktree, error) {
+	repoDir, worktrees, err := listManagedWorktrees(paths, projectDir)
+	if err != nil {
+		return "", managedWorktree{}, err
+	}
+
+	idx := slices.IndexFunc(worktrees, func(wt managedWorktree) bool { return wt.Name == name })
+	if idx == -1 {
+		return "", managedWorktree{}, fmt.Errorf("worktree not found: %s", name)
+	}
+	return repoDir, worktrees[idx], nil
+}
+
+func (a *App) cmdWorktree(args []string) int {
+	if len(args) > 0 && hasHelpFlag(args[:1]) {
+		fmt.Print(`Manage git worktrees used by 'agentbox run --worktree'
+
+Usage:
+  agentbox worktree [command]
+
+Commands:
+  ls                                List managed worktrees (default)
+  rm <name>                         Remove a worktree and its branch
+  merge <name>                      Merge the worktree branch into the current branch
+
+Worktrees are created by 'agentbox run --worktree <name>' on branch agentbox/<name>
+and stored in ~/.agentbox/worktrees/.
+
+Use "agentbox worktree <command> --help" for more information about a command.
+`)
+		return 0
+	}
+
+	if len(args) > 0 {
+		if code := RejectUnknownFlags(args[:1]); code != 0 {
+			return code
+		}
+	}
+
+	if len(args) == 0 {
+		return a.worktreeLs(nil)
+	}
+
+	subcmd := args[0]
+	subargs := args[1:]
+
+	switch subcmd {
+	case "ls":
+		return a.worktreeLs(subargs)
+	case "rm":
+		return a.worktreeRm(subargs)
+	case "merge":
+		return a.worktreeMerge(subargs)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown worktree subcommand: %s\n", subcmd)
+		return 1
+	}
+}
+
+func (a *App) worktreeLs(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`List managed worktrees
+
+Usage:
+  agentbox worktree ls [flags]
+
+Flags:
+  -q, --quiet                       Print only worktree names
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlagsWithAllowed(args, WorktreeLsFlags()); code != 0 {
+		return code
+	}
+
+	quiet := slices.Contains(args, "-q") || slices.Contains(args, "--quiet")
+
+	paths, cwd, err := pathsAndCwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	_, worktrees, err := listManagedWorktrees(paths, cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if quiet {
+		for _, wt := range worktrees {
+			fmt.Println(wt.Name)
+		}
+		return 0
+	}
+
+	if len(worktrees) == 0 {
+		fmt.Println("No worktrees. Create one with 'agentbox run --worktree <name>'")
+		return 0
+	}
+
+	running := runningWorktrees(cwd)
+
+	table := NewTable("NAME", "BRANCH", "CONTAINERS", "PATH")
+	for _, wt := range worktrees {
+		containers := "-"
+		if running != nil {
+			containers = strconv.Itoa(running[wt.Name])
+		}
+		table.AddRow(wt.Name, wt.Branch, containers, wt.Path)
+	}
+	table.Render()
+	return 0
+}
+
+func (a *App) worktreeRm(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Remove a worktree and its branch
+
+Usage:
+  agentbox worktree rm <name> [flags]
+
+Flags:
+  --force                           Remove even with uncommitted changes or an unmerged branch
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlagsWithAllowed(args, WorktreeRmFlags()); code != 0 {
+		return code
+	}
+
+	force := slices.Contains(args, "--force")
+	name := firstPositional(args)
+	if name == "" {
+		fmt.Fprintf(os.Stderr, "Usage: agentbox worktree rm <name> [--force]\n")
+		return 1
+	}
+
+	paths, cwd, err := pathsAndCwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	repoDir, wt, err := findManagedWorktree(paths, cwd, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if running := runningWorktrees(cwd); running[name] > 0 {
+		fmt.Fprintf(os.Stderr, "Error: worktree %s is used by %d running container(s)\n", name, running[name])
+		return 1
+	}
+
+	if err := git.RemoveWorktree(repoDir, wt.Path, force); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if !force {
+			fmt.Fprintln(os.Stderr, "Use --force to remove a worktree with uncommitted changes")
+		}
+		return 1
+	}
+	fmt.Printf("Removed worktree: %s\n", wt.Path)
+
+	if wt.Branch == "" {
+		return 0
+	}
+	if err := git.DeleteBranch(repoDir, wt.Branch, force); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: branch %s kept: %v\n", wt.Branch, err)
+		return 0
+	}
+	fmt.Printf("Deleted branch: %s\n", wt.Branch)
+	return 0
+}
+
+func (a *App) worktreeMerge(args []string) int {
+	if hasHelpFlag(args) {
+		fmt.Print(`Merge the worktree branch into the current branch
+
+Usage:
+  agentbox worktree merge <name> [flags]
+
+Flags:
+  --squash                          Squash the worktree commits into the working tree
+
+Uncommitted changes in the worktree are not merged, commit them first.
+`)
+		return 0
+	}
+
+	if code := RejectUnknownFlagsWithAllowed(args, WorktreeMergeFlags()); code != 0 {
+		return code
+	}
+
+	squash := slices.Contains(args, "--squash")
+	name := firstPositional(args)
+	if name == "" {
+		fmt.Fprintf(os.Stderr, "Usage: agentbox worktree merge <name> [--squash]\n")
+		return 1
+	}
+
+	paths, cwd, err := pathsAndCwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	repoDir, wt, err := findManagedWorktree(paths, cwd, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if wt.Branch == "" {
+		fmt.Fprintf(os.Stderr, "Error: worktree %s has a detached HEAD, nothing to merge\n", name)
+		return 1
+	}
+
+	clean, err := git.IsClean(wt.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !clean {
+		fmt.Fprintf(os.Stderr, "Warning: worktree %s has uncommitted changes, they will not be merged\n", name)
+	}
+
+	out, err := git.Merge(repoDir, wt.Branch, squash)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if out != "" {
+		fmt.Println(out)
+	}
+	fmt.Printf("Merged %s into the current branch\n", wt.Branch)
+	return 0
+}
+
+// runningWorktrees counts running containers per worktree in the project.
+// Returns nil if containers cannot be listed.
+func runningWorktrees(projectDir string) map[string]int {
+	containers, err := docker.ListContainers(projectDir, false)
+	if err != nil {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, c := range containers {
+		if c.Worktree != "" {
+			counts[c.Worktree]++
+		}
+	}
+	return counts
+}
+
+func pathsAndCwd() (*config.Paths, string, error) {
+	paths, err := config.NewPaths()
+	if err != nil {
+		return nil, "", fmt.Errorf("get paths: %w", err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, "", fmt.Errorf("get working dir: %w", err)
+	}
+	return paths, cwd, nil
+}
+
+// firstPositional returns the first argument that is not a flag.
+func firstPositional(args []string) string {
+	for _, arg := range args {
+		if arg != "" && arg[0] != '-' {
+			return arg
+		}
+	}
+	return ""
+}
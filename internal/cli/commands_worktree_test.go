@@ -0,0 +1,41 @@
+package cli
+
+import "testing"
+
+func TestValidateWorktreeName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"feature-x", false},
+		{"fix_1.2", false},
+		{"A1", false},
+		{"", true},
+		{"-x", true},
+		{".hidden", true},
+		{"a/b", true},
+		{"with space", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			err := validateWorktreeName(tt.name)
+
+			// assert
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateWorktreeName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFirstPositional(t *testing.T) {
+	// act
+	actual := firstPositional([]string{"--force", "feature-x", "other"})
+
+	// assert
+	if actual != "feature-x" {
+		t.Errorf("firstPositional = %q, want feature-x", actual)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"github.com/aleksey925/agentbox/internal/agents"
+	"github.com/aleksey925/agentbox/internal/config"
+	"github.com/aleksey925/agentbox/internal/docker"
 )
 
 func (a *App) cmdCompletion(args []string) int {
@@ -68,14 +70,22 @@ func generateBashCompletion(cmdName string) string {
 
 	commands := strings.Join(AllCommands(), " ")
 	runFlags := strings.Join(CommandFlags()["run"], " ")
+	execFlags := strings.Join(CommandFlags()["exec"], " ")
 	psFlags := strings.Join(CommandFlags()["ps"], " ")
 	agentSub := strings.Join(AgentSubcommands(), " ")
 	selfSub := strings.Join(SelfSubcommands(), " ")
+	worktreeSub := strings.Join(WorktreeSubcommands(), " ")
+	checkpointsSub := strings.Join(CheckpointsSubcommands(), " ")
+	sessionsSub := strings.Join(SessionsSubcommands(), " ")
+	imageSub := strings.Join(ImageSubcommands(), " ")
+	configSub := strings.Join(ConfigSubcommands(), " ")
+	configKeys := strings.Join(ConfigKeys(), " ")
+	attachFlags := strings.Join(CommandFlags()["attach"], " ")
 	selfUninstallFlags := strings.Join(SelfUninstallFlags(), " ")
 	shells := strings.Join(CompletionShells(), " ")
 
 	tmpl := `_{{.FuncName}}() {
-    local cur prev pprev="" commands agent_sub self_sub agent_names run_flags ps_flags self_uninstall_flags
+    local cur prev pprev="" commands agent_sub self_sub worktree_sub checkpoints_sub sessions_sub image_sub config_sub config_keys agent_names run_flags exec_flags attach_flags ps_flags self_uninstall_flags
     cur="${COMP_WORDS[COMP_CWORD]}"
     prev="${COMP_WORDS[COMP_CWORD-1]}"
     [[ $COMP_CWORD -ge 2 ]] && pprev="${COMP_WORDS[COMP_CWORD-2]}"
@@ -83,8 +93,16 @@ func generateBashCompletion(cmdName string) string {
     commands="{{.Commands}}"
     agent_sub="{{.AgentSub}}"
     self_sub="{{.SelfSub}}"
+    worktree_sub="{{.WorktreeSub}}"
+    checkpoints_sub="{{.CheckpointsSub}}"
+    sessions_sub="{{.SessionsSub}}"
+    image_sub="{{.ImageSub}}"
+    config_sub="{{.ConfigSub}}"
+    config_keys="{{.ConfigKeys}}"
     agent_names="{{.AgentNames}}"
     run_flags="{{.RunFlags}}"
+    exec_flags="{{.ExecFlags}}"
+    attach_flags="{{.AttachFlags}}"
     ps_flags="{{.PsFlags}}"
     self_uninstall_flags="{{.SelfUninstallFlags}}"
 
@@ -95,9 +113,30 @@ func generateBashCompletion(cmdName string) string {
         run)
             COMPREPLY=($(compgen -W "$run_flags" -- "$cur"))
             ;;
+        upgrade)
+            COMPREPLY=($(compgen -W "{{.UpgradeFlags}}" -- "$cur"))
+            ;;
+        exec)
+            COMPREPLY=($(compgen -W "$agent_names" -- "$cur"))
+            ;;
         attach)
-            local containers=$(docker ps --filter "label=com.docker.compose.service=agentbox" --filter "label=com.docker.compose.project.working_dir=$(pwd)" --format "{{.ID}}" 2>/dev/null)
-            COMPREPLY=($(compgen -W "$containers" -- "$cur"))
+            local runtime=docker
+            command -v docker >/dev/null 2>&1 || runtime=podman
+            local containers=$($runtime ps --filter "label=com.docker.compose.service=agentbox" --filter "label=com.docker.compose.project.working_dir=$(pwd)" --format "{{.ID}}" 2>/dev/null)
+            local sessions=$($runtime ps --filter "label=agentbox.session" --format "{{.Names}}" 2>/dev/null)
+            COMPREPLY=($(compgen -W "$containers $sessions $attach_flags" -- "$cur"))
+            ;;
+        stop|kill|restart|logs)
+            local runtime=docker
+            command -v docker >/dev/null 2>&1 || runtime=podman
+            local containers=$($runtime ps --filter "label=com.docker.compose.service=agentbox" --filter "label=com.docker.compose.project.working_dir=$(pwd)" --format "{{.ID}}" 2>/dev/null)
+            local sessions=$($runtime ps --filter "label=agentbox.session" --format "{{.Names}}" 2>/dev/null)
+            local flags=""
+            case "$prev" in
+                stop|kill) flags="{{.StopFlags}}" ;;
+                logs) flags="{{.LogsFlags}}" ;;
+            esac
+            COMPREPLY=($(compgen -W "$containers $sessions $flags" -- "$cur"))
             ;;
         ps)
             COMPREPLY=($(compgen -W "$ps_flags" -- "$cur"))
@@ -108,6 +147,61 @@ func generateBashCompletion(cmdName string) string {
         self)
             COMPREPLY=($(compgen -W "$self_sub" -- "$cur"))
             ;;
+        worktree)
+            COMPREPLY=($(compgen -W "$worktree_sub" -- "$cur"))
+            ;;
+        checkpoints)
+            COMPREPLY=($(compgen -W "$checkpoints_sub" -- "$cur"))
+            ;;
+        sessions)
+            COMPREPLY=($(compgen -W "$sessions_sub" -- "$cur"))
+            ;;
+        image)
+            COMPREPLY=($(compgen -W "$image_sub" -- "$cur"))
+            ;;
+        config)
+            COMPREPLY=($(compgen -W "$config_sub" -- "$cur"))
+            ;;
+        get|set|unset)
+            if [[ "$pprev" == "config" ]]; then
+                local flags=""
+                [[ "$prev" != "get" ]] && flags="{{.ConfigSetFlags}}"
+                COMPREPLY=($(compgen -W "$config_keys $flags" -- "$cur"))
+            fi
+            ;;
+        --project)
+            COMPREPLY=($(compgen -W "$config_keys" -- "$cur"))
+            ;;
+        play)
+            if [[ "$pprev" == "sessions" ]]; then
+                local ids=$(command agentbox sessions ls -q 2>/dev/null)
+                COMPREPLY=($(compgen -W "$ids" -- "$cur"))
+            fi
+            ;;
+        build)
+            if [[ "$pprev" == "image" ]]; then
+                COMPREPLY=($(compgen -W "{{.ImageBuildFlags}}" -- "$cur"))
+            fi
+            ;;
+        restore)
+            if [[ "$pprev" == "checkpoints" ]]; then
+                local ids=$(command agentbox checkpoints ls -q 2>/dev/null)
+                COMPREPLY=($(compgen -W "$ids" -- "$cur"))
+            fi
+            ;;
+        --agent)
+            COMPREPLY=($(compgen -W "$agent_names" -- "$cur"))
+            ;;
+        --egress)
+            COMPREPLY=($(compgen -W "{{.EgressModes}}" -- "$cur"))
+            ;;
+        --runtime)
+            COMPREPLY=($(compgen -W "{{.Runtimes}}" -- "$cur"))
+            ;;
+        rm|merge|--worktree)
+            local worktrees=$(command agentbox worktree ls -q 2>/dev/null)
+            COMPREPLY=($(compgen -W "$worktrees" -- "$cur"))
+            ;;
         update)
             if [[ "$pprev" == "agent" ]]; then
                 COMPREPLY=($(compgen -W "$agent_names" -- "$cur"))
@@ -128,6 +222,8 @@ func generateBashCompletion(cmdName string) string {
             if [[ "$pprev" == "use" ]]; then
                 local versions=$(ls ~/.agentbox/bin/"$prev"/ 2>/dev/null | grep -v current)
                 COMPREPLY=($(compgen -W "$versions" -- "$cur"))
+            elif [[ "$pprev" == "exec" ]]; then
+                COMPREPLY=($(compgen -W "$exec_flags" -- "$cur"))
             fi
             ;;
         completion)
@@ -143,12 +239,27 @@ complete -F _{{.FuncName}} {{.CmdName}}
 	result = strings.ReplaceAll(result, "{{.Commands}}", commands)
 	result = strings.ReplaceAll(result, "{{.AgentSub}}", agentSub)
 	result = strings.ReplaceAll(result, "{{.SelfSub}}", selfSub)
+	result = strings.ReplaceAll(result, "{{.WorktreeSub}}", worktreeSub)
+	result = strings.ReplaceAll(result, "{{.CheckpointsSub}}", checkpointsSub)
+	result = strings.ReplaceAll(result, "{{.SessionsSub}}", sessionsSub)
+	result = strings.ReplaceAll(result, "{{.ImageSub}}", imageSub)
+	result = strings.ReplaceAll(result, "{{.ConfigSub}}", configSub)
+	result = strings.ReplaceAll(result, "{{.ConfigKeys}}", configKeys)
+	result = strings.ReplaceAll(result, "{{.ConfigSetFlags}}", strings.Join(ConfigSetFlags(), " "))
+	result = strings.ReplaceAll(result, "{{.ImageBuildFlags}}", strings.Join(ImageBuildFlags(), " "))
 	result = strings.ReplaceAll(result, "{{.AgentNames}}", agentNamesStr)
 	result = strings.ReplaceAll(result, "{{.AgentNamesPattern}}", agentNamesPattern)
 	result = strings.ReplaceAll(result, "{{.RunFlags}}", runFlags)
+	result = strings.ReplaceAll(result, "{{.ExecFlags}}", execFlags)
+	result = strings.ReplaceAll(result, "{{.AttachFlags}}", attachFlags)
+	result = strings.ReplaceAll(result, "{{.StopFlags}}", strings.Join(CommandFlags()["stop"], " "))
+	result = strings.ReplaceAll(result, "{{.LogsFlags}}", strings.Join(CommandFlags()["logs"], " "))
 	result = strings.ReplaceAll(result, "{{.PsFlags}}", psFlags)
+	result = strings.ReplaceAll(result, "{{.UpgradeFlags}}", strings.Join(CommandFlags()["upgrade"], " "))
 	result = strings.ReplaceAll(result, "{{.SelfUninstallFlags}}", selfUninstallFlags)
 	result = strings.ReplaceAll(result, "{{.Shells}}", shells)
+	result = strings.ReplaceAll(result, "{{.EgressModes}}", strings.Join(config.EgressModes(), " "))
+	result = strings.ReplaceAll(result, "{{.Runtimes}}", strings.Join(docker.Runtimes(), " "))
 	return result
 }
 
@@ -162,17 +273,36 @@ func generateZshCompletion(cmdName string) string {
 	}
 	agentNamesZsh := strings.Join(agentEntries, "\n        ")
 
+	keyEntries := make([]string, 0, len(config.Keys()))
+	for _, k := range config.Keys() {
+		desc := strings.ReplaceAll(k.Description, "'", `'\''`)
+		keyEntries = append(keyEntries, fmt.Sprintf("'%s:%s'", k.Name, desc))
+	}
+	configKeysZsh := strings.Join(keyEntries, "\n        ")
+
 	base := `_agentbox() {
-    local -a commands agent_cmds self_cmds agent_names shells run_flags ps_flags self_uninstall_flags
+    local -a commands agent_cmds self_cmds worktree_cmds checkpoints_cmds sessions_cmds image_cmds image_build_flags config_cmds config_keys config_set_flags upgrade_flags agent_names shells run_flags exec_flags attach_flags ps_flags stop_flags logs_flags self_uninstall_flags
 
     commands=(
         'init:Initialize sandbox in current directory'
+        'upgrade:Upgrade project files to this agentbox version'
         'run:Start a new container'
+        'exec:Run an agent non-interactively (for CI)'
         'attach:Attach to running container'
         'ps:List running agentbox containers'
+        'stop:Stop running containers'
+        'kill:Kill running containers'
+        'restart:Restart a running container'
+        'logs:Show the output of a container'
+        'worktree:Manage git worktrees of sandboxes'
+        'checkpoints:List and restore git checkpoints of sessions'
+        'sessions:List and replay recorded terminal sessions'
+        'image:Manage the shared base image'
         'agent:Manage AI agents'
         'self:Update or uninstall agentbox'
+        'config:View and edit settings'
         'clean:Remove sandbox files from project'
+        'doctor:Check the environment for common problems'
         'completion:Generate shell completion script'
         'help:Show help'
         'version:Show version'
@@ -181,6 +311,41 @@ func generateZshCompletion(cmdName string) string {
     run_flags=(
         '--build:Rebuild image before running'
         '--build-no-cache:Rebuild image without Docker cache'
+        '--no-auto-build:Do not rebuild the image when its inputs change'
+        '--safe:Start agents without their default permission flags'
+        '--name:Name the session'
+        '--worktree:Run in a git worktree with the given name'
+        '--egress:Network egress policy (open, allowlist)'
+        '--review:Work on a copy of the project and review changes'
+        '--record:Record the terminal session'
+        '--cpus:Limit the number of CPUs'
+        '--memory:Limit memory (e.g. 4g)'
+        '--pids-limit:Limit the number of processes'
+        '-p:Publish a port (host:container)'
+        '--publish:Publish a port (host:container)'
+        '--host-gateway:Reach host services at host.docker.internal'
+        '--forward-git:Forward the SSH agent and git identity'
+    )
+
+    upgrade_flags=(
+        '--yes:Apply without asking'
+        '-y:Apply without asking'
+        '--dry-run:Only show the changes'
+    )
+
+    exec_flags=(
+        '--timeout:Stop the agent after duration'
+        '--prompt:Run args as a prompt in non-interactive mode'
+        '--json:Print a structured JSON result'
+        '--safe:Start the agent without its default permission flags'
+        '--egress:Network egress policy (open, allowlist)'
+    )
+
+    attach_flags=(
+        '--agent:Start an agent with its default flags'
+        '--user:Run as another user'
+        '--workdir:Working directory in the container'
+        '--record:Record the terminal session'
     )
 
     ps_flags=(
@@ -188,6 +353,15 @@ func generateZshCompletion(cmdName string) string {
         '-a:Show containers from all projects'
     )
 
+    stop_flags=(
+        '--all:Apply to all containers of the project'
+    )
+
+    logs_flags=(
+        '--follow:Follow the output until the container exits'
+        '-f:Follow the output until the container exits'
+    )
+
     agent_cmds=(
         'update:Update agents to latest version'
         'use:Switch agent to specific version'
@@ -199,6 +373,47 @@ func generateZshCompletion(cmdName string) string {
         'versions:List available versions'
     )
 
+    worktree_cmds=(
+        'ls:List managed worktrees'
+        'rm:Remove a worktree and its branch'
+        'merge:Merge the worktree branch into the current branch'
+    )
+
+    checkpoints_cmds=(
+        'ls:List checkpoints'
+        'restore:Restore the work tree to a checkpoint'
+    )
+
+    sessions_cmds=(
+        'ls:List recorded sessions'
+        'play:Replay a recorded session'
+    )
+
+    image_cmds=(
+        'ls:List base images'
+        'build:Build the base image of this agentbox version'
+        'prune:Remove base images of other agentbox versions'
+    )
+
+    image_build_flags=(
+        '--no-cache:Build without Docker cache'
+    )
+
+    config_cmds=(
+        'list:List settings with their values and sources'
+        'get:Print the value of a setting'
+        'set:Set a setting'
+        'unset:Remove a setting'
+    )
+
+    config_keys=(
+        {{.ConfigKeysZsh}}
+    )
+
+    config_set_flags=(
+        '--project:Edit .agentbox.toml of the current project'
+    )
+
     self_uninstall_flags=(
         '--purge:Also remove ~/.agentbox directory'
     )
@@ -224,10 +439,32 @@ func generateZshCompletion(cmdName string) string {
                 run)
                     _describe -t flags 'flag' run_flags
                     ;;
+                upgrade)
+                    _describe -t flags 'flag' upgrade_flags
+                    ;;
+                exec)
+                    _describe -t agents 'agent' agent_names
+                    ;;
                 attach)
                     local -a containers
-                    containers=(${(f)"$(docker ps --filter 'label=com.docker.compose.service=agentbox' --filter "label=com.docker.compose.project.working_dir=$(pwd)" --format '{{.ID}}:{{.Names}}' 2>/dev/null)"})
+                    local runtime=docker
+                    (( $+commands[docker] )) || runtime=podman
+                    containers=(${(f)"$($runtime ps --filter 'label=com.docker.compose.service=agentbox' --filter "label=com.docker.compose.project.working_dir=$(pwd)" --format '{{.ID}}:{{.Names}}' 2>/dev/null)"})
+                    containers+=(${(f)"$($runtime ps --filter 'label=agentbox.session' --format '{{.Names}}:session' 2>/dev/null)"})
+                    (( ${#containers} )) && _describe -t containers 'container' containers
+                    _describe -t flags 'flag' attach_flags
+                    ;;
+                stop|kill|restart|logs)
+                    local -a containers
+                    local runtime=docker
+                    (( $+commands[docker] )) || runtime=podman
+                    containers=(${(f)"$($runtime ps --filter 'label=com.docker.compose.service=agentbox' --filter "label=com.docker.compose.project.working_dir=$(pwd)" --format '{{.ID}}:{{.Names}}' 2>/dev/null)"})
+                    containers+=(${(f)"$($runtime ps --filter 'label=agentbox.session' --format '{{.Names}}:session' 2>/dev/null)"})
                     (( ${#containers} )) && _describe -t containers 'container' containers
+                    case $cmd in
+                        stop|kill) _describe -t flags 'flag' stop_flags ;;
+                        logs) _describe -t flags 'flag' logs_flags ;;
+                    esac
                     ;;
                 ps)
                     _describe -t flags 'flag' ps_flags
@@ -238,13 +475,87 @@ func generateZshCompletion(cmdName string) string {
                 self)
                     _describe -t commands 'self command' self_cmds
                     ;;
+                worktree)
+                    _describe -t commands 'worktree command' worktree_cmds
+                    ;;
+                checkpoints)
+                    _describe -t commands 'checkpoints command' checkpoints_cmds
+                    ;;
+                sessions)
+                    _describe -t commands 'sessions command' sessions_cmds
+                    ;;
+                image)
+                    _describe -t commands 'image command' image_cmds
+                    ;;
+                config)
+                    _describe -t commands 'config command' config_cmds
+                    ;;
                 completion)
                     _describe -t shells 'shell' shells
                     ;;
+                --runtime)
+                    compadd {{.Runtimes}}
+                    ;;
             esac
             ;;
         4)
             case $cmd in
+                run)
+                    if [[ ${words[3]} == --worktree ]]; then
+                        local -a worktrees
+                        worktrees=(${(f)"$(command agentbox worktree ls -q 2>/dev/null)"})
+                        (( ${#worktrees} )) && compadd -a worktrees
+                    fi
+                    ;;
+                exec)
+                    _describe -t flags 'flag' exec_flags
+                    ;;
+                attach)
+                    if [[ ${words[3]} == --agent ]]; then
+                        _describe -t agents 'agent' agent_names
+                    else
+                        _describe -t flags 'flag' attach_flags
+                    fi
+                    ;;
+                checkpoints)
+                    if [[ $subcmd == restore ]]; then
+                        local -a ids
+                        ids=(${(f)"$(command agentbox checkpoints ls -q 2>/dev/null)"})
+                        (( ${#ids} )) && compadd -a ids
+                    fi
+                    ;;
+                image)
+                    if [[ $subcmd == build ]]; then
+                        _describe -t flags 'flag' image_build_flags
+                    fi
+                    ;;
+                config)
+                    case $subcmd in
+                        get)
+                            _describe -t keys 'key' config_keys
+                            ;;
+                        set|unset)
+                            _describe -t keys 'key' config_keys
+                            _describe -t flags 'flag' config_set_flags
+                            ;;
+                    esac
+                    ;;
+                sessions)
+                    if [[ $subcmd == play ]]; then
+                        local -a ids
+                        ids=(${(f)"$(command agentbox sessions ls -q 2>/dev/null)"})
+                        (( ${#ids} )) && compadd -a ids
+                    fi
+                    ;;
+                worktree)
+                    case $subcmd in
+                        rm|merge)
+                            local -a worktrees
+                            worktrees=(${(f)"$(command agentbox worktree ls -q 2>/dev/null)"})
+                            (( ${#worktrees} )) && compadd -a worktrees
+                            ;;
+                    esac
+                    ;;
                 agent)
                     case $subcmd in
                         update)
@@ -271,6 +582,11 @@ func generateZshCompletion(cmdName string) string {
             ;;
         5)
             case $cmd in
+                config)
+                    if [[ ${words[4]} == --project ]]; then
+                        _describe -t keys 'key' config_keys
+                    fi
+                    ;;
                 agent)
                     case $subcmd in
                         update)
@@ -293,7 +609,9 @@ func generateZshCompletion(cmdName string) string {
 compdef _agentbox agentbox
 `
 	base = strings.ReplaceAll(base, "{{.AgentNamesZsh}}", agentNamesZsh)
+	base = strings.ReplaceAll(base, "{{.ConfigKeysZsh}}", configKeysZsh)
 	base = strings.ReplaceAll(base, "{{.CmdName}}", cmdName)
+	base = strings.ReplaceAll(base, "{{.Runtimes}}", strings.Join(docker.Runtimes(), " "))
 	if cmdName != "agentbox" {
 		base += fmt.Sprintf("compdef _agentbox %s\n", cmdName)
 	}
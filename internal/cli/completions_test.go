@@ -38,7 +38,7 @@ func TestGenerateBashCompletion(t *testing.T) {
 	expectedSubstrings := []string{
 		"__agentbox()",
 		"complete -F __agentbox agentbox",
-		"commands=\"init run attach ps agent self clean completion help version\"",
+		"commands=\"init upgrade run exec attach ps stop kill restart logs worktree checkpoints sessions image agent self config clean doctor completion help version\"",
 	}
 
 	for _, expected := range expectedSubstrings {
@@ -0,0 +1,593 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aleksey925/agentbox/internal/agents"
+	"github.com/aleksey925/agentbox/internal/config"
+	"github.com/aleksey925/agentbox/internal/docker"
+	"github.com/aleksey925/agentbox/internal/egress"
+	"github.com/aleksey925/agentbox/internal/gitcredential"
+	"github.com/aleksey925/agentbox/internal/ignore"
+	"github.com/aleksey925/agentbox/internal/review"
+)
+
+// sandbox is a prepared sandbox container.
+type sandbox struct {
+	docker.RunOptions
+	// egress is set when outgoing traffic is restricted to an allowlist.
+	egress *egress.Files
+	// review is set when the sandbox works on a copy of the project.
+	review *review.Session
+	// workDir is the host directory the agent changes, empty in review mode.
+	workDir string
+	// credentials is set when git in the sandbox gets credentials from the host.
+	credentials *gitcredential.Proxy
+	// overridePath is the compose override generated for the session.
+	overridePath string
+	// buildHash identifies the build inputs, the image is rebuilt when it changes.
+	buildHash string
+	settings  *config.Settings
+}
+
+// prepareSandbox prepares everything a sandbox container needs: launcher
+// environment, labels, sidecars and the generated compose override.
+func (a *App) prepareSandbox(projectDir string, opts runOptions) (*sandbox, error) {
+	paths, err := config.NewPaths()
+	if err != nil {
+		return nil, fmt.Errorf("get paths: %w", err)
+	}
+
+	settings, err := config.LoadSettings(paths, projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("load settings: %w", err)
+	}
+
+	manager, err := agents.NewManager(paths)
+	if err != nil {
+		return nil, fmt.Errorf("create agent manager: %w", err)
+	}
+
+	enabled, err := enabledAgents(manager, settings.Sandbox.Agents)
+	if err != nil {
+		return nil, err
+	}
+
+	sb := &sandbox{workDir: projectDir, settings: settings}
+	env := launcherEnv(enabled, settings, opts)
+	override := &docker.Override{}
+	labels := make(map[string]string)
+	stateDir := paths.ProjectStateDir(projectDir)
+
+	if opts.name != "" {
+		if err := checkSessionName(opts.name); err != nil {
+			return nil, err
+		}
+		labels[docker.LabelSession] = opts.name
+	}
+
+	if opts.worktree != "" {
+		volumes, worktreeDir, err := worktreeVolumes(paths, projectDir, opts.worktree)
+		if err != nil {
+			return nil, err
+		}
+		override.Volumes = append(override.Volumes, volumes...)
+		sb.workDir = worktreeDir
+		labels[docker.LabelWorktree] = opts.worktree
+	}
+
+	mountedDir := projectDir
+	if sb.workDir != "" {
+		mountedDir = sb.workDir
+	}
+	hidden, err := maskIgnored(override, projectDir, mountedDir, stateDir)
+	if err != nil {
+		return nil, err
+	}
+	printHidden(os.Stderr, hidden)
+
+	rt, err := docker.CurrentRuntime()
+	if err != nil {
+		return nil, err
+	}
+
+	var reconfigure bool
+	mode := egressMode(settings, opts)
+	if err := config.ValidateEgress(mode); err != nil {
+		return nil, err
+	}
+	if mode == config.EgressAllowlist {
+		if err := docker.CheckOverrideTag(context.Background(), rt); err != nil {
+			return nil, fmt.Errorf("allowlist egress mode: %w", err)
+		}
+		files, changed, err := egress.WriteConfig(filepath.Join(stateDir, "egress"), egressDomains(enabled, settings))
+		if err != nil {
+			return nil, err
+		}
+		reconfigure = changed
+		addEgressProxy(override, files)
+		maps.Copy(env, egress.ProxyEnv())
+		sb.egress = &files
+	}
+
+	resources, err := sandboxResources(settings.Resources, opts.resources)
+	if err != nil {
+		return nil, err
+	}
+	if !resources.IsZero() {
+		host, err := docker.Info()
+		if err != nil {
+			return nil, fmt.Errorf("check resource limits: %w", err)
+		}
+		if err := resources.Validate(host); err != nil {
+			return nil, fmt.Errorf("resource limits cannot be applied: %w", err)
+		}
+		override.Resources = resources
+	}
+
+	if opts.hostGateway {
+		settings.Sandbox.HostGateway = true
+	}
+	if err := applySandboxSettings(override, settings.Sandbox, paths.HomeDir, projectDir); err != nil {
+		return nil, err
+	}
+	override.Ports = append(override.Ports, opts.ports...)
+	if len(override.Ports) > 0 && mode == config.EgressAllowlist {
+		fmt.Fprintln(os.Stderr, "Warning: published ports may be unreachable in allowlist egress mode, the sandbox is only on an internal network")
+	}
+	if err := disableAgents(override, paths, manager, enabled, stateDir); err != nil {
+		return nil, err
+	}
+
+	override.UsernsMode = rt.UsernsMode()
+	override.BuildArgs = map[string]string{docker.BaseImageArg: docker.BaseImage(a.Version)}
+	if tools := toolsArg(settings.Tools); tools != "" {
+		override.BuildArgs[docker.ToolsArg] = tools
+	}
+	sb.buildHash, err = docker.BuildHash(projectDir, override.BuildArgs)
+	if err != nil {
+		return nil, fmt.Errorf("hash build inputs: %w", err)
+	}
+	override.BuildLabels = docker.BuildLabels(projectDir, sb.buildHash)
+
+	var gitConfig []string
+	if settings.Git.Forward || opts.forwardGit {
+		gitConfig = forwardGit(os.Stderr, override, env, rt.Name(), paths.HomeDir, projectDir)
+	}
+	// started late, so a failed preparation does not leave it running
+	if len(settings.Git.CredentialHosts) > 0 {
+		proxy, helperConfig, err := startCredentialProxy(override, runtime.GOOS, paths.RuntimeDir(), settings.Git.CredentialHosts)
+		if err != nil {
+			return nil, err
+		}
+		sb.credentials = proxy
+		gitConfig = append(gitConfig, helperConfig...)
+	}
+	maps.Copy(env, gitConfigEnv(gitConfig))
+
+	// copied last, so a failed preparation does not leave a copy behind
+	if opts.review {
+		fmt.Println("Copying project for review...")
+		matcher, err := ignore.Load(projectDir)
+		if err != nil {
+			sb.cleanup()
+			return nil, err
+		}
+		session, err := review.Create(filepath.Join(stateDir, "review"), projectDir, matcher)
+		if err != nil {
+			sb.cleanup()
+			return nil, err
+		}
+		override.Volumes = append(override.Volumes, session.Volumes(containerProjectDir)...)
+		sb.review = session
+		sb.workDir = ""
+	}
+
+	sb.overridePath, err = docker.WriteOverride(stateDir, overrideKey(opts), override)
+	if err != nil {
+		sb.cleanup()
+		return nil, fmt.Errorf("generate compose override: %w", err)
+	}
+
+	sb.RunOptions = docker.RunOptions{
+		Name:         opts.name,
+		Files:        []string{sb.overridePath},
+		Env:          env,
+		Labels:       labels,
+		ServicePorts: len(override.Ports) > 0,
+	}
+
+	// the proxy is shared by all sessions of the project and keeps the
+	// configuration it started with until told to reread it
+	if reconfigure {
+		err := docker.ExecService(projectDir, sb.Files, egress.ProxyService, egress.ReconfigureCommand()...)
+		if err != nil {
+			sb.cleanup()
+			return nil, fmt.Errorf("reconfigure egress proxy: %w", err)
+		}
+	}
+	return sb, nil
+}
+
+// checkSessionName fails if a session with the name is already running in any project.
+func checkSessionName(name string) error {
+	containers, err := docker.ListContainers("", true)
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		if c.Session == name || c.Name == name {
+			return fmt.Errorf("session %s is already running in %s", name, c.ProjectDir)
+		}
+	}
+	return nil
+}
+
+// maskIgnored hides the paths matched by the project's .agentboxignore in the
+// mounted directory: directories are covered with an empty tmpfs and files with
+// an empty read-only placeholder. Returns the hidden paths.
+func maskIgnored(override *docker.Override, projectDir, mountedDir, stateDir string) ([]ignore.Match, error) {
+	matcher, err := ignore.Load(projectDir)
+	if err != nil || matcher == nil {
+		return nil, err
+	}
+
+	matches, err := matcher.Walk(mountedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	placeholder := filepath.Join(stateDir, "ignore", "empty")
+	hasFiles := false
+	for _, m := range matches {
+		target := path.Join(containerProjectDir, m.Path)
+		if m.IsDir {
+			override.Tmpfs = append(override.Tmpfs, target)
+			continue
+		}
+		override.Volumes = append(override.Volumes, placeholder+":"+target+":ro")
+		hasFiles = true
+	}
+
+	if hasFiles {
+		if err := os.MkdirAll(filepath.Dir(placeholder), 0o755); err != nil {
+			return nil, fmt.Errorf("create ignore placeholder: %w", err)
+		}
+		if err := os.WriteFile(placeholder, nil, 0o644); err != nil {
+			return nil, fmt.Errorf("create ignore placeholder: %w", err)
+		}
+	}
+	return matches, nil
+}
+
+// maxHiddenShown limits the hidden paths printed when a sandbox starts.
+const maxHiddenShown = 10
+
+// printHidden reports the paths hidden from the sandbox.
+func printHidden(w io.Writer, hidden []ignore.Match) {
+	if len(hidden) == 0 {
+		return
+	}
+
+	names := make([]string, 0, min(len(hidden), maxHiddenShown))
+	for _, m := range hidden[:min(len(hidden), maxHiddenShown)] {
+		if m.IsDir {
+			names = append(names, m.Path+"/")
+		} else {
+			names = append(names, m.Path)
+		}
+	}
+	if more := len(hidden) - len(names); more > 0 {
+		names = append(names, fmt.Sprintf("and %d more", more))
+	}
+	fmt.Fprintf(w, "Hidden by %s: %s\n", ignore.File, strings.Join(names, ", "))
+}
+
+// cleanup releases what the sandbox still holds: the git credential proxy, the
+// compose override and the review copy, unless it was handed over for review.
+// Safe to call twice.
+func (sb *sandbox) cleanup() {
+	sb.closeCredentials()
+	if sb.overridePath != "" {
+		if err := os.Remove(sb.overridePath); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		sb.overridePath = ""
+	}
+	if sb.review != nil {
+		if err := sb.review.Remove(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		sb.review = nil
+	}
+}
+
+// overrideKey names the compose override of a session: the session name, which
+// is unique among running sessions, or the worktree and the process id.
+func overrideKey(opts runOptions) string {
+	if opts.name != "" {
+		return opts.name
+	}
+	key := strconv.Itoa(os.Getpid())
+	if opts.worktree != "" {
+		key = opts.worktree + "-" + key
+	}
+	return key
+}
+
+// closeCredentials stops the git credential proxy, if any, and returns the
+// hosts whose credentials it refused.
+func (sb *sandbox) closeCredentials() []string {
+	if sb.credentials == nil {
+		return nil
+	}
+	if err := sb.credentials.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	refused := sb.credentials.Refused()
+	sb.credentials = nil
+	return refused
+}
+
+// finishSandbox cleans up after a session and returns the hosts the egress
+// proxy denied since started. Refused git credentials are reported here.
+func finishSandbox(projectDir string, sb *sandbox, started time.Time) []egress.Denied {
+	printRefused(os.Stderr, sb.closeCredentials(), sb.settings.Git.CredentialHosts)
+
+	if sb.egress == nil {
+		return nil
+	}
+
+	denied, err := egress.ReadDenied(*sb.egress, started)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// the proxy is shared by all sessions of the project, stop it with the last one
+	if containers, err := docker.ListContainers(projectDir, false); err == nil && len(containers) == 0 {
+		if err := docker.RemoveService(projectDir, sb.Files, egress.ProxyService); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	return denied
+}
+
+// launcherEnv returns the container environment that configures the launchers
+// of the enabled agents according to the global and project settings.
+func launcherEnv(enabled []agents.Agent, settings *config.Settings, opts runOptions) map[string]string {
+	env := make(map[string]string)
+	for _, agent := range enabled {
+		args := settings.AgentArgs(agent.Name(), agent.DefaultArgs())
+		env[agents.ArgsEnvVar(agent.Name())] = agents.ShellJoin(args)
+	}
+	if opts.safe {
+		env[agents.SafeEnvVar] = "1"
+	}
+	return env
+}
+
+// enabledAgents returns the agents listed in sandbox.agents, or all agents if
+// the list is empty.
+func enabledAgents(manager *agents.Manager, names []string) ([]agents.Agent, error) {
+	if len(names) == 0 {
+		return manager.AllAgents(), nil
+	}
+	enabled := make([]agents.Agent, 0, len(names))
+	for _, name := range names {
+		agent, ok := manager.GetAgent(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown agent %q in sandbox.agents, expected one of: %s",
+				name, strings.Join(agents.AllAgentNames(), ", "))
+		}
+		enabled = append(enabled, agent)
+	}
+	return enabled, nil
+}
+
+// disabledLauncher replaces the launcher of an agent missing from sandbox.agents.
+const disabledLauncher = `#!/bin/sh
+echo "%s is disabled by sandbox.agents in %s" >&2
+exit 1
+`
+
+// disableAgents hides the agents that are not enabled: their binaries and
+// settings are covered and their launchers only print why they are missing.
+func disableAgents(override *docker.Override, paths *config.Paths, manager *agents.Manager, enabled []agents.Agent, stateDir string) error {
+	on := make(map[string]bool, len(enabled))
+	for _, agent := range enabled {
+		on[agent.Name()] = true
+	}
+
+	configPaths := agentConfigPaths(paths.HomeDir)
+	for _, agent := range manager.AllAgents() {
+		name := agent.Name()
+		if on[name] {
+			continue
+		}
+
+		// the mount point must exist in the read-only bin directory
+		if _, err := os.Stat(paths.AgentDir(name)); err == nil {
+			override.Tmpfs = append(override.Tmpfs, agents.ContainerBinDir+"/"+name)
+		}
+
+		launcher := filepath.Join(stateDir, "disabled", name)
+		if err := os.MkdirAll(filepath.Dir(launcher), 0o755); err != nil {
+			return fmt.Errorf("create disabled launcher: %w", err)
+		}
+		content := fmt.Sprintf(disabledLauncher, name, config.ProjectConfigFile)
+		if err := os.WriteFile(launcher, []byte(content), 0o755); err != nil {
+			return fmt.Errorf("create disabled launcher: %w", err)
+		}
+		override.Volumes = append(override.Volumes, launcher+":"+agents.LauncherPath(name)+":ro")
+
+		// the settings are replaced by mounts on the same targets, which compose
+		// merges with the mounts of docker-compose.agentbox.yml
+		for _, p := range configPaths {
+			if p.agent != name {
+				continue
+			}
+			empty := filepath.Join(stateDir, "disabled", filepath.Base(p.path))
+			target := path.Join(containerHomeDir, filepath.Base(p.path))
+			var err error
+			if p.dir {
+				err = os.MkdirAll(empty, 0o755)
+				// written with a trailing slash in docker-compose.agentbox.yml
+				target += "/"
+			} else {
+				err = os.WriteFile(empty, []byte("{}"), 0o644)
+			}
+			if err != nil {
+				return fmt.Errorf("create disabled config: %w", err)
+			}
+			override.Volumes = append(override.Volumes, empty+":"+target+":ro")
+		}
+	}
+	return nil
+}
+
+// applySandboxSettings compiles the [sandbox] table of the settings into the override.
+func applySandboxSettings(override *docker.Override, settings config.SandboxSettings, home, projectDir string) error {
+	for _, mount := range settings.Mounts {
+		volume, err := resolveMount(mount, home, projectDir)
+		if err != nil {
+			return err
+		}
+		override.Volumes = append(override.Volumes, volume)
+	}
+
+	if len(settings.Env) > 0 {
+		override.Environment = maps.Clone(settings.Env)
+	}
+	override.Ports = append(override.Ports, settings.Ports...)
+	if settings.HostGateway {
+		override.ExtraHosts = append(override.ExtraHosts, docker.HostGateway)
+	}
+
+	if settings.Security == config.SecurityStrict {
+		override.CapDrop = []string{"ALL"}
+		override.SecurityOpt = []string{"no-new-privileges:true"}
+	}
+	return nil
+}
+
+// resolveMount makes the host path of a sandbox.mounts entry absolute: "~" is
+// the home directory and relative paths are relative to the project directory.
+func resolveMount(mount, home, projectDir string) (string, error) {
+	if err := config.ValidateMount(mount); err != nil {
+		return "", fmt.Errorf("sandbox.mounts: %w", err)
+	}
+	host, rest, _ := strings.Cut(mount, ":")
+	switch {
+	case host == "~" || strings.HasPrefix(host, "~/"):
+		host = filepath.Join(home, host[1:])
+	case !filepath.IsAbs(host):
+		host = filepath.Join(projectDir, host)
+	}
+	// docker would create a missing host path as a root-owned directory
+	if _, err := os.Stat(host); err != nil {
+		return "", fmt.Errorf("sandbox.mounts: %w", err)
+	}
+	return host + ":" + rest, nil
+}
+
+// toolsArg returns the mise tools of the [tools] table, sorted, as "name@version".
+func toolsArg(tools map[string]string) string {
+	specs := make([]string, 0, len(tools))
+	for _, name := range slices.Sorted(maps.Keys(tools)) {
+		specs = append(specs, name+"@"+tools[name])
+	}
+	return strings.Join(specs, " ")
+}
+
+// sandboxResources returns the resource limits from the run flags, falling back
+// to the settings for limits not given as flags.
+func sandboxResources(settings config.ResourceSettings, flags docker.Resources) (docker.Resources, error) {
+	resources := flags
+	if resources.CPUs == 0 && settings.CPUs != 0 {
+		if settings.CPUs < 0 {
+			return resources, fmt.Errorf("invalid resources.cpus: %g", settings.CPUs)
+		}
+		resources.CPUs = settings.CPUs
+	}
+	if resources.Memory == 0 && settings.Memory != "" {
+		memory, err := config.ParseMemory(settings.Memory)
+		if err != nil {
+			return resources, fmt.Errorf("invalid resources.memory: %w", err)
+		}
+		resources.Memory = memory
+	}
+	if resources.PidsLimit == 0 && settings.PidsLimit != 0 {
+		if settings.PidsLimit < 0 {
+			return resources, fmt.Errorf("invalid resources.pids_limit: %d", settings.PidsLimit)
+		}
+		resources.PidsLimit = settings.PidsLimit
+	}
+	return resources, nil
+}
+
+// egressMode returns the egress mode from the run flags or settings.
+func egressMode(settings *config.Settings, opts runOptions) string {
+	switch {
+	case opts.egress != "":
+		return opts.egress
+	case settings.Network.Egress != "":
+		return settings.Network.Egress
+	default:
+		return config.EgressOpen
+	}
+}
+
+// egressDomains returns the endpoints of the enabled agents and the configured extra domains.
+func egressDomains(enabled []agents.Agent, settings *config.Settings) []string {
+	var domains []string
+	for _, agent := range enabled {
+		domains = append(domains, agent.Domains()...)
+	}
+	return append(domains, settings.Network.Allow...)
+}
+
+// addEgressProxy moves the sandbox to an internal network where the filtering
+// proxy is the only way out.
+func addEgressProxy(override *docker.Override, files egress.Files) {
+	override.Networks = []string{egress.Network}
+	override.DependsOn = append(override.DependsOn, egress.ProxyService)
+	override.Services = append(override.Services, docker.Service{
+		Name:     egress.ProxyService,
+		Image:    egress.ProxyImage,
+		Volumes:  files.Volumes(),
+		Networks: []string{egress.Network, "default"},
+	})
+	override.InternalNetworks = append(override.InternalNetworks, egress.Network)
+}
+
+// printDenied reports requests blocked by the egress proxy.
+func printDenied(w io.Writer, denied []egress.Denied) {
+	if len(denied) == 0 {
+		return
+	}
+
+	hosts := make([]string, 0, len(denied))
+	fmt.Fprintln(w, "\nEgress policy blocked requests to:")
+	for _, d := range denied {
+		requests := "request"
+		if d.Count > 1 {
+			requests = "requests"
+		}
+		fmt.Fprintf(w, "  %s (%d %s)\n", d.Host, d.Count, requests)
+		hosts = append(hosts, strconv.Quote(d.Host))
+	}
+	fmt.Fprintf(w, "\nTo allow them, add to %s:\n", config.ProjectConfigFile)
+	fmt.Fprintf(w, "  [network]\n  allow = [%s]\n", strings.Join(hosts, ", "))
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/aleksey925/agentbox/internal/config"
+	"github.com/aleksey925/agentbox/internal/docker"
+	"github.com/aleksey925/agentbox/internal/git"
+	"github.com/aleksey925/agentbox/internal/gitcredential"
+)
+
+const (
+	// containerSSHAgent is where the host SSH agent socket is mounted.
+	containerSSHAgent = "/run/agentbox/ssh-agent.sock"
+	// containerKnownHosts is where the host known_hosts file is mounted.
+	containerKnownHosts = "/run/agentbox/known_hosts"
+	// dockerDesktopSSHAgent is the SSH agent of macOS hosts inside Docker Desktop.
+	dockerDesktopSSHAgent = "/run/host-services/ssh-auth.sock"
+)
+
+// sshAgentSocket returns the host SSH agent socket to mount, empty if there is none.
+// Docker Desktop cannot mount macOS sockets and forwards the agent itself.
+func sshAgentSocket(goos, runtimeName, authSock string) string {
+	if goos == "darwin" && runtimeName == docker.RuntimeDocker {
+		return dockerDesktopSSHAgent
+	}
+	return authSock
+}
+
+// forwardGit mounts the host SSH agent and known hosts into the sandbox and
+// returns the git config entries, as key and value pairs, that copy the git
+// identity of the host.
+func forwardGit(w io.Writer, override *docker.Override, env map[string]string, runtimeName, home, projectDir string) []string {
+	if socket := sshAgentSocket(runtime.GOOS, runtimeName, os.Getenv("SSH_AUTH_SOCK")); socket != "" {
+		override.Volumes = append(override.Volumes, socket+":"+containerSSHAgent)
+		env["SSH_AUTH_SOCK"] = containerSSHAgent
+	} else {
+		fmt.Fprintln(w, "Warning: SSH_AUTH_SOCK is not set, the SSH agent is not forwarded")
+	}
+
+	// without the known hosts git over SSH fails to verify the server
+	knownHosts := filepath.Join(home, ".ssh", "known_hosts")
+	if _, err := os.Stat(knownHosts); err == nil {
+		override.Volumes = append(override.Volumes, knownHosts+":"+containerKnownHosts+":ro")
+		env["GIT_SSH_COMMAND"] = `ssh -o "UserKnownHostsFile=~/.ssh/known_hosts ` + containerKnownHosts + `"`
+	}
+
+	var gitConfig []string
+	for _, key := range []string{"user.name", "user.email"} {
+		if value := git.ConfigValue(projectDir, key); value != "" {
+			gitConfig = append(gitConfig, key, value)
+		}
+	}
+	return gitConfig
+}
+
+// gitConfigEnv returns the environment that adds git config entries, given as
+// key and value pairs, without writing a config file in the sandbox.
+func gitConfigEnv(entries []string) map[string]string {
+	env := make(map[string]string)
+	if len(entries) == 0 {
+		return env
+	}
+	count := len(entries) / 2
+	env["GIT_CONFIG_COUNT"] = strconv.Itoa(count)
+	for i := range count {
+		env["GIT_CONFIG_KEY_"+strconv.Itoa(i)] = entries[2*i]
+		env["GIT_CONFIG_VALUE_"+strconv.Itoa(i)] = entries[2*i+1]
+	}
+	return env
+}
+
+// startCredentialProxy starts the proxy that gives git in the sandbox the host
+// credentials of the allowed hosts, and returns the git config entries that use it.
+// On macOS the containers run in a VM that cannot bind mount the proxy socket.
+func startCredentialProxy(override *docker.Override, goos, runDir string, hosts []string) (*gitcredential.Proxy, []string, error) {
+	if goos == "darwin" {
+		return nil, nil, errors.New("git.credential_hosts is not supported on macOS, the container VM cannot connect to the credential socket; remove it or use git.forward with an SSH agent")
+	}
+	proxy, err := gitcredential.Start(runDir, hosts)
+	if err != nil {
+		return nil, nil, err
+	}
+	override.Volumes = append(override.Volumes, proxy.Volume())
+	return proxy, []string{"credential.helper", gitcredential.HelperPath}, nil
+}
+
+// printRefused reports the hosts whose git credentials were not given to the sandbox.
+func printRefused(w io.Writer, refused, allowed []string) {
+	if len(refused) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "\nGit credentials were not given for:")
+	hosts := make([]string, 0, len(allowed)+len(refused))
+	for _, host := range allowed {
+		hosts = append(hosts, strconv.Quote(host))
+	}
+	for _, host := range refused {
+		fmt.Fprintf(w, "  %s\n", host)
+		hosts = append(hosts, strconv.Quote(host))
+	}
+	fmt.Fprintf(w, "\nTo allow them, add to %s:\n", config.ProjectConfigFile)
+	fmt.Fprintf(w, "  [git]\n  credential_hosts = [%s]\n", strings.Join(hosts, ", "))
+}
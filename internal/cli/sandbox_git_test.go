@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aleksey925/agentbox/internal/docker"
+)
+
+// shortTempDir returns a temporary directory for unix sockets, whose paths are
+// limited to about 100 characters; t.TempDir is too long on macOS.
+func shortTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "agentbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+func TestSSHAgentSocket(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		runtime  string
+		authSock string
+		expected string
+	}{
+		{"linux", "linux", docker.RuntimeDocker, "/tmp/ssh-x/agent.1", "/tmp/ssh-x/agent.1"},
+		{"linux without agent", "linux", docker.RuntimePodman, "", ""},
+		{"docker desktop", "darwin", docker.RuntimeDocker, "/private/tmp/launchd/Listeners", dockerDesktopSSHAgent},
+		{"podman on macOS", "darwin", docker.RuntimePodman, "/private/tmp/launchd/Listeners", "/private/tmp/launchd/Listeners"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result := sshAgentSocket(tt.goos, tt.runtime, tt.authSock)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("sshAgentSocket() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestForwardGit(t *testing.T) {
+	// arrange
+	home := t.TempDir()
+	writeTestFile(t, filepath.Join(home, ".ssh", "known_hosts"), "github.com ssh-ed25519 AAAA\n")
+	gitConfig := filepath.Join(home, ".gitconfig")
+	writeTestFile(t, gitConfig, "[user]\n\tname = Jane Doe\n\temail = jane@example.com\n")
+	t.Setenv("GIT_CONFIG_GLOBAL", gitConfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("SSH_AUTH_SOCK", "/tmp/ssh-x/agent.1")
+	override := &docker.Override{}
+	env := map[string]string{}
+	var warnings bytes.Buffer
+
+	// act
+	entries := forwardGit(&warnings, override, env, docker.RuntimePodman, home, t.TempDir())
+
+	// assert
+	expectedVolumes := []string{
+		"/tmp/ssh-x/agent.1:" + containerSSHAgent,
+		filepath.Join(home, ".ssh", "known_hosts") + ":" + containerKnownHosts + ":ro",
+	}
+	if !slices.Equal(override.Volumes, expectedVolumes) {
+		t.Errorf("Volumes = %v, want %v", override.Volumes, expectedVolumes)
+	}
+	if env["SSH_AUTH_SOCK"] != containerSSHAgent || !strings.Contains(env["GIT_SSH_COMMAND"], containerKnownHosts) {
+		t.Errorf("env = %v, want the agent socket and known hosts", env)
+	}
+	if !slices.Equal(entries, []string{"user.name", "Jane Doe", "user.email", "jane@example.com"}) {
+		t.Errorf("entries = %q, want the host identity", entries)
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("unexpected warnings: %s", warnings.String())
+	}
+}
+
+func TestForwardGit__no_agent(t *testing.T) {
+	// arrange
+	t.Setenv("SSH_AUTH_SOCK", "")
+	override := &docker.Override{}
+	var warnings bytes.Buffer
+
+	// act
+	forwardGit(&warnings, override, map[string]string{}, docker.RuntimePodman, t.TempDir(), t.TempDir())
+
+	// assert
+	if len(override.Volumes) != 0 {
+		t.Errorf("Volumes = %v, want none", override.Volumes)
+	}
+	if !strings.Contains(warnings.String(), "SSH agent is not forwarded") {
+		t.Errorf("warnings = %q, want a missing agent warning", warnings.String())
+	}
+}
+
+func TestGitConfigEnv(t *testing.T) {
+	// act
+	env := gitConfigEnv([]string{"user.name", "Jane Doe", "credential.helper", "/run/agentbox/git/credential-helper"})
+	empty := gitConfigEnv(nil)
+
+	// assert
+	expected := map[string]string{
+		"GIT_CONFIG_COUNT":   "2",
+		"GIT_CONFIG_KEY_0":   "user.name",
+		"GIT_CONFIG_VALUE_0": "Jane Doe",
+		"GIT_CONFIG_KEY_1":   "credential.helper",
+		"GIT_CONFIG_VALUE_1": "/run/agentbox/git/credential-helper",
+	}
+	for key, value := range expected {
+		if env[key] != value {
+			t.Errorf("env[%s] = %q, want %q", key, env[key], value)
+		}
+	}
+	if len(empty) != 0 {
+		t.Errorf("gitConfigEnv(nil) = %v, want empty", empty)
+	}
+}
+
+func TestStartCredentialProxy(t *testing.T) {
+	// arrange
+	override := &docker.Override{}
+
+	// act
+	proxy, entries, err := startCredentialProxy(override, "linux", shortTempDir(t), []string{"github.com"})
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = proxy.Close() })
+	if !slices.Equal(override.Volumes, []string{proxy.Volume()}) {
+		t.Errorf("Volumes = %v, want the proxy volume", override.Volumes)
+	}
+	if !slices.Equal(entries, []string{"credential.helper", "/run/agentbox/git/credential-helper"}) {
+		t.Errorf("entries = %q", entries)
+	}
+	if _, err := os.Stat(strings.Split(proxy.Volume(), ":")[0]); err != nil {
+		t.Errorf("proxy dir: %v", err)
+	}
+}
+
+func TestStartCredentialProxy__macos(t *testing.T) {
+	// arrange
+	override := &docker.Override{}
+
+	// act
+	proxy, _, err := startCredentialProxy(override, "darwin", shortTempDir(t), []string{"github.com"})
+
+	// assert
+	if err == nil {
+		_ = proxy.Close()
+		t.Fatal("expected an error on macOS")
+	}
+	if !strings.Contains(err.Error(), "git.credential_hosts") {
+		t.Errorf("error = %q, want it to name the setting", err)
+	}
+	if len(override.Volumes) != 0 {
+		t.Errorf("Volumes = %v, want none", override.Volumes)
+	}
+}
+
+func TestPrintRefused(t *testing.T) {
+	// arrange
+	var buf bytes.Buffer
+
+	// act
+	printRefused(&buf, []string{"gitlab.com"}, []string{"github.com"})
+
+	// assert
+	output := buf.String()
+	for _, expected := range []string{
+		"  gitlab.com\n",
+		"[git]\n",
+		`credential_hosts = ["github.com", "gitlab.com"]`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("output missing %q:\n%s", expected, output)
+		}
+	}
+}
+
+func TestPrintRefused__nothing_refused(t *testing.T) {
+	// arrange
+	var buf bytes.Buffer
+
+	// act
+	printRefused(&buf, nil, []string{"github.com"})
+
+	// assert
+	if buf.Len() != 0 {
+		t.Errorf("printRefused printed %q, want nothing", buf.String())
+	}
+}
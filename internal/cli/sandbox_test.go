@@ -0,0 +1,495 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aleksey925/agentbox/internal/agents"
+	"github.com/aleksey925/agentbox/internal/config"
+	"github.com/aleksey925/agentbox/internal/docker"
+	"github.com/aleksey925/agentbox/internal/egress"
+	"github.com/aleksey925/agentbox/internal/gitcredential"
+	"github.com/aleksey925/agentbox/internal/ignore"
+	"github.com/aleksey925/agentbox/internal/review"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEgressMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		flag     string
+		expected string
+	}{
+		{"default", "", "", config.EgressOpen},
+		{"from settings", config.EgressAllowlist, "", config.EgressAllowlist},
+		{"flag overrides settings", config.EgressAllowlist, config.EgressOpen, config.EgressOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			settings := &config.Settings{Network: config.NetworkSettings{Egress: tt.settings}}
+
+			// act
+			result := egressMode(settings, runOptions{egress: tt.flag})
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("egressMode = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSandboxResources(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings config.ResourceSettings
+		flags    docker.Resources
+		expected docker.Resources
+		wantErr  bool
+	}{
+		{"no limits", config.ResourceSettings{}, docker.Resources{}, docker.Resources{}, false},
+		{
+			"from settings",
+			config.ResourceSettings{CPUs: 2, Memory: "4g", PidsLimit: 512},
+			docker.Resources{},
+			docker.Resources{CPUs: 2, Memory: 4 << 30, PidsLimit: 512},
+			false,
+		},
+		{
+			"flags override settings",
+			config.ResourceSettings{CPUs: 2, Memory: "4g"},
+			docker.Resources{Memory: 1 << 30, PidsLimit: 100},
+			docker.Resources{CPUs: 2, Memory: 1 << 30, PidsLimit: 100},
+			false,
+		},
+		{"invalid memory", config.ResourceSettings{Memory: "lots"}, docker.Resources{}, docker.Resources{}, true},
+		{"negative cpus", config.ResourceSettings{CPUs: -1}, docker.Resources{}, docker.Resources{}, true},
+		{"negative pids limit", config.ResourceSettings{PidsLimit: -1}, docker.Resources{}, docker.Resources{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result, err := sandboxResources(tt.settings, tt.flags)
+
+			// assert
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sandboxResources error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("sandboxResources = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseRunFlags__resources(t *testing.T) {
+	// arrange
+	app := &App{Version: "test"}
+
+	// act
+	opts, err := app.parseRunFlags([]string{"--cpus", "1.5", "--memory", "2g", "--pids-limit", "256"})
+
+	// assert
+	if err != nil {
+		t.Fatalf("parseRunFlags error: %v", err)
+	}
+	expected := docker.Resources{CPUs: 1.5, Memory: 2 << 30, PidsLimit: 256}
+	if opts.resources != expected {
+		t.Errorf("resources = %+v, want %+v", opts.resources, expected)
+	}
+}
+
+func TestParseRunFlags__ports(t *testing.T) {
+	// arrange
+	app := &App{Version: "test"}
+
+	// act
+	opts, err := app.parseRunFlags([]string{"-p", "3000", "--publish", "127.0.0.1:8080:80", "--host-gateway"})
+	_, invalidErr := app.parseRunFlags([]string{"-p", "localhost:3000"})
+
+	// assert
+	if err != nil {
+		t.Fatalf("parseRunFlags error: %v", err)
+	}
+	if !slices.Equal(opts.ports, []string{"3000", "127.0.0.1:8080:80"}) || !opts.hostGateway {
+		t.Errorf("ports = %v, hostGateway = %v", opts.ports, opts.hostGateway)
+	}
+	if invalidErr == nil {
+		t.Error("parseRunFlags(-p localhost:3000) should fail")
+	}
+}
+
+func TestParseRunFlags__invalid_resources(t *testing.T) {
+	tests := [][]string{
+		{"--cpus", "0"},
+		{"--memory", "4t"},
+		{"--pids-limit", "-5"},
+		{"--memory"},
+	}
+
+	for _, args := range tests {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			// arrange
+			app := &App{Version: "test"}
+
+			// act
+			_, err := app.parseRunFlags(args)
+
+			// assert
+			if err == nil {
+				t.Errorf("parseRunFlags(%v) should fail", args)
+			}
+		})
+	}
+}
+
+func TestAddEgressProxy(t *testing.T) {
+	// arrange
+	override := &docker.Override{}
+	files := egress.Files{Config: "/state/egress/squid.conf", LogsDir: "/state/egress/logs"}
+
+	// act
+	addEgressProxy(override, files)
+
+	// assert
+	rendered := string(override.Render())
+	for _, expected := range []string{
+		"    networks: !override\n      - \"agentbox-egress\"\n",
+		"  egress:\n    image: \"" + egress.ProxyImage + "\"\n",
+		"  agentbox-egress:\n    internal: true\n",
+	} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("override missing %q:\n%s", expected, rendered)
+		}
+	}
+}
+
+func TestPrintDenied(t *testing.T) {
+	// arrange
+	var buf bytes.Buffer
+	denied := []egress.Denied{
+		{Host: "registry.npmjs.org", Count: 3},
+		{Host: "example.org", Count: 1},
+	}
+
+	// act
+	printDenied(&buf, denied)
+
+	// assert
+	output := buf.String()
+	for _, expected := range []string{
+		"registry.npmjs.org (3 requests)",
+		"example.org (1 request)",
+		`allow = ["registry.npmjs.org", "example.org"]`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("output missing %q:\n%s", expected, output)
+		}
+	}
+}
+
+func TestPrintDenied__nothing_denied(t *testing.T) {
+	// arrange
+	var buf bytes.Buffer
+
+	// act
+	printDenied(&buf, nil)
+
+	// assert
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+}
+
+func TestMaskIgnored(t *testing.T) {
+	// arrange
+	projectDir := t.TempDir()
+	stateDir := t.TempDir()
+	writeTestFile(t, filepath.Join(projectDir, ignore.File), ".env\nsecrets/\n")
+	writeTestFile(t, filepath.Join(projectDir, ".env"), "TOKEN=1")
+	writeTestFile(t, filepath.Join(projectDir, "secrets", "db.json"), "{}")
+	writeTestFile(t, filepath.Join(projectDir, "main.go"), "package main")
+	override := &docker.Override{}
+
+	// act
+	hidden, err := maskIgnored(override, projectDir, projectDir, stateDir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("maskIgnored error: %v", err)
+	}
+	if len(hidden) != 2 {
+		t.Errorf("hidden = %v, want 2 paths", hidden)
+	}
+	placeholder := filepath.Join(stateDir, "ignore", "empty")
+	expectedVolumes := []string{placeholder + ":/home/box/app/.env:ro"}
+	if !slices.Equal(override.Volumes, expectedVolumes) {
+		t.Errorf("Volumes = %v, want %v", override.Volumes, expectedVolumes)
+	}
+	if !slices.Equal(override.Tmpfs, []string{"/home/box/app/secrets"}) {
+		t.Errorf("Tmpfs = %v", override.Tmpfs)
+	}
+	if info, err := os.Stat(placeholder); err != nil || info.Size() != 0 {
+		t.Errorf("placeholder = %v, %v; want an empty file", info, err)
+	}
+}
+
+func TestMaskIgnored__no_ignore_file(t *testing.T) {
+	// arrange
+	projectDir := t.TempDir()
+	writeTestFile(t, filepath.Join(projectDir, ".env"), "TOKEN=1")
+	override := &docker.Override{}
+
+	// act
+	hidden, err := maskIgnored(override, projectDir, projectDir, t.TempDir())
+
+	// assert
+	if err != nil || len(hidden) != 0 || len(override.Volumes) != 0 {
+		t.Errorf("maskIgnored = %v, %v; volumes %v", hidden, err, override.Volumes)
+	}
+}
+
+func TestPrintHidden(t *testing.T) {
+	// arrange
+	hidden := []ignore.Match{{Path: ".env"}, {Path: "secrets", IsDir: true}}
+	for i := range 10 {
+		hidden = append(hidden, ignore.Match{Path: fmt.Sprintf("keys/%d.pem", i)})
+	}
+	var out bytes.Buffer
+
+	// act
+	printHidden(&out, hidden)
+
+	// assert
+	result := out.String()
+	if !strings.HasPrefix(result, "Hidden by .agentboxignore: .env, secrets/, keys/0.pem") {
+		t.Errorf("output = %q", result)
+	}
+	if !strings.HasSuffix(result, ", and 2 more\n") {
+		t.Errorf("output = %q, want the rest summarized", result)
+	}
+}
+
+func TestApplySandboxSettings(t *testing.T) {
+	// arrange
+	home := t.TempDir()
+	projectDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(home, ".m2"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(projectDir, "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	settings := config.SandboxSettings{
+		Mounts:      []string{"~/.m2:/home/box/.m2", "data:/data:ro"},
+		Env:         map[string]string{"NODE_ENV": "development"},
+		Ports:       []string{"3000"},
+		Security:    config.SecurityStrict,
+		HostGateway: true,
+	}
+	override := &docker.Override{Volumes: []string{"/state/ignore/empty:/home/box/app/.env:ro"}}
+
+	// act
+	err := applySandboxSettings(override, settings, home, projectDir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedVolumes := []string{
+		"/state/ignore/empty:/home/box/app/.env:ro",
+		filepath.Join(home, ".m2") + ":/home/box/.m2",
+		filepath.Join(projectDir, "data") + ":/data:ro",
+	}
+	if !slices.Equal(override.Volumes, expectedVolumes) {
+		t.Errorf("Volumes = %v, want %v", override.Volumes, expectedVolumes)
+	}
+	if override.Environment["NODE_ENV"] != "development" || !slices.Equal(override.Ports, []string{"3000"}) {
+		t.Errorf("Environment = %v, Ports = %v", override.Environment, override.Ports)
+	}
+	if !slices.Equal(override.ExtraHosts, []string{docker.HostGateway}) {
+		t.Errorf("ExtraHosts = %v, want the host gateway", override.ExtraHosts)
+	}
+	if !slices.Equal(override.CapDrop, []string{"ALL"}) || !slices.Equal(override.SecurityOpt, []string{"no-new-privileges:true"}) {
+		t.Errorf("CapDrop = %v, SecurityOpt = %v, want the strict profile", override.CapDrop, override.SecurityOpt)
+	}
+}
+
+func TestApplySandboxSettings__missing_mount(t *testing.T) {
+	// arrange
+	settings := config.SandboxSettings{Mounts: []string{"missing:/data"}}
+
+	// act
+	err := applySandboxSettings(&docker.Override{}, settings, t.TempDir(), t.TempDir())
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "sandbox.mounts") {
+		t.Errorf("applySandboxSettings() error = %v, want sandbox.mounts error", err)
+	}
+}
+
+func TestToolsArg(t *testing.T) {
+	// act
+	result := toolsArg(map[string]string{"python": "3.12", "node": "22"})
+	empty := toolsArg(nil)
+
+	// assert
+	if result != "node@22 python@3.12" {
+		t.Errorf("toolsArg() = %q, want \"node@22 python@3.12\"", result)
+	}
+	if empty != "" {
+		t.Errorf("toolsArg(nil) = %q, want empty", empty)
+	}
+}
+
+func TestEnabledAgents(t *testing.T) {
+	// arrange
+	manager, err := agents.NewManager(&config.Paths{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	all, allErr := enabledAgents(manager, nil)
+	some, someErr := enabledAgents(manager, []string{"codex"})
+	_, unknownErr := enabledAgents(manager, []string{"cursor"})
+
+	// assert
+	if allErr != nil || len(all) != len(agents.AllAgentNames()) {
+		t.Errorf("enabledAgents(nil) = %d agents, %v; want all", len(all), allErr)
+	}
+	if someErr != nil || len(some) != 1 || some[0].Name() != "codex" {
+		t.Errorf("enabledAgents([codex]) = %v, %v", some, someErr)
+	}
+	if unknownErr == nil || !strings.Contains(unknownErr.Error(), "claude, copilot, codex, gemini") {
+		t.Errorf("enabledAgents([cursor]) error = %v, want the known agents", unknownErr)
+	}
+}
+
+func TestDisableAgents(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	paths := &config.Paths{HomeDir: filepath.Join(tmpDir, "home"), BinDir: filepath.Join(tmpDir, "bin")}
+	if err := os.MkdirAll(paths.AgentDir("claude"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stateDir := filepath.Join(tmpDir, "state")
+	manager, err := agents.NewManager(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled, err := enabledAgents(manager, []string{"copilot", "codex", "gemini"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	override := &docker.Override{}
+
+	// act
+	err = disableAgents(override, paths, manager, enabled, stateDir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	disabledDir := filepath.Join(stateDir, "disabled")
+	expectedVolumes := []string{
+		filepath.Join(disabledDir, "claude") + ":/opt/agentbox/launchers/claude:ro",
+		filepath.Join(disabledDir, ".claude.json") + ":/home/box/.claude.json:ro",
+		filepath.Join(disabledDir, ".claude") + ":/home/box/.claude/:ro",
+	}
+	if !slices.Equal(override.Volumes, expectedVolumes) {
+		t.Errorf("Volumes = %v, want %v", override.Volumes, expectedVolumes)
+	}
+	if !slices.Equal(override.Tmpfs, []string{"/opt/agentbox/bin/claude"}) {
+		t.Errorf("Tmpfs = %v, want the claude binaries covered", override.Tmpfs)
+	}
+	launcher, err := os.ReadFile(filepath.Join(disabledDir, "claude"))
+	if err != nil || !strings.Contains(string(launcher), "claude is disabled by sandbox.agents") {
+		t.Errorf("launcher = %q, %v", launcher, err)
+	}
+}
+
+func TestSandbox_cleanup(t *testing.T) {
+	// arrange
+	projectDir := t.TempDir()
+	writeTestFile(t, filepath.Join(projectDir, "main.go"), "package main\n")
+	session, err := review.Create(filepath.Join(t.TempDir(), "review"), projectDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy, err := gitcredential.Start(shortTempDir(t), []string{"github.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overridePath := filepath.Join(t.TempDir(), "docker-compose.override.feature.yml")
+	writeTestFile(t, overridePath, "services: {}\n")
+	sb := &sandbox{review: session, credentials: proxy, overridePath: overridePath}
+
+	// act
+	sb.cleanup()
+	sb.cleanup()
+
+	// assert
+	if sb.review != nil || sb.credentials != nil {
+		t.Errorf("review = %v, credentials = %v, want both released", sb.review, sb.credentials)
+	}
+	if _, err := os.Stat(session.WorkDir()); !os.IsNotExist(err) {
+		t.Errorf("review copy still exists: %v", err)
+	}
+	if _, err := os.Stat(strings.Split(proxy.Volume(), ":")[0]); !os.IsNotExist(err) {
+		t.Errorf("credential proxy dir still exists: %v", err)
+	}
+	if _, err := os.Stat(overridePath); !os.IsNotExist(err) {
+		t.Errorf("compose override still exists: %v", err)
+	}
+}
+
+func TestOverrideKey(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name     string
+		opts     runOptions
+		expected string
+	}{
+		{"named session", runOptions{name: "review", worktree: "feature"}, "review"},
+		{"worktree", runOptions{worktree: "feature"}, "feature-" + pid},
+		{"unnamed", runOptions{}, pid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result := overrideKey(tt.opts)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("overrideKey() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
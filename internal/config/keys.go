@@ -0,0 +1,383 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// EnvPrefix starts the environment variables that override settings, e.g.
+// AGENTBOX_NETWORK_EGRESS overrides network.egress.
+const EnvPrefix = "AGENTBOX_"
+
+// Key is a documented setting that 'agentbox config' reads and writes.
+type Key struct {
+	// Name is the dotted path of the key in the config file, e.g. "network.egress".
+	Name string
+	// Description tells what the key configures.
+	Description string
+	// Default is the value used when the key is not set, "" if there is none.
+	Default string
+	// validate checks a parsed value, nil accepts any value of the key type.
+	validate func(value any) error
+	// hostAccess marks keys that give the sandbox access to the host. A project
+	// config may only set them if the project is in trust.projects.
+	hostAccess bool
+}
+
+// Env returns the environment variable that overrides the key.
+func (k Key) Env() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(k.Name, ".", "_"))
+}
+
+var keys = []Key{
+	{Name: "network.egress", Description: "Network egress policy: open or allowlist", Default: EgressOpen,
+		validate: func(v any) error { return ValidateEgress(v.(string)) }},
+	{Name: "network.allow", Description: "Extra domains reachable in allowlist mode, a leading dot matches subdomains"},
+	{Name: "checkpoints.enabled", Description: "Take git checkpoints during sessions", Default: "true"},
+	{Name: "checkpoints.interval", Description: "Interval between checkpoints, \"0\" only checkpoints the start and end",
+		Default: shortDuration(DefaultCheckpointInterval), validate: validateDuration(true)},
+	{Name: "recording.enabled", Description: "Record every run and attach session", Default: "false"},
+	{Name: "recording.redact", Description: "Regular expressions of secrets replaced in recordings",
+		validate: func(v any) error {
+			for _, pattern := range v.([]string) {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	{Name: "resources.cpus", Description: "Number of CPUs the sandbox may use, 0 for no limit",
+		validate: func(v any) error { return nonNegative(v.(float64)) }},
+	{Name: "resources.memory", Description: "Memory limit of the sandbox, e.g. 4g",
+		validate: func(v any) error {
+			if v.(string) == "" {
+				return nil
+			}
+			_, err := ParseMemory(v.(string))
+			return err
+		}},
+	{Name: "resources.pids_limit", Description: "Maximum number of processes in the sandbox, 0 for no limit",
+		validate: func(v any) error { return nonNegative(v.(int64)) }},
+	{Name: "templates.dir", Description: "Team template directory, relative to the project directory"},
+	{Name: "templates.packages", Description: "Extra system packages installed in the project image"},
+	{Name: "run.flags", Description: "Flags added before the flags of every 'agentbox run'", hostAccess: true},
+	{Name: "updates.keep_versions", Description: "Installed versions of each agent kept by 'agentbox agent update'",
+		Default: strconv.Itoa(DefaultKeepVersions), validate: func(v any) error {
+			if v.(int) < 1 {
+				return errors.New("must be at least 1")
+			}
+			return nil
+		}},
+	{Name: "http.timeout", Description: "Timeout of requests for release information",
+		Default: shortDuration(DefaultHTTPTimeout), validate: validateDuration(false)},
+	{Name: "http.download_timeout", Description: "Timeout of agent and agentbox downloads",
+		Default: shortDuration(DefaultDownloadTimeout), validate: validateDuration(false)},
+	{Name: "sandbox.agents", Description: "Agents available in the sandbox, empty for all"},
+	{Name: "sandbox.mounts", Description: "Extra bind mounts, host:container[:ro]",
+		validate: func(v any) error { return eachItem(v, ValidateMount) }, hostAccess: true},
+	{Name: "sandbox.ports", Description: "Published ports, [[ip:]host:]container",
+		validate: func(v any) error { return eachItem(v, ValidatePort) }, hostAccess: true},
+	{Name: "sandbox.host_gateway", Description: "Reach host services at host.docker.internal", Default: "false",
+		hostAccess: true},
+	{Name: "sandbox.security", Description: "Security profile: default or strict", Default: SecurityDefault,
+		validate: func(v any) error {
+			if s := v.(string); s != SecurityDefault && s != SecurityStrict {
+				return fmt.Errorf("unknown profile %q, expected %s or %s", s, SecurityDefault, SecurityStrict)
+			}
+			return nil
+		}},
+	{Name: "git.forward", Description: "Forward the SSH agent and the git user name and email", Default: "false",
+		hostAccess: true},
+	{Name: "git.credential_hosts", Description: "Hosts git in the sandbox gets credentials for from the host",
+		validate: func(v any) error {
+			return eachItem(v, func(host string) error {
+				if strings.ContainsAny(host, "/@: \t") || strings.Trim(host, ".") == "" {
+					return fmt.Errorf("invalid host %q", host)
+				}
+				return nil
+			})
+		}, hostAccess: true},
+	{Name: "trust.projects", Description: "Projects whose .agentbox.toml may set mounts, ports, git and run flags",
+		validate: func(v any) error {
+			return eachItem(v, func(dir string) error {
+				if !filepath.IsAbs(dir) && dir != "~" && !strings.HasPrefix(dir, "~/") {
+					return fmt.Errorf("invalid project %q, the path must be absolute", dir)
+				}
+				return nil
+			})
+		}},
+}
+
+// Keys returns the documented settings.
+func Keys() []Key {
+	return keys
+}
+
+// LookupKey returns the documented setting with the name.
+func LookupKey(name string) (Key, error) {
+	for _, k := range keys {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	return Key{}, fmt.Errorf("unknown config key %q, see 'agentbox config list'", name)
+}
+
+// shortDuration formats a duration without zero units, e.g. "5m" instead of "5m0s".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+func validateDuration(allowZero bool) func(any) error {
+	return func(v any) error {
+		s := v.(string)
+		if s == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, e.g. 30s or 5m", s)
+		}
+		if d < 0 || (d == 0 && !allowZero) {
+			return fmt.Errorf("duration %q must be positive", s)
+		}
+		return nil
+	}
+}
+
+func eachItem(v any, validate func(string) error) error {
+	for _, item := range v.([]string) {
+		if err := validate(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateMount checks an extra mount in the "host:container[:ro|rw]" form.
+func ValidateMount(mount string) error {
+	parts := strings.Split(mount, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+		return fmt.Errorf("invalid mount %q, expected host:container[:ro]", mount)
+	}
+	if !path.IsAbs(parts[1]) {
+		return fmt.Errorf("invalid mount %q, the container path must be absolute", mount)
+	}
+	if len(parts) == 3 && parts[2] != "ro" && parts[2] != "rw" {
+		return fmt.Errorf("invalid mount %q, the mode must be ro or rw", mount)
+	}
+	return nil
+}
+
+// envNameRe matches the names of environment variables.
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func nonNegative[T int64 | float64](n T) error {
+	if n < 0 {
+		return errors.New("must not be negative")
+	}
+	return nil
+}
+
+// field returns the Settings field of the key, found by the toml tags.
+func (k Key) field(s *Settings) reflect.Value {
+	v := reflect.ValueOf(s).Elem()
+	for _, part := range strings.Split(k.Name, ".") {
+		t := v.Type()
+		for i := range t.NumField() {
+			if strings.Split(t.Field(i).Tag.Get("toml"), ",")[0] == part {
+				v = v.Field(i)
+				break
+			}
+		}
+	}
+	return v
+}
+
+// Parse converts a command-line or environment value to the type of the key
+// and validates it. Lists are comma-separated.
+func (k Key) Parse(raw string) (any, error) {
+	var value any
+	var err error
+	switch t := k.field(&Settings{}).Type(); t.Kind() {
+	case reflect.String:
+		value = raw
+	case reflect.Bool, reflect.Pointer:
+		value, err = strconv.ParseBool(raw)
+	case reflect.Float64:
+		value, err = strconv.ParseFloat(raw, 64)
+	case reflect.Int:
+		value, err = strconv.Atoi(raw)
+	case reflect.Int64:
+		value, err = strconv.ParseInt(raw, 10, 64)
+	case reflect.Slice:
+		list := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		value = list
+	default:
+		panic(fmt.Sprintf("config key %s has unsupported type %s", k.Name, t))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q", k.Name, raw)
+	}
+	if k.validate != nil {
+		if err := k.validate(value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", k.Name, err)
+		}
+	}
+	return value, nil
+}
+
+// set stores a parsed value in the settings.
+func (k Key) set(s *Settings, value any) {
+	f := k.field(s)
+	if f.Kind() == reflect.Pointer {
+		b := value.(bool)
+		f.Set(reflect.ValueOf(&b))
+		return
+	}
+	f.Set(reflect.ValueOf(value))
+}
+
+// Get returns the value of the key in the settings, or its default if not set.
+func (k Key) Get(s *Settings) string {
+	f := k.field(s)
+	if f.IsZero() {
+		return k.Default
+	}
+	switch f.Kind() {
+	case reflect.Pointer:
+		return strconv.FormatBool(f.Elem().Bool())
+	case reflect.Slice:
+		return strings.Join(f.Interface().([]string), ",")
+	case reflect.Float64:
+		return strconv.FormatFloat(f.Float(), 'g', -1, 64)
+	default:
+		return fmt.Sprint(f.Interface())
+	}
+}
+
+// applyEnv overrides the settings with the environment variables of the keys.
+func applyEnv(s *Settings) error {
+	for _, k := range keys {
+		raw, ok := os.LookupEnv(k.Env())
+		if !ok {
+			continue
+		}
+		value, err := k.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k.Env(), err)
+		}
+		k.set(s, value)
+	}
+	return nil
+}
+
+// validate checks the values of the keys set in the settings.
+func (s *Settings) validate() error {
+	var errs []error
+	for _, k := range keys {
+		f := k.field(s)
+		if k.validate == nil || f.IsZero() {
+			continue
+		}
+		if err := k.validate(f.Interface()); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", k.Name, err))
+		}
+	}
+	for name := range s.Sandbox.Env {
+		if !envNameRe.MatchString(name) {
+			errs = append(errs, fmt.Errorf("invalid sandbox.env: %q is not a variable name", name))
+		}
+	}
+	for name, version := range s.Tools {
+		if name == "" || version == "" || strings.ContainsAny(name+version, " \t\"'$`") {
+			errs = append(errs, fmt.Errorf("invalid tools: %q = %q", name, version))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// IsSet reports whether the config file sets the key.
+func IsSet(path string, k Key) (bool, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	}
+	md, err := toml.DecodeFile(path, &map[string]any{})
+	if err != nil {
+		return false, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return md.IsDefined(strings.Split(k.Name, ".")...), nil
+}
+
+// SetValue writes the key to the config file, creating it if needed. The
+// file is rewritten from its parsed content, so its comments are not kept.
+func SetValue(path string, k Key, value any) error {
+	return updateFile(path, k, func(table map[string]any, name string) {
+		table[name] = value
+	})
+}
+
+// UnsetValue removes the key from the config file.
+func UnsetValue(path string, k Key) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	return updateFile(path, k, func(table map[string]any, name string) {
+		delete(table, name)
+	})
+}
+
+func updateFile(path string, k Key, update func(table map[string]any, name string)) error {
+	content := map[string]any{}
+	if _, err := os.Stat(path); err == nil {
+		if _, err := toml.DecodeFile(path, &content); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+
+	tableName, name, _ := strings.Cut(k.Name, ".")
+	table, ok := content[tableName].(map[string]any)
+	if !ok {
+		table = map[string]any{}
+		content[tableName] = table
+	}
+	update(table, name)
+	if len(table) == 0 {
+		delete(content, tableName)
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(content); err != nil {
+		return fmt.Errorf("encode %s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create dir %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
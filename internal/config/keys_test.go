@@ -0,0 +1,218 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeys__all_resolve_to_settings_fields(t *testing.T) {
+	for _, k := range Keys() {
+		t.Run(k.Name, func(t *testing.T) {
+			// act
+			_, err := k.Parse(k.Default)
+
+			// assert
+			if k.Default != "" && err != nil {
+				t.Errorf("default %q does not parse: %v", k.Default, err)
+			}
+			if k.field(&Settings{}).Kind() == reflect.Struct {
+				t.Errorf("key %s does not resolve to a value field", k.Name)
+			}
+		})
+	}
+}
+
+func TestKeyEnv(t *testing.T) {
+	// arrange
+	k, err := LookupKey("resources.pids_limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	env := k.Env()
+
+	// assert
+	if env != "AGENTBOX_RESOURCES_PIDS_LIMIT" {
+		t.Errorf("Env() = %q, want AGENTBOX_RESOURCES_PIDS_LIMIT", env)
+	}
+}
+
+func TestKeyParse(t *testing.T) {
+	tests := []struct {
+		key      string
+		raw      string
+		expected any
+		wantErr  bool
+	}{
+		{"network.egress", "allowlist", "allowlist", false},
+		{"network.egress", "closed", nil, true},
+		{"network.allow", "a.com, .b.com,", []string{"a.com", ".b.com"}, false},
+		{"checkpoints.enabled", "false", false, false},
+		{"checkpoints.enabled", "maybe", nil, true},
+		{"checkpoints.interval", "0", "0", false},
+		{"resources.cpus", "1.5", 1.5, false},
+		{"resources.cpus", "-1", nil, true},
+		{"resources.memory", "4x", nil, true},
+		{"resources.pids_limit", "256", int64(256), false},
+		{"recording.redact", "sk-[a-z]+,(", nil, true},
+		{"updates.keep_versions", "0", nil, true},
+		{"updates.keep_versions", "3", 3, false},
+		{"http.timeout", "0s", nil, true},
+		{"sandbox.mounts", "~/.m2:/home/box/.m2,data:/data:ro", []string{"~/.m2:/home/box/.m2", "data:/data:ro"}, false},
+		{"sandbox.mounts", "/data", nil, true},
+		{"sandbox.ports", "3000,8080:80", []string{"3000", "8080:80"}, false},
+		{"sandbox.ports", "3000:http", nil, true},
+		{"sandbox.security", "strict", "strict", false},
+		{"sandbox.security", "off", nil, true},
+		{"sandbox.host_gateway", "true", true, false},
+		{"git.credential_hosts", "github.com,.example.com", []string{"github.com", ".example.com"}, false},
+		{"git.credential_hosts", "https://github.com", nil, true},
+		{"git.credential_hosts", "git@github.com", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.raw, func(t *testing.T) {
+			// arrange
+			k, err := LookupKey(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// act
+			value, err := k.Parse(tt.raw)
+
+			// assert
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(value, tt.expected) {
+				t.Errorf("Parse() = %#v, want %#v", value, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLookupKey__unknown(t *testing.T) {
+	// act
+	_, err := LookupKey("network.proxy")
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "agentbox config list") {
+		t.Errorf("LookupKey() error = %v, want unknown key error", err)
+	}
+}
+
+func TestLoadSettings__env_overrides_files(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+	writeFile(t, paths.ConfigFile, `
+[network]
+egress = "allowlist"
+
+[updates]
+keep_versions = 2
+`)
+	t.Setenv("AGENTBOX_NETWORK_EGRESS", "open")
+	t.Setenv("AGENTBOX_RUN_FLAGS", "--safe,--record")
+
+	// act
+	settings, err := LoadSettings(paths, "")
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.Network.Egress != "open" {
+		t.Errorf("Network.Egress = %q, want open", settings.Network.Egress)
+	}
+	if !reflect.DeepEqual(settings.Run.Flags, []string{"--safe", "--record"}) {
+		t.Errorf("Run.Flags = %v, want [--safe --record]", settings.Run.Flags)
+	}
+	if settings.Updates.KeptVersions() != 2 {
+		t.Errorf("KeptVersions() = %d, want 2", settings.Updates.KeptVersions())
+	}
+}
+
+func TestLoadSettings__invalid_value(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+	writeFile(t, paths.ConfigFile, `
+[http]
+timeout = "soon"
+`)
+
+	// act
+	_, err := LoadSettings(paths, "")
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "invalid http.timeout") {
+		t.Errorf("LoadSettings() error = %v, want invalid http.timeout", err)
+	}
+}
+
+func TestSetValue__keeps_other_settings(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, path, `
+[agents.claude]
+args = ["--verbose"]
+
+[network]
+allow = ["example.com"]
+`)
+	egress, _ := LookupKey("network.egress")
+	allow, _ := LookupKey("network.allow")
+
+	// act
+	setErr := SetValue(path, egress, "allowlist")
+	unsetErr := UnsetValue(path, allow)
+
+	// assert
+	if setErr != nil || unsetErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", setErr, unsetErr)
+	}
+	settings := &Settings{}
+	if _, err := decodeFile(path, settings); err != nil {
+		t.Fatal(err)
+	}
+	if settings.Network.Egress != "allowlist" || settings.Network.Allow != nil {
+		t.Errorf("Network = %+v, want egress allowlist without allow", settings.Network)
+	}
+	if !reflect.DeepEqual(settings.Agents["claude"].Args, []string{"--verbose"}) {
+		t.Errorf("Agents = %v, want claude args kept", settings.Agents)
+	}
+	set, err := IsSet(path, allow)
+	if err != nil || set {
+		t.Errorf("IsSet(network.allow) = %v, %v, want false", set, err)
+	}
+}
+
+func TestUnsetValue__removes_empty_table(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "config.toml")
+	k, _ := LookupKey("updates.keep_versions")
+	if err := SetValue(path, k, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	err := UnsetValue(path, k)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(data)) != "" {
+		t.Errorf("file = %q, want empty", data)
+	}
+}
@@ -1,15 +1,23 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
 )
 
 type Paths struct {
-	HomeDir     string
-	AgentboxDir string
-	BinDir      string
+	HomeDir      string
+	AgentboxDir  string
+	BinDir       string
+	LaunchersDir string
+	ProjectsDir  string
+	WorktreesDir string
+	SessionsDir  string
+	TemplatesDir string
+	ConfigFile   string
 }
 
 func NewPaths() (*Paths, error) {
@@ -21,9 +29,15 @@ func NewPaths() (*Paths, error) {
 	agentboxDir := filepath.Join(homeDir, ".agentbox")
 
 	return &Paths{
-		HomeDir:     homeDir,
-		AgentboxDir: agentboxDir,
-		BinDir:      filepath.Join(agentboxDir, "bin"),
+		HomeDir:      homeDir,
+		AgentboxDir:  agentboxDir,
+		BinDir:       filepath.Join(agentboxDir, "bin"),
+		LaunchersDir: filepath.Join(agentboxDir, "launchers"),
+		ProjectsDir:  filepath.Join(agentboxDir, "projects"),
+		WorktreesDir: filepath.Join(agentboxDir, "worktrees"),
+		SessionsDir:  filepath.Join(agentboxDir, "sessions"),
+		TemplatesDir: filepath.Join(agentboxDir, "templates"),
+		ConfigFile:   filepath.Join(agentboxDir, "config.toml"),
 	}, nil
 }
 
@@ -39,10 +53,43 @@ func (p *Paths) AgentCurrentFile(agent string) string {
 	return filepath.Join(p.BinDir, agent, "current")
 }
 
+// RuntimeDir returns the directory, private to the user, with the sockets of
+// running sessions: agentbox in $XDG_RUNTIME_DIR, or ~/.agentbox/run.
+func (p *Paths) RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "agentbox")
+	}
+	return filepath.Join(p.AgentboxDir, "run")
+}
+
+// ProjectSlug returns a stable directory name for a project: the base name
+// followed by a short hash of the absolute path, so that projects with the
+// same name do not collide.
+func ProjectSlug(projectDir string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(projectDir)))
+	return filepath.Base(projectDir) + "-" + hex.EncodeToString(sum[:4])
+}
+
+// ProjectStateDir returns the directory with generated files for a project.
+func (p *Paths) ProjectStateDir(projectDir string) string {
+	return filepath.Join(p.ProjectsDir, ProjectSlug(projectDir))
+}
+
+// ProjectWorktreesDir returns the directory with managed worktrees of a project.
+func (p *Paths) ProjectWorktreesDir(projectDir string) string {
+	return filepath.Join(p.WorktreesDir, ProjectSlug(projectDir))
+}
+
+// ProjectSessionsDir returns the directory with session recordings of a project.
+func (p *Paths) ProjectSessionsDir(projectDir string) string {
+	return filepath.Join(p.SessionsDir, ProjectSlug(projectDir))
+}
+
 func (p *Paths) EnsureDirs() error {
 	dirs := []string{
 		p.AgentboxDir,
 		p.BinDir,
+		p.LaunchersDir,
 	}
 
 	for _, dir := range dirs {
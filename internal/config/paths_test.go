@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -34,6 +35,16 @@ func TestNewPaths(t *testing.T) {
 	if paths.BinDir != expectedBinDir {
 		t.Errorf("BinDir = %s, want %s", paths.BinDir, expectedBinDir)
 	}
+
+	expectedLaunchersDir := filepath.Join(expectedAgentboxDir, "launchers")
+	if paths.LaunchersDir != expectedLaunchersDir {
+		t.Errorf("LaunchersDir = %s, want %s", paths.LaunchersDir, expectedLaunchersDir)
+	}
+
+	expectedConfigFile := filepath.Join(expectedAgentboxDir, "config.toml")
+	if paths.ConfigFile != expectedConfigFile {
+		t.Errorf("ConfigFile = %s, want %s", paths.ConfigFile, expectedConfigFile)
+	}
 }
 
 func TestPaths_AgentDir(t *testing.T) {
@@ -88,8 +99,9 @@ func TestPaths_EnsureDirs(t *testing.T) {
 	// arrange
 	tmpDir := t.TempDir()
 	paths := &Paths{
-		AgentboxDir: filepath.Join(tmpDir, ".agentbox"),
-		BinDir:      filepath.Join(tmpDir, ".agentbox", "bin"),
+		AgentboxDir:  filepath.Join(tmpDir, ".agentbox"),
+		BinDir:       filepath.Join(tmpDir, ".agentbox", "bin"),
+		LaunchersDir: filepath.Join(tmpDir, ".agentbox", "launchers"),
 	}
 
 	// act
@@ -100,7 +112,7 @@ func TestPaths_EnsureDirs(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	expectedDirs := []string{paths.AgentboxDir, paths.BinDir}
+	expectedDirs := []string{paths.AgentboxDir, paths.BinDir, paths.LaunchersDir}
 	for _, dir := range expectedDirs {
 		info, err := os.Stat(dir)
 		if err != nil {
@@ -117,8 +129,9 @@ func TestPaths_EnsureDirs__already_exists(t *testing.T) {
 	// arrange
 	tmpDir := t.TempDir()
 	paths := &Paths{
-		AgentboxDir: filepath.Join(tmpDir, ".agentbox"),
-		BinDir:      filepath.Join(tmpDir, ".agentbox", "bin"),
+		AgentboxDir:  filepath.Join(tmpDir, ".agentbox"),
+		BinDir:       filepath.Join(tmpDir, ".agentbox", "bin"),
+		LaunchersDir: filepath.Join(tmpDir, ".agentbox", "launchers"),
 	}
 
 	if err := os.MkdirAll(paths.BinDir, 0o755); err != nil {
@@ -133,3 +146,47 @@ func TestPaths_EnsureDirs__already_exists(t *testing.T) {
 		t.Fatalf("unexpected error when dirs already exist: %v", err)
 	}
 }
+
+func TestProjectSlug(t *testing.T) {
+	// act
+	slug := ProjectSlug("/home/user/projects/my-app")
+	other := ProjectSlug("/home/user/work/my-app")
+
+	// assert
+	if !strings.HasPrefix(slug, "my-app-") || len(slug) != len("my-app-")+8 {
+		t.Errorf("ProjectSlug = %s, want my-app-<8 hex chars>", slug)
+	}
+	if slug == other {
+		t.Errorf("projects with the same name should have different slugs, got %s", slug)
+	}
+	if ProjectSlug("/home/user/projects/my-app/") != slug {
+		t.Error("ProjectSlug should ignore trailing slash")
+	}
+}
+
+func TestPaths_ProjectStateDir(t *testing.T) {
+	// arrange
+	paths := &Paths{
+		ProjectsDir:  "/home/user/.agentbox/projects",
+		WorktreesDir: "/home/user/.agentbox/worktrees",
+		SessionsDir:  "/home/user/.agentbox/sessions",
+	}
+	projectDir := "/home/user/my-app"
+
+	// act
+	stateDir := paths.ProjectStateDir(projectDir)
+	worktreesDir := paths.ProjectWorktreesDir(projectDir)
+	sessionsDir := paths.ProjectSessionsDir(projectDir)
+
+	// assert
+	slug := ProjectSlug(projectDir)
+	if stateDir != "/home/user/.agentbox/projects/"+slug {
+		t.Errorf("ProjectStateDir = %s", stateDir)
+	}
+	if worktreesDir != "/home/user/.agentbox/worktrees/"+slug {
+		t.Errorf("ProjectWorktreesDir = %s", worktreesDir)
+	}
+	if sessionsDir != "/home/user/.agentbox/sessions/"+slug {
+		t.Errorf("ProjectSessionsDir = %s", sessionsDir)
+	}
+}
@@ -0,0 +1,363 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProjectConfigFile is the project-level config file name.
+const ProjectConfigFile = ".agentbox.toml"
+
+// Settings is the user configuration. It is read from ~/.agentbox/config.toml
+// and then from the project's .agentbox.toml, so project values take precedence.
+type Settings struct {
+	Agents      map[string]AgentSettings `toml:"agents"`
+	Network     NetworkSettings          `toml:"network"`
+	Checkpoints CheckpointSettings       `toml:"checkpoints"`
+	Recording   RecordingSettings        `toml:"recording"`
+	Resources   ResourceSettings         `toml:"resources"`
+	Templates   TemplateSettings         `toml:"templates"`
+	Run         RunSettings              `toml:"run"`
+	Updates     UpdateSettings           `toml:"updates"`
+	HTTP        HTTPSettings             `toml:"http"`
+	Sandbox     SandboxSettings          `toml:"sandbox"`
+	Git         GitSettings              `toml:"git"`
+	Trust       TrustSettings            `toml:"trust"`
+	// Tools maps mise tools installed in the project image to their versions,
+	// e.g. {node = "22"}.
+	Tools map[string]string `toml:"tools"`
+}
+
+// AgentSettings configures how the launcher starts an agent.
+type AgentSettings struct {
+	// Args replaces the agent's default arguments. Nil means "not configured",
+	// an empty list starts the agent without any default arguments.
+	Args []string `toml:"args"`
+}
+
+// NetworkSettings configures network access of the sandbox.
+type NetworkSettings struct {
+	// Egress is the egress policy: "open" (default) or "allowlist".
+	Egress string `toml:"egress"`
+	// Allow lists extra domains reachable in allowlist mode, in addition to the
+	// agents' own endpoints. A leading dot matches subdomains.
+	Allow []string `toml:"allow"`
+}
+
+// DefaultCheckpointInterval is used when the checkpoint interval is not configured.
+const DefaultCheckpointInterval = 5 * time.Minute
+
+// CheckpointSettings configures git checkpoints taken during sessions.
+type CheckpointSettings struct {
+	// Enabled turns checkpoints on or off. Nil means enabled.
+	Enabled *bool `toml:"enabled"`
+	// Interval between checkpoints while the container runs, e.g. "10m".
+	// "0" keeps only the checkpoints at the start and the end of a session.
+	Interval string `toml:"interval"`
+}
+
+// IsEnabled reports whether checkpoints are enabled.
+func (c CheckpointSettings) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// IntervalDuration returns the parsed interval, or the default if not configured.
+func (c CheckpointSettings) IntervalDuration() (time.Duration, error) {
+	if c.Interval == "" {
+		return DefaultCheckpointInterval, nil
+	}
+	interval, err := time.ParseDuration(c.Interval)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid checkpoints.interval: %s", c.Interval)
+	}
+	return interval, nil
+}
+
+// RecordingSettings configures terminal session recording.
+type RecordingSettings struct {
+	// Enabled records every run and attach session, as if --record was passed.
+	Enabled bool `toml:"enabled"`
+	// Redact lists regular expressions of secrets replaced in recordings,
+	// in addition to the built-in patterns for well-known API keys.
+	Redact []string `toml:"redact"`
+}
+
+// ResourceSettings limits the resources of the sandbox container. Zero values mean no limit.
+type ResourceSettings struct {
+	// CPUs is the number of CPUs the container may use, e.g. 2 or 1.5.
+	CPUs float64 `toml:"cpus"`
+	// Memory is the memory limit in Docker notation, e.g. "4g".
+	Memory string `toml:"memory"`
+	// PidsLimit is the maximum number of processes in the container.
+	PidsLimit int64 `toml:"pids_limit"`
+}
+
+// TemplateSettings configures the templates of the files written by init.
+type TemplateSettings struct {
+	// Dir is a team template directory, used for files missing in ~/.agentbox/templates.
+	// A relative path is relative to the project directory.
+	Dir string `toml:"dir"`
+	// Packages are extra system packages installed in the project image,
+	// available to templates as .Packages.
+	Packages []string `toml:"packages"`
+}
+
+// RunSettings configures 'agentbox run'.
+type RunSettings struct {
+	// Flags are added before the command-line flags, which take precedence, e.g. ["--safe"].
+	Flags []string `toml:"flags"`
+}
+
+// DefaultKeepVersions is used when the number of kept agent versions is not configured.
+const DefaultKeepVersions = 5
+
+// UpdateSettings configures agent updates.
+type UpdateSettings struct {
+	// KeepVersions is the number of installed versions of each agent kept after an update.
+	KeepVersions int `toml:"keep_versions"`
+}
+
+// KeptVersions returns the number of agent versions to keep, or the default if not configured.
+func (u UpdateSettings) KeptVersions() int {
+	if u.KeepVersions == 0 {
+		return DefaultKeepVersions
+	}
+	return u.KeepVersions
+}
+
+// Timeouts used when the HTTP timeouts are not configured.
+const (
+	DefaultHTTPTimeout     = 30 * time.Second
+	DefaultDownloadTimeout = 5 * time.Minute
+)
+
+// HTTPSettings configures requests to release servers.
+type HTTPSettings struct {
+	// Timeout of requests for release information, e.g. "30s".
+	Timeout string `toml:"timeout"`
+	// DownloadTimeout of agent and agentbox downloads, e.g. "10m".
+	DownloadTimeout string `toml:"download_timeout"`
+}
+
+// TimeoutDuration returns the request timeout, or the default if not configured.
+func (h HTTPSettings) TimeoutDuration() time.Duration {
+	return durationOr(h.Timeout, DefaultHTTPTimeout)
+}
+
+// DownloadTimeoutDuration returns the download timeout, or the default if not configured.
+func (h HTTPSettings) DownloadTimeoutDuration() time.Duration {
+	return durationOr(h.DownloadTimeout, DefaultDownloadTimeout)
+}
+
+// durationOr parses a duration checked by LoadSettings, returning def if it is not set.
+func durationOr(s string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// Egress policies of the sandbox.
+const (
+	// EgressOpen lets the sandbox reach any host.
+	EgressOpen = "open"
+	// EgressAllowlist only lets the sandbox reach the agents' endpoints and network.allow
+	// through a filtering proxy.
+	EgressAllowlist = "allowlist"
+)
+
+// Security profiles of the sandbox container.
+const (
+	// SecurityDefault keeps the capabilities the container runtime grants by default.
+	SecurityDefault = "default"
+	// SecurityStrict drops all capabilities and forbids gaining privileges, e.g. with sudo.
+	SecurityStrict = "strict"
+)
+
+// SandboxSettings configures the sandbox container. agentbox compiles them into
+// the generated compose override, next to docker-compose.agentbox.local.yml.
+type SandboxSettings struct {
+	// Agents lists the agents available in the sandbox, empty means all of them.
+	Agents []string `toml:"agents"`
+	// Mounts are extra bind mounts in the "host:container[:ro]" form. A relative
+	// host path is relative to the project directory.
+	Mounts []string `toml:"mounts"`
+	// Env sets environment variables in the sandbox.
+	Env map[string]string `toml:"env"`
+	// Ports are published ports in compose syntax, e.g. "127.0.0.1:3000:3000".
+	Ports []string `toml:"ports"`
+	// HostGateway makes host services reachable at host.docker.internal.
+	HostGateway bool `toml:"host_gateway"`
+	// Security is the security profile: "default" or "strict".
+	Security string `toml:"security"`
+}
+
+// GitSettings configures git access to remotes from the sandbox.
+type GitSettings struct {
+	// Forward mounts the host SSH agent into the sandbox and copies user.name
+	// and user.email from the host git config.
+	Forward bool `toml:"forward"`
+	// CredentialHosts lists the hosts for which git in the sandbox gets
+	// credentials from git on the host. A leading dot matches subdomains.
+	CredentialHosts []string `toml:"credential_hosts"`
+}
+
+// TrustSettings lists the projects whose config may give the sandbox access
+// to the host. It is only read from the global config.
+type TrustSettings struct {
+	// Projects are project directories, "~" is the home directory.
+	Projects []string `toml:"projects"`
+}
+
+// trusts reports whether projectDir is listed in the trusted projects.
+func (t TrustSettings) trusts(paths *Paths, projectDir string) bool {
+	projectDir = canonicalDir(projectDir)
+	for _, dir := range t.Projects {
+		if dir == "~" || strings.HasPrefix(dir, "~/") {
+			dir = filepath.Join(paths.HomeDir, dir[1:])
+		}
+		if canonicalDir(dir) == projectDir {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalDir returns dir cleaned and with symlinks resolved where possible.
+func canonicalDir(dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		return resolved
+	}
+	return filepath.Clean(dir)
+}
+
+// TemplateDirs returns the template directories in priority order: the user
+// templates, then the team templates if configured.
+func (s *Settings) TemplateDirs(paths *Paths, projectDir string) []string {
+	dirs := []string{paths.TemplatesDir}
+	dir := s.Templates.Dir
+	switch {
+	case dir == "":
+		return dirs
+	case dir == "~" || strings.HasPrefix(dir, "~/"):
+		dir = filepath.Join(paths.HomeDir, dir[1:])
+	case !filepath.IsAbs(dir):
+		dir = filepath.Join(projectDir, dir)
+	}
+	return append(dirs, dir)
+}
+
+// LoadSettings reads the global config and, if projectDir is not empty, the project config,
+// then applies the environment overrides of the documented keys (see Keys) and
+// validates them. Missing files are not an error. Allowed domains, redact
+// patterns, mounts and ports from all files are combined. A project config may
+// only set the keys that give access to the host, or relax the global egress
+// policy and security profile, if the project is trusted.
+func LoadSettings(paths *Paths, projectDir string) (*Settings, error) {
+	settings := &Settings{}
+
+	files := []string{paths.ConfigFile}
+	if projectDir != "" {
+		files = append(files, filepath.Join(projectDir, ProjectConfigFile))
+	}
+
+	var allow, redact, mounts, ports []string
+	for _, path := range files {
+		settings.Network.Allow = nil
+		settings.Recording.Redact = nil
+		settings.Sandbox.Mounts = nil
+		settings.Sandbox.Ports = nil
+		global := *settings
+		md, err := decodeFile(path, settings)
+		if err != nil {
+			return nil, err
+		}
+		if path != paths.ConfigFile {
+			trusted := settings.Trust.trusts(paths, projectDir)
+			if err := checkProjectKeys(md, path, paths, &global, settings, trusted); err != nil {
+				return nil, err
+			}
+		}
+		allow = append(allow, settings.Network.Allow...)
+		redact = append(redact, settings.Recording.Redact...)
+		mounts = append(mounts, settings.Sandbox.Mounts...)
+		ports = append(ports, settings.Sandbox.Ports...)
+	}
+	settings.Network.Allow = allow
+	settings.Recording.Redact = redact
+	settings.Sandbox.Mounts = mounts
+	settings.Sandbox.Ports = ports
+
+	if err := applyEnv(settings); err != nil {
+		return nil, err
+	}
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// decodeFile merges the file into settings and returns its metadata. Keys set
+// in the file replace the values read earlier and absent keys are kept, field by
+// field; maps such as agents, tools and sandbox.env are merged key by key.
+func decodeFile(path string, settings *Settings) (toml.MetaData, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return toml.MetaData{}, nil
+	}
+
+	md, err := toml.DecodeFile(path, settings)
+	if err != nil {
+		return md, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return md, nil
+}
+
+// checkProjectKeys returns an error if the project config at path sets
+// trust.projects, or, unless the project is trusted, keys that give access to
+// the host or values that are less strict than the global ones.
+func checkProjectKeys(md toml.MetaData, path string, paths *Paths, global, project *Settings, trusted bool) error {
+	if md.IsDefined("trust", "projects") {
+		return fmt.Errorf("%s: trust.projects can only be set in %s", path, paths.ConfigFile)
+	}
+	if trusted {
+		return nil
+	}
+	var weakened []string
+	if global.Network.Egress == EgressAllowlist && project.Network.Egress != EgressAllowlist {
+		weakened = append(weakened, "network.egress")
+	}
+	if global.Sandbox.Security == SecurityStrict && project.Sandbox.Security != SecurityStrict {
+		weakened = append(weakened, "sandbox.security")
+	}
+	if len(weakened) > 0 {
+		return fmt.Errorf("%s relaxes %s of %s; if you trust the project, add %s to trust.projects in %s",
+			path, strings.Join(weakened, ", "), paths.ConfigFile, filepath.Dir(path), paths.ConfigFile)
+	}
+
+	var names []string
+	for _, k := range keys {
+		if k.hostAccess && md.IsDefined(strings.Split(k.Name, ".")...) {
+			names = append(names, k.Name)
+		}
+	}
+	if len(names) > 0 {
+		return fmt.Errorf("%s sets %s, which give the sandbox access to the host; "+
+			"if you trust the project, add %s to trust.projects in %s",
+			path, strings.Join(names, ", "), filepath.Dir(path), paths.ConfigFile)
+	}
+	return nil
+}
+
+// AgentArgs returns the configured arguments for the agent, or defaults if none are configured.
+func (s *Settings) AgentArgs(name string, defaults []string) []string {
+	if agent, ok := s.Agents[name]; ok && agent.Args != nil {
+		return agent.Args
+	}
+	return defaults
+}
@@ -0,0 +1,478 @@
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadSettings__no_files(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+
+	// act
+	settings, err := LoadSettings(paths, tmpDir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(settings.Agents) != 0 {
+		t.Errorf("Agents = %v, want empty", settings.Agents)
+	}
+}
+
+func TestLoadSettings__project_overrides_global(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "project")
+	paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+
+	writeFile(t, paths.ConfigFile, `
+[agents.claude]
+args = ["--dangerously-skip-permissions", "--verbose"]
+
+[agents.codex]
+args = ["--full-auto"]
+`)
+	writeFile(t, filepath.Join(projectDir, ProjectConfigFile), `
+[agents.claude]
+args = ["--model", "opus"]
+`)
+
+	// act
+	settings, err := LoadSettings(paths, projectDir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	claudeArgs := settings.AgentArgs("claude", nil)
+	if !slices.Equal(claudeArgs, []string{"--model", "opus"}) {
+		t.Errorf("claude args = %v, want project args", claudeArgs)
+	}
+
+	codexArgs := settings.AgentArgs("codex", nil)
+	if !slices.Equal(codexArgs, []string{"--full-auto"}) {
+		t.Errorf("codex args = %v, want global args", codexArgs)
+	}
+}
+
+func TestLoadSettings__network_allow_combined(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "project")
+	paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+
+	writeFile(t, paths.ConfigFile, `
+[network]
+egress = "allowlist"
+allow = [".npmjs.org"]
+`)
+	writeFile(t, filepath.Join(projectDir, ProjectConfigFile), `
+[network]
+allow = ["pypi.org", "files.pythonhosted.org"]
+`)
+
+	// act
+	settings, err := LoadSettings(paths, projectDir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.Network.Egress != "allowlist" {
+		t.Errorf("Network.Egress = %q, want allowlist", settings.Network.Egress)
+	}
+	expected := []string{".npmjs.org", "pypi.org", "files.pythonhosted.org"}
+	if !slices.Equal(settings.Network.Allow, expected) {
+		t.Errorf("Network.Allow = %v, want %v", settings.Network.Allow, expected)
+	}
+}
+
+func TestLoadSettings__recording(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "project")
+	paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+
+	writeFile(t, paths.ConfigFile, `
+[recording]
+enabled = true
+redact = ['corp_[a-z0-9]{32}']
+`)
+	writeFile(t, filepath.Join(projectDir, ProjectConfigFile), `
+[recording]
+redact = ['DB_PASSWORD=\S+']
+`)
+
+	// act
+	settings, err := LoadSettings(paths, projectDir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !settings.Recording.Enabled {
+		t.Error("Recording.Enabled = false, want true")
+	}
+	expected := []string{`corp_[a-z0-9]{32}`, `DB_PASSWORD=\S+`}
+	if !slices.Equal(settings.Recording.Redact, expected) {
+		t.Errorf("Recording.Redact = %v, want %v", settings.Recording.Redact, expected)
+	}
+}
+
+func TestLoadSettings__resources(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "project")
+	paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+
+	writeFile(t, paths.ConfigFile, `
+[resources]
+cpus = 4
+memory = "8g"
+`)
+	writeFile(t, filepath.Join(projectDir, ProjectConfigFile), `
+[resources]
+memory = "2g"
+pids_limit = 256
+`)
+
+	// act
+	settings, err := LoadSettings(paths, projectDir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := ResourceSettings{CPUs: 4, Memory: "2g", PidsLimit: 256}
+	if settings.Resources != expected {
+		t.Errorf("Resources = %+v, want %+v", settings.Resources, expected)
+	}
+}
+
+func TestLoadSettings__sandbox(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "project")
+	paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+
+	writeFile(t, paths.ConfigFile, `
+[sandbox]
+mounts = ["~/.m2:/home/box/.m2"]
+
+[sandbox.env]
+EDITOR = "vim"
+
+[trust]
+projects = ["`+projectDir+`"]
+`)
+	writeFile(t, filepath.Join(projectDir, ProjectConfigFile), `
+[sandbox]
+agents = ["claude"]
+mounts = ["../shared:/home/box/shared:ro"]
+ports = ["127.0.0.1:3000:3000"]
+security = "strict"
+
+[sandbox.env]
+NODE_ENV = "development"
+
+[tools]
+node = "22"
+`)
+
+	// act
+	settings, err := LoadSettings(paths, projectDir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sandbox := settings.Sandbox
+	if !slices.Equal(sandbox.Mounts, []string{"~/.m2:/home/box/.m2", "../shared:/home/box/shared:ro"}) {
+		t.Errorf("Mounts = %v, want global and project mounts", sandbox.Mounts)
+	}
+	if !maps.Equal(sandbox.Env, map[string]string{"EDITOR": "vim", "NODE_ENV": "development"}) {
+		t.Errorf("Env = %v, want global and project variables", sandbox.Env)
+	}
+	if !slices.Equal(sandbox.Agents, []string{"claude"}) || !slices.Equal(sandbox.Ports, []string{"127.0.0.1:3000:3000"}) {
+		t.Errorf("Sandbox = %+v, want project agents and ports", sandbox)
+	}
+	if sandbox.Security != SecurityStrict || settings.Tools["node"] != "22" {
+		t.Errorf("Security = %q, Tools = %v, want strict and node 22", sandbox.Security, settings.Tools)
+	}
+}
+
+func TestLoadSettings__invalid_sandbox(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"relative container path", "[sandbox]\nmounts = [\"data:data\"]\n"},
+		{"mount mode", "[sandbox]\nmounts = [\"data:/data:rx\"]\n"},
+		{"port", "[sandbox]\nports = [\"localhost:3000\"]\n"},
+		{"security profile", "[sandbox]\nsecurity = \"paranoid\"\n"},
+		{"env name", "[sandbox.env]\n\"NODE-ENV\" = \"dev\"\n"},
+		{"tool version", "[tools]\nnode = \"22; rm -rf /\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			tmpDir := t.TempDir()
+			paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+			writeFile(t, paths.ConfigFile, tt.content)
+
+			// act
+			_, err := LoadSettings(paths, "")
+
+			// assert
+			if err == nil {
+				t.Error("LoadSettings() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestLoadSettings__untrusted_project(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"mounts", "[sandbox]\nmounts = [\"/:/host\"]\n"},
+		{"git forward", "[git]\nforward = true\n"},
+		{"credential hosts", "[git]\ncredential_hosts = [\"github.com\"]\n"},
+		{"run flags", "[run]\nflags = [\"--forward-git\"]\n"},
+		{"ports", "[sandbox]\nports = [\"3000\"]\n"},
+		{"host gateway", "[sandbox]\nhost_gateway = true\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			tmpDir := t.TempDir()
+			projectDir := filepath.Join(tmpDir, "project")
+			paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+			writeFile(t, filepath.Join(projectDir, ProjectConfigFile), tt.content)
+
+			// act
+			_, err := LoadSettings(paths, projectDir)
+
+			// assert
+			if err == nil || !strings.Contains(err.Error(), "trust.projects") {
+				t.Errorf("LoadSettings() error = %v, want it to ask for trust", err)
+			}
+		})
+	}
+}
+
+func TestLoadSettings__project_relaxes_global(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  string
+		project string
+		wantErr bool
+	}{
+		{"open egress", "[network]\negress = \"allowlist\"\n", "[network]\negress = \"open\"\n", true},
+		{"default security", "[sandbox]\nsecurity = \"strict\"\n", "[sandbox]\nsecurity = \"default\"\n", true},
+		{"same egress", "[network]\negress = \"allowlist\"\n", "[network]\negress = \"allowlist\"\n", false},
+		{"stricter security", "", "[sandbox]\nsecurity = \"strict\"\n", false},
+		{"trusted", "[network]\negress = \"allowlist\"\n[trust]\nprojects = [\"~/project\"]\n",
+			"[network]\negress = \"open\"\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			tmpDir := t.TempDir()
+			projectDir := filepath.Join(tmpDir, "project")
+			paths := &Paths{HomeDir: tmpDir, ConfigFile: filepath.Join(tmpDir, "config.toml")}
+			writeFile(t, paths.ConfigFile, tt.global)
+			writeFile(t, filepath.Join(projectDir, ProjectConfigFile), tt.project)
+
+			// act
+			_, err := LoadSettings(paths, projectDir)
+
+			// assert
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadSettings__trusted_project(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "project")
+	paths := &Paths{HomeDir: tmpDir, ConfigFile: filepath.Join(tmpDir, "config.toml")}
+	writeFile(t, paths.ConfigFile, "[trust]\nprojects = [\"~/project\"]\n")
+	writeFile(t, filepath.Join(projectDir, ProjectConfigFile), `
+[git]
+forward = true
+credential_hosts = ["github.com"]
+
+[run]
+flags = ["--safe"]
+`)
+
+	// act
+	settings, err := LoadSettings(paths, projectDir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !settings.Git.Forward || !slices.Equal(settings.Git.CredentialHosts, []string{"github.com"}) {
+		t.Errorf("Git = %+v, want the project settings", settings.Git)
+	}
+	if !slices.Equal(settings.Run.Flags, []string{"--safe"}) {
+		t.Errorf("Run.Flags = %v, want [--safe]", settings.Run.Flags)
+	}
+}
+
+func TestLoadSettings__project_sets_trust(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "project")
+	paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+	writeFile(t, paths.ConfigFile, "[trust]\nprojects = [\""+projectDir+"\"]\n")
+	writeFile(t, filepath.Join(projectDir, ProjectConfigFile), "[trust]\nprojects = [\"/\"]\n")
+
+	// act
+	_, err := LoadSettings(paths, projectDir)
+
+	// assert
+	if err == nil {
+		t.Error("LoadSettings() error = nil, want error")
+	}
+}
+
+func TestSettings_TemplateDirs(t *testing.T) {
+	paths := &Paths{HomeDir: "/home/user", TemplatesDir: "/home/user/.agentbox/templates"}
+	tests := []struct {
+		name     string
+		dir      string
+		expected []string
+	}{
+		{"not configured", "", []string{"/home/user/.agentbox/templates"}},
+		{"absolute", "/srv/templates", []string{"/home/user/.agentbox/templates", "/srv/templates"}},
+		{"home", "~/team/templates", []string{"/home/user/.agentbox/templates", "/home/user/team/templates"}},
+		{"relative to project", "tools/agentbox", []string{"/home/user/.agentbox/templates", "/src/app/tools/agentbox"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			settings := &Settings{Templates: TemplateSettings{Dir: tt.dir}}
+
+			// act
+			result := settings.TemplateDirs(paths, "/src/app")
+
+			// assert
+			if !slices.Equal(result, tt.expected) {
+				t.Errorf("TemplateDirs() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadSettings__invalid_toml(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	paths := &Paths{ConfigFile: filepath.Join(tmpDir, "config.toml")}
+	writeFile(t, paths.ConfigFile, "[agents\n")
+
+	// act
+	_, err := LoadSettings(paths, "")
+
+	// assert
+	if err == nil {
+		t.Fatal("expected error for invalid config")
+	}
+}
+
+func TestSettings_AgentArgs(t *testing.T) {
+	settings := &Settings{
+		Agents: map[string]AgentSettings{
+			"claude": {Args: []string{}},
+			"gemini": {},
+		},
+	}
+	defaults := []string{"--yolo"}
+
+	tests := []struct {
+		name     string
+		agent    string
+		expected []string
+	}{
+		{"empty list disables defaults", "claude", []string{}},
+		{"table without args uses defaults", "gemini", defaults},
+		{"unconfigured agent uses defaults", "codex", defaults},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result := settings.AgentArgs(tt.agent, defaults)
+
+			// assert
+			if !slices.Equal(result, tt.expected) {
+				t.Errorf("AgentArgs(%s) = %v, want %v", tt.agent, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckpointSettings(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name             string
+		settings         CheckpointSettings
+		expectedEnabled  bool
+		expectedInterval time.Duration
+		wantErr          bool
+	}{
+		{"defaults", CheckpointSettings{}, true, DefaultCheckpointInterval, false},
+		{"disabled", CheckpointSettings{Enabled: &disabled}, false, DefaultCheckpointInterval, false},
+		{"custom interval", CheckpointSettings{Interval: "90s"}, true, 90 * time.Second, false},
+		{"no periodic", CheckpointSettings{Interval: "0"}, true, 0, false},
+		{"invalid interval", CheckpointSettings{Interval: "often"}, true, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			enabled := tt.settings.IsEnabled()
+			interval, err := tt.settings.IntervalDuration()
+
+			// assert
+			if enabled != tt.expectedEnabled {
+				t.Errorf("IsEnabled = %v, want %v", enabled, tt.expectedEnabled)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IntervalDuration error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if interval != tt.expectedInterval {
+				t.Errorf("IntervalDuration = %s, want %s", interval, tt.expectedInterval)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// EgressModes returns all supported egress modes.
+func EgressModes() []string {
+	return []string{EgressOpen, EgressAllowlist}
+}
+
+// ValidateEgress returns an error if mode is not a supported egress mode.
+func ValidateEgress(mode string) error {
+	if !slices.Contains(EgressModes(), mode) {
+		return fmt.Errorf("invalid egress mode %q (available: %s)", mode, strings.Join(EgressModes(), ", "))
+	}
+	return nil
+}
+
+// portRe matches a port in compose short syntax: [[ip:][host]:]container[/protocol],
+// where ports may be ranges such as 8000-8010.
+var portRe = regexp.MustCompile(`^(?:(?:(\d{1,3}(?:\.\d{1,3}){3}|\[[0-9a-fA-F:]+\]):)?(\d+(?:-\d+)?)?:)?(\d+(?:-\d+)?)(?:/(tcp|udp))?$`)
+
+// ValidatePort checks a published port in compose short syntax, e.g. "3000",
+// "8080:80" or "127.0.0.1:8080:80/tcp".
+func ValidatePort(spec string) error {
+	m := portRe.FindStringSubmatch(spec)
+	if m == nil {
+		return fmt.Errorf("invalid port %q, expected [[ip:]host:]container[/tcp|udp]", spec)
+	}
+	for _, ports := range []string{m[2], m[3]} {
+		if ports == "" {
+			continue
+		}
+		for _, port := range strings.Split(ports, "-") {
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				return fmt.Errorf("invalid port %q, ports are 1-65535", spec)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseMemory parses a memory size in Docker notation: a number of bytes with
+// an optional b, k, m or g suffix, such as "512m" or "4g".
+func ParseMemory(s string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	value = strings.TrimSuffix(value, "b")
+	multiplier := int64(1)
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'm':
+			multiplier = 1 << 20
+		case 'g':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			value = value[:len(value)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 || n*float64(multiplier) > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid memory %q: use a size such as 512m or 4g", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
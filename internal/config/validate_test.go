@@ -0,0 +1,82 @@
+package config
+
+import "testing"
+
+func TestValidateEgress(t *testing.T) {
+	// assert
+	if err := ValidateEgress(EgressAllowlist); err != nil {
+		t.Errorf("ValidateEgress(allowlist) error: %v", err)
+	}
+	if err := ValidateEgress("closed"); err == nil {
+		t.Error("ValidateEgress(closed) should fail")
+	}
+}
+
+func TestValidatePort(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"3000", false},
+		{"8080:80", false},
+		{"127.0.0.1:8080:80", false},
+		{"127.0.0.1::80", false},
+		{"8000-8010:8000-8010/tcp", false},
+		{"5353:53/udp", false},
+		{"[::1]:8080:80", false},
+		{"", true},
+		{"http", true},
+		{"70000", true},
+		{"0:80", true},
+		{"8080:80/sctp", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			// act
+			err := ValidatePort(tt.spec)
+
+			// assert
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePort(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{"1024", 1024, false},
+		{"512m", 512 << 20, false},
+		{"4g", 4 << 30, false},
+		{"4G", 4 << 30, false},
+		{"4gb", 4 << 30, false},
+		{"1.5g", 3 << 29, false},
+		{"64k", 64 << 10, false},
+		{"100b", 100, false},
+		{"", 0, true},
+		{"g", 0, true},
+		{"0", 0, true},
+		{"4t", 0, true},
+		{"lots", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			// act
+			result, err := ParseMemory(tt.input)
+
+			// assert
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMemory(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("ParseMemory(%q) = %d, want %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Op is a line edit operation.
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Edit is a single line of an edit script. Lines keep their trailing newline.
+type Edit struct {
+	Op   Op
+	Line string
+}
+
+// contextLines is the number of unchanged lines around each hunk.
+const contextLines = 3
+
+// maxEditDistance bounds the work spent on very different inputs. Beyond it
+// the whole old content is replaced by the new one.
+const maxEditDistance = 4000
+
+// SplitLines splits text into lines, keeping line endings.
+func SplitLines(text []byte) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(text), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// IsBinary reports whether data looks like binary content.
+func IsBinary(data []byte) bool {
+	const sniffLen = 8000
+	return bytes.IndexByte(data[:min(len(data), sniffLen)], 0) != -1
+}
+
+// Lines returns an edit script that turns a into b.
+func Lines(a, b []string) []Edit {
+	// common prefix and suffix are cheap to strip and keep the search small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		edits = append(edits, Edit{Op: Equal, Line: line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Op: Equal, Line: line})
+	}
+	return edits
+}
+
+// myers implements the greedy O(ND) algorithm by Eugene Myers.
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxEditDistance)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset)
+			}
+		}
+	}
+
+	return replaceAll(a, b)
+}
+
+func backtrack(trace [][]int, a, b []string, offset int) []Edit {
+	x, y := len(a), len(b)
+	var reversed []Edit
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Edit{Op: Equal, Line: a[x-1]})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			reversed = append(reversed, Edit{Op: Insert, Line: b[y-1]})
+			y--
+		} else {
+			reversed = append(reversed, Edit{Op: Delete, Line: a[x-1]})
+			x--
+		}
+	}
+
+	edits := make([]Edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
+
+func replaceAll(a, b []string) []Edit {
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a {
+		edits = append(edits, Edit{Op: Delete, Line: line})
+	}
+	for _, line := range b {
+		edits = append(edits, Edit{Op: Insert, Line: line})
+	}
+	return edits
+}
+
+// Stats returns the number of inserted and deleted lines in edits.
+func Stats(edits []Edit) (inserted, deleted int) {
+	for _, e := range edits {
+		switch e.Op {
+		case Insert:
+			inserted++
+		case Delete:
+			deleted++
+		}
+	}
+	return inserted, deleted
+}
+
+// Unified returns a unified diff between a and b, or an empty string if they are equal.
+func Unified(oldName, newName string, a, b []byte) string {
+	if bytes.Equal(a, b) {
+		return ""
+	}
+	if IsBinary(a) || IsBinary(b) {
+		return fmt.Sprintf("Binary files %s and %s differ\n", oldName, newName)
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	writeHunks(&out, Lines(SplitLines(a), SplitLines(b)))
+	return out.String()
+}
+
+// writeHunks groups edits into hunks with surrounding context.
+func writeHunks(out *strings.Builder, edits []Edit) {
+	for start := 0; start < len(edits); {
+		// find the next change
+		for start < len(edits) && edits[start].Op == Equal {
+			start++
+		}
+		if start == len(edits) {
+			return
+		}
+
+		// extend the hunk while changes are close enough to share context
+		end := start
+		for i := start; i < len(edits); i++ {
+			if edits[i].Op != Equal {
+				end = i + 1
+				continue
+			}
+			if i-end >= 2*contextLines {
+				break
+			}
+		}
+
+		from := max(start-contextLines, 0)
+		to := min(end+contextLines, len(edits))
+		writeHunk(out, edits, from, to)
+		start = to
+	}
+}
+
+func writeHunk(out *strings.Builder, edits []Edit, from, to int) {
+	// line numbers of the hunk start in the old and new content
+	oldLine, newLine := 1, 1
+	for _, e := range edits[:from] {
+		if e.Op != Insert {
+			oldLine++
+		}
+		if e.Op != Delete {
+			newLine++
+		}
+	}
+
+	var oldCount, newCount int
+	var body strings.Builder
+	for _, e := range edits[from:to] {
+		prefix := " "
+		switch e.Op {
+		case Equal:
+			oldCount++
+			newCount++
+		case Delete:
+			prefix = "-"
+			oldCount++
+		case Insert:
+			prefix = "+"
+			newCount++
+		}
+		body.WriteString(prefix)
+		body.WriteString(e.Line)
+		if !strings.HasSuffix(e.Line, "\n") {
+			body.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+	out.WriteString(body.String())
+}
+
+// hunkRange formats a hunk range; an empty range refers to the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,126 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// apply rebuilds both sides of an edit script.
+func apply(edits []Edit) (a, b []string) {
+	for _, e := range edits {
+		if e.Op != Insert {
+			a = append(a, e.Line)
+		}
+		if e.Op != Delete {
+			b = append(b, e.Line)
+		}
+	}
+	return a, b
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name              string
+		a, b              string
+		inserted, deleted int
+	}{
+		{"equal", "a\nb\nc\n", "a\nb\nc\n", 0, 0},
+		{"empty to text", "", "a\nb\n", 2, 0},
+		{"text to empty", "a\nb\n", "", 0, 2},
+		{"replace middle", "a\nb\nc\n", "a\nx\nc\n", 1, 1},
+		{"insert and delete", "a\nb\nc\nd\n", "b\nc\ne\nd\nf\n", 2, 1},
+		{"myers example", "a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n", 2, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			a, b := SplitLines([]byte(tt.a)), SplitLines([]byte(tt.b))
+
+			// act
+			edits := Lines(a, b)
+
+			// assert
+			gotA, gotB := apply(edits)
+			if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+				t.Fatalf("edit script does not reproduce inputs: %v", edits)
+			}
+			inserted, deleted := Stats(edits)
+			if inserted != tt.inserted || deleted != tt.deleted {
+				t.Errorf("Stats = +%d -%d, want +%d -%d", inserted, deleted, tt.inserted, tt.deleted)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	// arrange
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+
+	// act
+	result := Unified("a/file", "b/file", []byte(a), []byte(b))
+
+	// assert
+	expected := `--- a/file
++++ b/file
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`
+	if result != expected {
+		t.Errorf("Unified =\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestUnified__no_newline_at_end(t *testing.T) {
+	// act
+	result := Unified("a", "b", []byte("x\n"), []byte("x\ny"))
+
+	// assert
+	expected := "--- a\n+++ b\n@@ -1 +1,2 @@\n x\n+y\n\\ No newline at end of file\n"
+	if result != expected {
+		t.Errorf("Unified =\n%q\nwant:\n%q", result, expected)
+	}
+}
+
+func TestUnified__new_file(t *testing.T) {
+	// act
+	result := Unified("/dev/null", "b/new", nil, []byte("a\nb\n"))
+
+	// assert
+	if !strings.Contains(result, "@@ -0,0 +1,2 @@\n+a\n+b\n") {
+		t.Errorf("Unified =\n%s", result)
+	}
+}
+
+func TestUnified__equal(t *testing.T) {
+	// act
+	result := Unified("a", "b", []byte("same\n"), []byte("same\n"))
+
+	// assert
+	if result != "" {
+		t.Errorf("Unified = %q, want empty", result)
+	}
+}
+
+func TestUnified__binary(t *testing.T) {
+	// act
+	result := Unified("a/img", "b/img", []byte{0x89, 0x00, 0x01}, []byte{0x89, 0x00, 0x02})
+
+	// assert
+	if result != "Binary files a/img and b/img differ\n" {
+		t.Errorf("Unified = %q", result)
+	}
+}
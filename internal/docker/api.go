@@ -0,0 +1,401 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIError is an error response of the Docker Engine API.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// IsNotFound reports whether err is a "not found" response, e.g. for an unknown container.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Client talks to the Docker Engine API over a unix socket.
+// Requests are not versioned, so the daemon's current API version is used.
+type Client struct {
+	socket string
+	http   *http.Client
+}
+
+// NewClient returns a client for the socket.
+func NewClient(socket string) *Client {
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	}
+	return &Client{
+		socket: socket,
+		http:   &http.Client{Transport: &http.Transport{DialContext: dial}},
+	}
+}
+
+// SocketPath returns the Docker socket from DOCKER_HOST or the default
+// locations, or "" if there is none, e.g. when DOCKER_HOST is a TCP address.
+func SocketPath() string {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		if path, ok := strings.CutPrefix(host, "unix://"); ok {
+			return path
+		}
+		return ""
+	}
+
+	candidates := []string{"/var/run/docker.sock"}
+	if home, err := os.UserHomeDir(); err == nil {
+		// Docker Desktop
+		candidates = append(candidates, filepath.Join(home, ".docker", "run", "docker.sock"))
+	}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return path
+		}
+	}
+	return ""
+}
+
+var (
+	defaultClient     *Client
+	defaultClientOnce sync.Once
+)
+
+// apiClient returns a client for the socket of the current runtime, or nil if
+// it is not reachable, in which case callers fall back to the CLI.
+func apiClient() *Client {
+	defaultClientOnce.Do(func() {
+		rt, err := CurrentRuntime()
+		if err != nil {
+			return
+		}
+		socket := rt.Socket()
+		if socket == "" {
+			return
+		}
+		client := NewClient(socket)
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx); err == nil {
+			defaultClient = client
+		}
+	})
+	return defaultClient
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, result any) error {
+	resp, err := c.request(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if result == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+// request sends a request and returns the response if its status is successful.
+func (c *Client) request(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, readAPIError(resp)
+	}
+	return resp, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body any) (*http.Request, error) {
+	u := url.URL{Scheme: "http", Host: "docker", Path: path, RawQuery: query.Encode()}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+func readAPIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(data))
+	if err := json.Unmarshal(data, &body); err == nil && body.Message != "" {
+		message = body.Message
+	}
+	if message == "" {
+		message = resp.Status
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: message}
+}
+
+// Ping checks that the daemon responds.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/_ping", nil, nil, nil)
+}
+
+// Info returns the resources and cgroup features of the host.
+func (c *Client) Info(ctx context.Context) (HostInfo, error) {
+	var info HostInfo
+	err := c.do(ctx, http.MethodGet, "/info", nil, nil, &info)
+	return info, err
+}
+
+// APIContainer is a container in the list returned by the API.
+type APIContainer struct {
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	Created int64             `json:"Created"`
+	State   string            `json:"State"`
+	Status  string            `json:"Status"`
+	Labels  map[string]string `json:"Labels"`
+	Ports   []APIPort         `json:"Ports"`
+}
+
+// APIPort is a port of a container as listed by the API.
+type APIPort struct {
+	IP          string `json:"IP"`
+	PrivatePort int    `json:"PrivatePort"`
+	PublicPort  int    `json:"PublicPort"`
+	Type        string `json:"Type"`
+}
+
+// apiPorts formats ports the way docker ps shows them.
+func apiPorts(ports []APIPort) string {
+	formatted := make([]string, 0, len(ports))
+	for _, p := range ports {
+		if p.PublicPort == 0 {
+			formatted = append(formatted, fmt.Sprintf("%d/%s", p.PrivatePort, p.Type))
+			continue
+		}
+		host := p.IP
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		formatted = append(formatted, fmt.Sprintf("%s:%d->%d/%s", host, p.PublicPort, p.PrivatePort, p.Type))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// ListContainers returns running containers matching label filters, such as "com.docker.compose.service=agentbox".
+func (c *Client) ListContainers(ctx context.Context, labels []string) ([]APIContainer, error) {
+	query := url.Values{}
+	if len(labels) > 0 {
+		filters, err := json.Marshal(map[string][]string{"label": labels})
+		if err != nil {
+			return nil, err
+		}
+		query.Set("filters", string(filters))
+	}
+
+	var containers []APIContainer
+	err := c.do(ctx, http.MethodGet, "/containers/json", query, nil, &containers)
+	return containers, err
+}
+
+// ContainerDetails is the part of the inspect response agentbox uses.
+type ContainerDetails struct {
+	ID    string `json:"Id"`
+	Name  string `json:"Name"`
+	State struct {
+		Status    string    `json:"Status"`
+		Running   bool      `json:"Running"`
+		StartedAt time.Time `json:"StartedAt"`
+		ExitCode  int       `json:"ExitCode"`
+	} `json:"State"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// Inspect returns details of a container.
+func (c *Client) Inspect(ctx context.Context, id string) (ContainerDetails, error) {
+	var details ContainerDetails
+	err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", nil, nil, &details)
+	return details, err
+}
+
+// Stop stops a container, killing it after timeout; a negative timeout uses the container default.
+func (c *Client) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/stop", timeoutQuery(timeout), nil, nil)
+}
+
+// Restart restarts a container, see Stop for timeout.
+func (c *Client) Restart(ctx context.Context, id string, timeout time.Duration) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/restart", timeoutQuery(timeout), nil, nil)
+}
+
+// Kill kills a container.
+func (c *Client) Kill(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/kill", nil, nil, nil)
+}
+
+func timeoutQuery(timeout time.Duration) url.Values {
+	if timeout < 0 {
+		return nil
+	}
+	return url.Values{"t": {strconv.Itoa(int(timeout.Seconds()))}}
+}
+
+// ExecConfig configures a command started in a container.
+type ExecConfig struct {
+	Cmd          []string `json:"Cmd"`
+	User         string   `json:"User,omitempty"`
+	WorkingDir   string   `json:"WorkingDir,omitempty"`
+	Env          []string `json:"Env,omitempty"`
+	Tty          bool     `json:"Tty"`
+	AttachStdin  bool     `json:"AttachStdin"`
+	AttachStdout bool     `json:"AttachStdout"`
+	AttachStderr bool     `json:"AttachStderr"`
+}
+
+// ExecCreate prepares a command in a container and returns the exec id.
+func (c *Client) ExecCreate(ctx context.Context, containerID string, config ExecConfig) (string, error) {
+	var resp struct {
+		ID string `json:"Id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(containerID)+"/exec", nil, config, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// ExecStart starts an exec and returns the connection attached to its TTY.
+// The caller must close the connection.
+func (c *Client) ExecStart(ctx context.Context, execID string) (io.ReadWriteCloser, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/exec/"+url.PathEscape(execID)+"/start", nil,
+		map[string]bool{"Detach": false, "Tty": true})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", c.socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		defer conn.Close()
+		return nil, readAPIError(resp)
+	}
+	return &hijackedConn{Conn: conn, r: br}, nil
+}
+
+// hijackedConn reads the bytes buffered while reading the response first.
+type hijackedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *hijackedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// CloseWrite signals the end of input to the command.
+func (c *hijackedConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// ExecResize changes the TTY size of an exec.
+func (c *Client) ExecResize(ctx context.Context, execID string, width, height int) error {
+	query := url.Values{"w": {strconv.Itoa(width)}, "h": {strconv.Itoa(height)}}
+	return c.do(ctx, http.MethodPost, "/exec/"+url.PathEscape(execID)+"/resize", query, nil, nil)
+}
+
+// Bounds of waiting for an exec to be reported as finished after its stream closed.
+const (
+	execWaitTimeout  = 5 * time.Second
+	execPollInterval = 50 * time.Millisecond
+)
+
+// ExecExitCode returns the exit code of a finished exec. The daemon may still
+// report the exec as running right after its stream closed, so it is polled
+// for a short time until it is not.
+func (c *Client) ExecExitCode(ctx context.Context, execID string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, execWaitTimeout)
+	defer cancel()
+
+	for {
+		var resp struct {
+			Running  bool `json:"Running"`
+			ExitCode int  `json:"ExitCode"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/exec/"+url.PathEscape(execID)+"/json", nil, nil, &resp); err != nil {
+			return 0, err
+		}
+		if !resp.Running {
+			return resp.ExitCode, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("exec %s is still running", execID)
+		case <-time.After(execPollInterval):
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestClient serves handler on a unix socket and returns a client for it.
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	// unix socket paths are limited to about 100 characters, t.TempDir is too long on macOS
+	dir, err := os.MkdirTemp("", "agentbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return NewClient(socket)
+}
+
+func TestClient_ListContainers(t *testing.T) {
+	// arrange
+	var filters string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" {
+			http.NotFound(w, r)
+			return
+		}
+		filters = r.URL.Query().Get("filters")
+		_, _ = io.WriteString(w, `[{"Id":"abc123def4567890","Names":["/app-agentbox-1"],"Created":1700000000,`+
+			`"Labels":{"agentbox.worktree":"feature-x"}}]`)
+	}))
+
+	// act
+	containers, err := client.ListContainers(context.Background(), []string{"com.docker.compose.service=agentbox"})
+
+	// assert
+	if err != nil {
+		t.Fatalf("ListContainers error: %v", err)
+	}
+	if filters != `{"label":["com.docker.compose.service=agentbox"]}` {
+		t.Errorf("filters = %s", filters)
+	}
+	if len(containers) != 1 || containers[0].ID != "abc123def4567890" || containers[0].Labels[LabelWorktree] != "feature-x" {
+		t.Errorf("containers = %+v", containers)
+	}
+}
+
+func TestClient_Inspect(t *testing.T) {
+	// arrange
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/abc/json" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"message":"No such container: other"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"Id":"abc","Name":"/app-agentbox-1","State":{"Running":true,`+
+			`"StartedAt":"2026-10-18T12:00:00Z"},"Config":{"Image":"agentbox-app"}}`)
+	}))
+
+	t.Run("found", func(t *testing.T) {
+		// act
+		details, err := client.Inspect(context.Background(), "abc")
+
+		// assert
+		if err != nil {
+			t.Fatalf("Inspect error: %v", err)
+		}
+		if !details.State.Running || details.Config.Image != "agentbox-app" ||
+			!details.State.StartedAt.Equal(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)) {
+			t.Errorf("details = %+v", details)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		// act
+		_, err := client.Inspect(context.Background(), "other")
+
+		// assert
+		if !IsNotFound(err) {
+			t.Fatalf("err = %v, want not found", err)
+		}
+		if err.Error() != "No such container: other" {
+			t.Errorf("message = %q", err.Error())
+		}
+	})
+}
+
+func TestClient_Stop(t *testing.T) {
+	// arrange
+	var requests []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	ctx := context.Background()
+
+	// act
+	err1 := client.Stop(ctx, "abc", -1)
+	err2 := client.Stop(ctx, "abc", 10*time.Second)
+	err3 := client.Kill(ctx, "abc")
+
+	// assert
+	if err1 != nil || err2 != nil || err3 != nil {
+		t.Fatalf("errors = %v, %v, %v", err1, err2, err3)
+	}
+	expected := []string{
+		"POST /containers/abc/stop",
+		"POST /containers/abc/stop?t=10",
+		"POST /containers/abc/kill",
+	}
+	if !slices.Equal(requests, expected) {
+		t.Errorf("requests = %v, want %v", requests, expected)
+	}
+}
+
+func TestClient_Exec(t *testing.T) {
+	// arrange
+	var config ExecConfig
+	var resized string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /containers/abc/exec", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&config)
+		_, _ = io.WriteString(w, `{"Id":"exec1"}`)
+	})
+	mux.HandleFunc("POST /exec/exec1/start", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "tcp" {
+			http.Error(w, "upgrade expected", http.StatusBadRequest)
+			return
+		}
+		_, _ = io.Copy(io.Discard, r.Body)
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.WriteString(conn, "HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		// echo one line back, like a shell would
+		line, _ := bufio.NewReader(buf).ReadString('\n')
+		_, _ = io.WriteString(conn, "echo: "+line)
+	})
+	mux.HandleFunc("POST /exec/exec1/resize", func(w http.ResponseWriter, r *http.Request) {
+		resized = r.URL.Query().Get("w") + "x" + r.URL.Query().Get("h")
+	})
+	mux.HandleFunc("GET /exec/exec1/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"Running":false,"ExitCode":3}`)
+	})
+	client := newTestClient(t, mux)
+	ctx := context.Background()
+
+	// act
+	execID, err := client.ExecCreate(ctx, "abc", ExecConfig{Cmd: []string{"/bin/bash"}, User: "root", Tty: true})
+	if err != nil {
+		t.Fatalf("ExecCreate error: %v", err)
+	}
+	conn, err := client.ExecStart(ctx, execID)
+	if err != nil {
+		t.Fatalf("ExecStart error: %v", err)
+	}
+	defer conn.Close()
+	if err := client.ExecResize(ctx, execID, 120, 40); err != nil {
+		t.Fatalf("ExecResize error: %v", err)
+	}
+	_, _ = io.WriteString(conn, "hello\n")
+	output, _ := io.ReadAll(conn)
+	code, err := client.ExecExitCode(ctx, execID)
+
+	// assert
+	if err != nil {
+		t.Fatalf("ExecExitCode error: %v", err)
+	}
+	if !slices.Equal(config.Cmd, []string{"/bin/bash"}) || config.User != "root" || !config.Tty {
+		t.Errorf("config = %+v", config)
+	}
+	if string(output) != "echo: hello\n" {
+		t.Errorf("output = %q", output)
+	}
+	if resized != "120x40" {
+		t.Errorf("resized = %q, want 120x40", resized)
+	}
+	if code != 3 {
+		t.Errorf("exit code = %d, want 3", code)
+	}
+}
+
+func TestClient_ExecExitCode__still_running(t *testing.T) {
+	// arrange
+	polls := 0
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 3 {
+			_, _ = io.WriteString(w, `{"Running":true,"ExitCode":0}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"Running":false,"ExitCode":7}`)
+	}))
+
+	// act
+	code, err := client.ExecExitCode(context.Background(), "exec1")
+
+	// assert
+	if err != nil {
+		t.Fatalf("ExecExitCode error: %v", err)
+	}
+	if code != 7 || polls != 3 {
+		t.Errorf("code = %d after %d polls, want 7 after 3", code, polls)
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerHost string
+		expected   string
+	}{
+		{"unix socket", "unix:///run/user/1000/docker.sock", "/run/user/1000/docker.sock"},
+		{"tcp host", "tcp://10.0.0.1:2375", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			t.Setenv("DOCKER_HOST", tt.dockerHost)
+
+			// act
+			result := SocketPath()
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("SocketPath() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConvertContainers(t *testing.T) {
+	// arrange
+	now := time.Unix(1700000000, 0)
+	containers := []APIContainer{
+		{ID: "abc123def4567890", Names: []string{"/app-agentbox-1"}, Created: now.Add(-2 * time.Hour).Unix()},
+		{ID: "789xyz000111aaaa", Names: []string{"/review"}, Created: now.Add(-5 * time.Minute).Unix(),
+			Labels: map[string]string{LabelWorktree: "feature-x", LabelSession: "review", labelProjectDir: "/home/user/app"},
+			Ports: []APIPort{
+				{IP: "127.0.0.1", PrivatePort: 3000, PublicPort: 3000, Type: "tcp"},
+				{IP: "0.0.0.0", PrivatePort: 80, PublicPort: 8080, Type: "tcp"},
+				{IP: "::", PrivatePort: 80, PublicPort: 8080, Type: "tcp"},
+				{PrivatePort: 5432, Type: "tcp"},
+			}},
+	}
+
+	// act
+	result := convertContainers(containers, now)
+
+	// assert
+	expected := []Container{
+		{ID: "abc123def456", Name: "app-agentbox-1", Started: "2 hours ago"},
+		{ID: "789xyz000111", Name: "review", Started: "5 minutes ago", Worktree: "feature-x", Session: "review",
+			ProjectDir: "/home/user/app", Ports: "127.0.0.1:3000->3000/tcp, 8080->80/tcp"},
+	}
+	if !slices.Equal(result, expected) {
+		t.Errorf("convertContainers = %+v, want %+v", result, expected)
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "Less than a second"},
+		{time.Second, "1 second"},
+		{30 * time.Second, "30 seconds"},
+		{90 * time.Second, "About a minute"},
+		{45 * time.Minute, "45 minutes"},
+		{70 * time.Minute, "About an hour"},
+		{5 * time.Hour, "5 hours"},
+		{72 * time.Hour, "3 days"},
+		{21 * 24 * time.Hour, "3 weeks"},
+		{90 * 24 * time.Hour, "3 months"},
+		{3 * 365 * 24 * time.Hour, "3 years"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			// act
+			result := HumanDuration(tt.duration)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("HumanDuration(%v) = %q, want %q", tt.duration, result, tt.expected)
+			}
+		})
+	}
+}
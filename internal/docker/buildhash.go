@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Labels set on the project image to tell whether it matches its build inputs.
+const (
+	LabelBuildHash    = "agentbox.build.hash"
+	LabelBuildProject = "agentbox.build.project"
+)
+
+// buildInputs are the project files that define the sandbox image. The compose
+// files are included because they may change the build section; unrelated
+// edits only cost a cached rebuild.
+var buildInputs = []string{
+	"Dockerfile.agentbox",
+	"mise.toml",
+	"docker-compose.agentbox.yml",
+	"docker-compose.agentbox.local.yml",
+}
+
+// BuildHash returns a hash of the build inputs of the project and the build args.
+// Missing files are hashed as absent, so creating one changes the hash.
+func BuildHash(projectDir string, buildArgs map[string]string) (string, error) {
+	h := sha256.New()
+	for _, name := range buildInputs {
+		data, err := os.ReadFile(filepath.Join(projectDir, name))
+		if os.IsNotExist(err) {
+			fmt.Fprintf(h, "file %s absent\n", name)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", name, err)
+		}
+		fmt.Fprintf(h, "file %s %d\n", name, len(data))
+		h.Write(data)
+	}
+	for _, key := range slices.Sorted(maps.Keys(buildArgs)) {
+		fmt.Fprintf(h, "arg %s=%s\n", key, buildArgs[key])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// BuildLabels returns the labels that mark the image of a project as built from hash.
+func BuildLabels(projectDir, hash string) map[string]string {
+	return map[string]string{
+		LabelBuildHash:    hash,
+		LabelBuildProject: projectDir,
+	}
+}
+
+// ImageUpToDate reports whether a tagged image of the project was built from the inputs with hash.
+func ImageUpToDate(projectDir, hash string) (bool, error) {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return false, err
+	}
+
+	// an image that lost its tag to a newer build is dangling, even if it has the hash
+	args := []string{"images", "--quiet", "--filter", "dangling=false"}
+	labels := BuildLabels(projectDir, hash)
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		args = append(args, "--filter", "label="+key+"="+labels[key])
+	}
+	out, err := rt.Command(context.Background(), args...).Output()
+	if err != nil {
+		return false, fmt.Errorf("%s images: %w", rt.Name(), err)
+	}
+	return strings.TrimSpace(string(out)) != "", nil
+}
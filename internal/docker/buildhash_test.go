@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildHash(t *testing.T) {
+	// arrange
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash := func(args map[string]string) string {
+		t.Helper()
+		h, err := BuildHash(dir, args)
+		if err != nil {
+			t.Fatalf("BuildHash() error = %v", err)
+		}
+		return h
+	}
+	writeFile("Dockerfile.agentbox", "FROM agentbox-base\n")
+	writeFile("mise.toml", "[tools]\ngo = \"1.25\"\n")
+	args := map[string]string{BaseImageArg: "agentbox-base:1.4.0"}
+
+	// act
+	initial := hash(args)
+	same := hash(map[string]string{BaseImageArg: "agentbox-base:1.4.0"})
+	otherArg := hash(map[string]string{BaseImageArg: "agentbox-base:1.5.0"})
+	writeFile("mise.toml", "[tools]\ngo = \"1.26\"\n")
+	otherTools := hash(args)
+	writeFile("docker-compose.agentbox.local.yml", "services: {}\n")
+	localCompose := hash(args)
+
+	// assert
+	if initial != same {
+		t.Errorf("hash changed without changes: %s != %s", initial, same)
+	}
+	for name, h := range map[string]string{"build arg": otherArg, "mise.toml": otherTools, "local compose": localCompose} {
+		if h == initial {
+			t.Errorf("hash did not change after changing %s", name)
+		}
+	}
+	if otherTools == localCompose {
+		t.Errorf("hash did not change after creating the local compose file")
+	}
+}
+
+func TestBuildHash__moved_content(t *testing.T) {
+	// arrange: the same bytes split differently between files must not collide
+	first := t.TempDir()
+	second := t.TempDir()
+	files := map[string][2]string{
+		first:  {"FROM a\nRUN x", "\n"},
+		second: {"FROM a\n", "RUN x\n"},
+	}
+	for dir, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, "Dockerfile.agentbox"), []byte(content[0]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "mise.toml"), []byte(content[1]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// act
+	h1, err1 := BuildHash(first, nil)
+	h2, err2 := BuildHash(second, nil)
+
+	// assert
+	if err1 != nil || err2 != nil {
+		t.Fatalf("BuildHash() errors = %v, %v", err1, err2)
+	}
+	if h1 == h2 {
+		t.Errorf("BuildHash() collided for different file contents: %s", h1)
+	}
+}
@@ -3,100 +3,400 @@ package docker
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
+	"time"
 )
 
-func Run(projectDir string) error {
-	ctx := context.Background()
+// Labels set by agentbox on sandbox containers.
+const (
+	LabelWorktree = "agentbox.worktree"
+	LabelSession  = "agentbox.session"
+)
+
+// labelProjectDir is set by compose to the project directory.
+const labelProjectDir = "com.docker.compose.project.working_dir"
+
+// RunOptions configures a sandbox session started by Run.
+type RunOptions struct {
+	// Name is the container name, empty lets compose generate one.
+	Name string
+	// Files are extra compose files layered on top of the project files.
+	Files []string
+	// Env is passed to the container in addition to the compose environment.
+	Env map[string]string
+	// Labels are added to the container.
+	Labels map[string]string
+	// ServicePorts publishes the ports of the service, which compose run skips by default.
+	ServicePorts bool
+	// Terminal runs the interactive session, nil connects it to the current terminal.
+	Terminal Terminal
+}
+
+// Terminal runs an interactive docker command, for example in a recorded
+// pseudo-terminal.
+type Terminal func(cmd *exec.Cmd) error
+
+// runInteractive runs cmd with terminal, or attached to os.Stdin and os.Stdout.
+func runInteractive(cmd *exec.Cmd, terminal Terminal) error {
+	if terminal != nil {
+		return terminal(cmd)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// composeArgs returns the compose file arguments for the project and extra files.
+func composeArgs(files []string) []string {
 	args := []string{
-		"compose",
 		"-f", "docker-compose.agentbox.yml",
 		"-f", "docker-compose.agentbox.local.yml",
-		"run", "--rm",
-		"agentbox",
 	}
+	for _, file := range files {
+		args = append(args, "-f", file)
+	}
+	return args
+}
+
+func Run(projectDir string, opts RunOptions) error {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	args := composeArgs(opts.Files)
+	args = append(args, "run", "--rm")
+	if opts.ServicePorts {
+		args = append(args, "--service-ports")
+	}
+	if opts.Name != "" {
+		args = append(args, "--name", opts.Name)
+	}
+	args = append(args, envArgs(opts.Env)...)
+	args = append(args, labelArgs(opts.Labels)...)
+	args = append(args, "agentbox")
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd := rt.ComposeCommand(ctx, args...)
 	cmd.Dir = projectDir
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("docker compose run: %w", err)
+	if err := runInteractive(cmd, opts.Terminal); err != nil {
+		return fmt.Errorf("%s run: %w", rt.ComposeName(), err)
 	}
 	return nil
 }
 
-func Attach(containerID string) error {
-	ctx := context.Background()
-	cmd := exec.CommandContext(ctx, "docker", "exec", "-it", containerID, "/bin/bash")
+// ErrTimeout is returned by RunCommand when the command does not finish in time.
+var ErrTimeout = errors.New("command timed out")
+
+// RunCommand starts a fresh container, runs command in it without a TTY and
+// returns the command's exit code. An error is returned if compose fails before
+// the container starts, e.g. on a missing image. When ctx is done the container
+// is removed and ErrTimeout is returned.
+func RunCommand(ctx context.Context, projectDir string, opts RunOptions, command []string, stdout, stderr io.Writer) (int, error) {
+	if len(command) == 0 {
+		return 0, errors.New("empty command")
+	}
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return 0, err
+	}
+
+	name := "agentbox-exec-" + randomSuffix()
+	args := composeArgs(opts.Files)
+	args = append(args,
+		"run", "-T",
+		"--name", name,
+		"--entrypoint", command[0],
+	)
+	args = append(args, envArgs(opts.Env)...)
+	args = append(args, labelArgs(opts.Labels)...)
+	args = append(args, "agentbox")
+	args = append(args, command[1:]...)
+
+	cmd := rt.ComposeCommand(ctx, args...)
+	cmd.Dir = projectDir
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// killing the compose client leaves the container running, so remove it first
+	cmd.Cancel = func() error {
+		removeContainer(rt, name)
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = 10 * time.Second
+	// without --rm the container is kept until its state tells whether it ran
+	defer removeContainer(rt, name)
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("docker exec: %w", err)
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return 0, ErrTimeout
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return 0, fmt.Errorf("%s run: %w", rt.ComposeName(), err)
+		}
+		if code, ok := containerExitCode(Inspect(name)); ok {
+			return code, nil
+		}
+		return 0, fmt.Errorf("%s run: %w", rt.ComposeName(), err)
+	}
+	return 0, nil
+}
+
+// containerExitCode returns the exit code of a container that ran, false if it
+// was never started, e.g. because compose could not pull its image or create it.
+func containerExitCode(details ContainerDetails, err error) (int, bool) {
+	if err != nil || details.State.StartedAt.IsZero() {
+		return 0, false
+	}
+	return details.State.ExitCode, true
+}
+
+// ExecService runs command in the container of a compose service if it is
+// running, e.g. to make a sidecar reread its configuration.
+func ExecService(projectDir string, files []string, service string, command ...string) error {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return err
+	}
+
+	args := composeArgs(files)
+	args = append(args, "ps", "--status", "running", "--quiet", service)
+	cmd := rt.ComposeCommand(context.Background(), args...)
+	cmd.Dir = projectDir
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("%s ps %s: %w", rt.ComposeName(), service, err)
+	}
+	if strings.TrimSpace(string(out)) == "" {
+		return nil
+	}
+
+	args = composeArgs(files)
+	args = append(args, "exec", "-T", service)
+	args = append(args, command...)
+	cmd = rt.ComposeCommand(context.Background(), args...)
+	cmd.Dir = projectDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s exec %s: %w: %s", rt.ComposeName(), service, err, strings.TrimSpace(string(out)))
 	}
 	return nil
 }
 
-func Build(projectDir string, noCache bool) error {
+// RemoveService stops and removes the containers of a compose service,
+// such as a sidecar started as a dependency of the sandbox.
+func RemoveService(projectDir string, files []string, service string) error {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return err
+	}
+
+	args := composeArgs(files)
+	args = append(args, "rm", "--stop", "--force", service)
+
+	cmd := rt.ComposeCommand(context.Background(), args...)
+	cmd.Dir = projectDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s rm %s: %w: %s", rt.ComposeName(), service, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func removeContainer(rt Runtime, name string) {
+	cmd := rt.Command(context.Background(), "rm", "-f", name)
+	_ = cmd.Run()
+}
+
+func randomSuffix() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// envArgs converts env into sorted "-e KEY=VALUE" arguments.
+func envArgs(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		args = append(args, "-e", key+"="+env[key])
+	}
+	return args
+}
+
+// labelArgs converts labels into sorted "--label KEY=VALUE" arguments.
+func labelArgs(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		args = append(args, "--label", key+"="+labels[key])
+	}
+	return args
+}
+
+// AttachOptions configures a command started in a running container by Attach.
+type AttachOptions struct {
+	// Command is run instead of the default shell.
+	Command []string
+	// User runs the command as another user, such as "root".
+	User string
+	// WorkDir is the working directory of the command.
+	WorkDir string
+	// Terminal runs the session, nil connects it to the current terminal.
+	Terminal Terminal
+}
+
+// DefaultAttachCommand is started by Attach when no command is given.
+var DefaultAttachCommand = []string{"/bin/bash"}
+
+// Attach starts an interactive command, a shell by default, in a running container.
+// It uses the Engine API when the Docker socket is reachable and no Terminal is
+// set, and the docker CLI otherwise.
+func Attach(containerID string, opts AttachOptions) error {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return err
+	}
+
+	if client := apiClient(); client != nil && opts.Terminal == nil {
+		if err := attachAPI(client, containerID, opts); err != nil {
+			return fmt.Errorf("%s exec: %w", rt.Name(), err)
+		}
+		return nil
+	}
+
 	ctx := context.Background()
-	args := []string{
-		"compose",
-		"-f", "docker-compose.agentbox.yml",
-		"-f", "docker-compose.agentbox.local.yml",
-		"build",
+	cmd := rt.Command(ctx, attachArgs(containerID, opts)...)
+
+	if err := runInteractive(cmd, opts.Terminal); err != nil {
+		return fmt.Errorf("%s exec: %w", rt.Name(), err)
+	}
+	return nil
+}
+
+func attachArgs(containerID string, opts AttachOptions) []string {
+	args := []string{"exec", "-it"}
+	if opts.User != "" {
+		args = append(args, "--user", opts.User)
+	}
+	if opts.WorkDir != "" {
+		args = append(args, "--workdir", opts.WorkDir)
+	}
+	args = append(args, containerID)
+
+	if len(opts.Command) == 0 {
+		return append(args, DefaultAttachCommand...)
+	}
+	return append(args, opts.Command...)
+}
+
+// Build builds the agentbox image with the project compose files and the given extra files.
+func Build(projectDir string, files []string, noCache bool) error {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return err
 	}
 
+	ctx := context.Background()
+	args := append(composeArgs(files), "build")
+
 	if noCache {
 		args = append(args, "--no-cache")
 	}
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd := rt.ComposeCommand(ctx, args...)
 	cmd.Dir = projectDir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("docker compose build: %w", err)
+		return fmt.Errorf("%s build: %w", rt.ComposeName(), err)
 	}
 	return nil
 }
 
 type Container struct {
-	ID      string
-	Name    string
-	Started string
+	ID       string
+	Name     string
+	Started  string
+	Worktree string
+	// Session is the name given with run --name.
+	Session string
+	// ProjectDir is the host directory of the project.
+	ProjectDir string
+	Image      string
+	// Status is the state as shown by docker ps, e.g. "Up 2 hours".
+	Status string
+	// Ports are the published ports, e.g. "127.0.0.1:3000->3000/tcp, 8080->80/tcp".
+	Ports string
 }
 
+// ListContainers returns the running agentbox containers of the project, or of
+// all projects if all is set.
 func ListContainers(projectDir string, all bool) ([]Container, error) {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return nil, err
+	}
+
+	labels := []string{"com.docker.compose.service=agentbox"}
+	if !all && projectDir != "" {
+		labels = append(labels, labelProjectDir+"="+projectDir)
+	}
+
+	if client := apiClient(); client != nil {
+		containers, err := client.ListContainers(context.Background(), labels)
+		if err != nil {
+			return nil, fmt.Errorf("list containers: %w", err)
+		}
+		return convertContainers(containers, time.Now()), nil
+	}
+	return listContainersCLI(rt, labels)
+}
+
+func listContainersCLI(rt Runtime, labels []string) ([]Container, error) {
 	ctx := context.Background()
 
 	args := []string{
 		"ps",
-		"--filter", "label=com.docker.compose.service=agentbox",
-		"--format", "{{.ID}}\t{{.Names}}\t{{.RunningFor}}",
+		"--format", "{{.ID}}\t{{.Names}}\t{{.RunningFor}}\t" + rt.LabelFormat(LabelWorktree) +
+			"\t" + rt.LabelFormat(LabelSession) + "\t" + rt.LabelFormat(labelProjectDir) +
+			"\t{{.Image}}\t{{.Status}}\t{{.Ports}}",
 	}
-
-	if !all && projectDir != "" {
-		args = append(args, "--filter", "label=com.docker.compose.project.working_dir="+projectDir)
+	for _, label := range labels {
+		args = append(args, "--filter", "label="+label)
 	}
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd := rt.Command(ctx, args...)
 	var out, stderr bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
 		if stderr.Len() > 0 {
-			return nil, fmt.Errorf("docker ps: %s", strings.TrimSpace(stderr.String()))
+			return nil, fmt.Errorf("%s ps: %s", rt.Name(), strings.TrimSpace(stderr.String()))
 		}
-		return nil, fmt.Errorf("docker ps: %w", err)
+		return nil, fmt.Errorf("%s ps: %w", rt.Name(), err)
 	}
 
 	return parseContainersOutput(out.String()), nil
@@ -109,15 +409,92 @@ func parseContainersOutput(output string) []Container {
 		if line == "" {
 			continue
 		}
-		parts := strings.SplitN(line, "\t", 3)
+		parts := strings.SplitN(line, "\t", 9)
 		if len(parts) < 3 {
 			continue
 		}
-		containers = append(containers, Container{
+		c := Container{
 			ID:      parts[0],
 			Name:    parts[1],
 			Started: parts[2],
-		})
+		}
+		// labels are optional, older containers don't have them
+		field := func(i int) string {
+			if i < len(parts) {
+				return strings.TrimSpace(parts[i])
+			}
+			return ""
+		}
+		c.Worktree = field(3)
+		c.Session = field(4)
+		c.ProjectDir = field(5)
+		c.Image = field(6)
+		c.Status = field(7)
+		c.Ports = compactPorts(field(8))
+		containers = append(containers, c)
 	}
 	return containers
 }
+
+func convertContainers(containers []APIContainer, now time.Time) []Container {
+	result := make([]Container, 0, len(containers))
+	for _, c := range containers {
+		id := c.ID
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		var name string
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		result = append(result, Container{
+			ID:         id,
+			Name:       name,
+			Started:    HumanDuration(now.Sub(time.Unix(c.Created, 0))) + " ago",
+			Worktree:   c.Labels[LabelWorktree],
+			Session:    c.Labels[LabelSession],
+			ProjectDir: c.Labels[labelProjectDir],
+			Image:      c.Image,
+			Status:     c.Status,
+			Ports:      compactPorts(apiPorts(c.Ports)),
+		})
+	}
+	return result
+}
+
+// HumanDuration formats a duration the way docker ps shows how long ago a
+// container was created, e.g. "5 minutes" or "About an hour".
+func HumanDuration(d time.Duration) string {
+	seconds := int(d.Seconds())
+	switch {
+	case seconds < 1:
+		return "Less than a second"
+	case seconds == 1:
+		return "1 second"
+	case seconds < 60:
+		return fmt.Sprintf("%d seconds", seconds)
+	}
+
+	minutes := int(d.Minutes())
+	switch {
+	case minutes == 1:
+		return "About a minute"
+	case minutes < 60:
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+
+	hours := int(d.Hours() + 0.5)
+	switch {
+	case hours == 1:
+		return "About an hour"
+	case hours < 48:
+		return fmt.Sprintf("%d hours", hours)
+	case hours < 24*7*2:
+		return fmt.Sprintf("%d days", hours/24)
+	case hours < 24*30*2:
+		return fmt.Sprintf("%d weeks", hours/24/7)
+	case hours < 24*365*2:
+		return fmt.Sprintf("%d months", hours/24/30)
+	}
+	return fmt.Sprintf("%d years", int(d.Hours())/24/365)
+}
@@ -1,7 +1,11 @@
 package docker
 
 import (
+	"errors"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseContainersOutput(t *testing.T) {
@@ -29,6 +33,21 @@ func TestParseContainersOutput(t *testing.T) {
 	}
 }
 
+func TestParseContainersOutput__partial_labels(t *testing.T) {
+	// arrange
+	output := "abc123def456\tmy-project-agentbox-1\t2 hours ago\tfeature-x\treview"
+
+	// act
+	containers := parseContainersOutput(output)
+
+	// assert
+	expected := Container{ID: "abc123def456", Name: "my-project-agentbox-1", Started: "2 hours ago",
+		Worktree: "feature-x", Session: "review"}
+	if len(containers) != 1 || containers[0] != expected {
+		t.Errorf("containers = %+v, want [%+v]", containers, expected)
+	}
+}
+
 func TestParseContainersOutput__empty(t *testing.T) {
 	// act
 	containers := parseContainersOutput("")
@@ -95,3 +114,152 @@ func TestParseContainersOutput__single_container(t *testing.T) {
 		t.Errorf("containers[0] = %+v, want %+v", containers[0], expected)
 	}
 }
+
+func TestEnvArgs(t *testing.T) {
+	// arrange
+	env := map[string]string{
+		"B_VAR": "two words",
+		"A_VAR": "1",
+	}
+
+	// act
+	args := envArgs(env)
+
+	// assert
+	expected := []string{"-e", "A_VAR=1", "-e", "B_VAR=two words"}
+	if strings.Join(args, "|") != strings.Join(expected, "|") {
+		t.Errorf("envArgs = %v, want %v", args, expected)
+	}
+}
+
+func TestParseContainersOutput__worktree_label(t *testing.T) {
+	// arrange
+	output := "abc123def456\tmy-project-agentbox-run-1\t2 hours ago\tfeature-x\n" +
+		"789xyz000111\tmy-project-agentbox-run-2\t5 minutes ago\t"
+
+	// act
+	containers := parseContainersOutput(output)
+
+	// assert
+	expected := []Container{
+		{ID: "abc123def456", Name: "my-project-agentbox-run-1", Started: "2 hours ago", Worktree: "feature-x"},
+		{ID: "789xyz000111", Name: "my-project-agentbox-run-2", Started: "5 minutes ago"},
+	}
+
+	if len(containers) != len(expected) {
+		t.Fatalf("len(containers) = %d, want %d", len(containers), len(expected))
+	}
+
+	for i, c := range containers {
+		if c != expected[i] {
+			t.Errorf("containers[%d] = %+v, want %+v", i, c, expected[i])
+		}
+	}
+}
+
+func TestParseContainersOutput__session_labels(t *testing.T) {
+	// arrange
+	output := "abc123def456\treview\t2 hours ago\t\treview\t/home/user/app\tapp-agentbox\tUp 2 hours\t0.0.0.0:3000->3000/tcp, :::3000->3000/tcp\n" +
+		"789xyz000111\tapp-agentbox-run-2\t5 minutes ago\tfeature-x\t\t/home/user/app"
+
+	// act
+	containers := parseContainersOutput(output)
+
+	// assert
+	expected := []Container{
+		{ID: "abc123def456", Name: "review", Started: "2 hours ago", Session: "review", ProjectDir: "/home/user/app",
+			Image: "app-agentbox", Status: "Up 2 hours", Ports: "3000->3000/tcp"},
+		{ID: "789xyz000111", Name: "app-agentbox-run-2", Started: "5 minutes ago", Worktree: "feature-x", ProjectDir: "/home/user/app"},
+	}
+	if !slices.Equal(containers, expected) {
+		t.Errorf("containers = %+v, want %+v", containers, expected)
+	}
+}
+
+func TestLabelArgs(t *testing.T) {
+	// arrange
+	labels := map[string]string{
+		LabelWorktree: "feature-x",
+		"a.label":     "1",
+	}
+
+	// act
+	args := labelArgs(labels)
+
+	// assert
+	expected := []string{"--label", "a.label=1", "--label", "agentbox.worktree=feature-x"}
+	if strings.Join(args, "|") != strings.Join(expected, "|") {
+		t.Errorf("labelArgs = %v, want %v", args, expected)
+	}
+}
+
+func TestComposeArgs(t *testing.T) {
+	// act
+	args := composeArgs([]string{"/tmp/override.yml"})
+
+	// assert
+	expected := []string{
+		"-f", "docker-compose.agentbox.yml",
+		"-f", "docker-compose.agentbox.local.yml",
+		"-f", "/tmp/override.yml",
+	}
+	if strings.Join(args, "|") != strings.Join(expected, "|") {
+		t.Errorf("composeArgs = %v, want %v", args, expected)
+	}
+}
+
+func TestAttachArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     AttachOptions
+		expected []string
+	}{
+		{"default shell", AttachOptions{}, []string{"exec", "-it", "abc123", "/bin/bash"}},
+		{
+			"command as user in workdir",
+			AttachOptions{Command: []string{"/opt/agentbox/launchers/claude", "--resume"}, User: "root", WorkDir: "/tmp"},
+			[]string{"exec", "-it", "--user", "root", "--workdir", "/tmp", "abc123", "/opt/agentbox/launchers/claude", "--resume"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			args := attachArgs("abc123", tt.opts)
+
+			// assert
+			if strings.Join(args, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("attachArgs = %v, want %v", args, tt.expected)
+			}
+		})
+	}
+}
+
+func TestContainerExitCode(t *testing.T) {
+	ran := ContainerDetails{}
+	ran.State.StartedAt = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	ran.State.ExitCode = 3
+	tests := []struct {
+		name     string
+		details  ContainerDetails
+		err      error
+		expected int
+		ok       bool
+	}{
+		{"ran", ran, nil, 3, true},
+		{"created but not started", ContainerDetails{}, nil, 0, false},
+		{"not created", ContainerDetails{}, errors.New("no such container"), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			code, ok := containerExitCode(tt.details, tt.err)
+
+			// assert
+			if code != tt.expected || ok != tt.ok {
+				t.Errorf("containerExitCode() = %d, %v, want %d, %v", code, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+const (
+	// BaseRepository is the repository of the base image shared by all projects.
+	BaseRepository = "agentbox-base"
+	// BaseImageArg is the build argument of the project Dockerfile naming the base image.
+	BaseImageArg = "AGENTBOX_BASE_IMAGE"
+	// ToolsArg is the build argument of the project Dockerfile listing extra mise tools,
+	// such as "node@22 python@3.12".
+	ToolsArg = "AGENTBOX_TOOLS"
+	// LabelVersion is set on base images to the agentbox version that built them.
+	LabelVersion = "agentbox.version"
+)
+
+var invalidTagChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// BaseImage returns the base image tag for an agentbox version, e.g. "agentbox-base:1.4.0".
+func BaseImage(version string) string {
+	tag := invalidTagChars.ReplaceAllString(strings.TrimPrefix(version, "v"), "-")
+	if tag == "" || tag[0] == '.' || tag[0] == '-' {
+		tag = "v" + tag
+	}
+	return BaseRepository + ":" + tag
+}
+
+// Image is a local image.
+type Image struct {
+	ID         string
+	Repository string
+	Tag        string
+	Created    string
+	Size       string
+}
+
+// Ref returns the image reference, repository:tag.
+func (i Image) Ref() string {
+	return i.Repository + ":" + i.Tag
+}
+
+// ImageBuildOptions configures BuildImage.
+type ImageBuildOptions struct {
+	// Tags name the image, the first one is used in messages.
+	Tags   []string
+	Labels map[string]string
+	// Dockerfile is the path of the Dockerfile, inside the context by default.
+	Dockerfile string
+	NoCache    bool
+}
+
+// BuildImage builds an image from contextDir, streaming the build output.
+func BuildImage(contextDir string, opts ImageBuildOptions) error {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return err
+	}
+
+	cmd := rt.Command(context.Background(), buildArgs(contextDir, opts)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s build: %w", rt.Name(), err)
+	}
+	return nil
+}
+
+func buildArgs(contextDir string, opts ImageBuildOptions) []string {
+	args := []string{"build"}
+	for _, tag := range opts.Tags {
+		args = append(args, "--tag", tag)
+	}
+	args = append(args, labelArgs(opts.Labels)...)
+	if opts.Dockerfile != "" {
+		args = append(args, "--file", opts.Dockerfile)
+	}
+	if opts.NoCache {
+		args = append(args, "--no-cache")
+	}
+	return append(args, contextDir)
+}
+
+// ImageExists reports whether an image is present locally.
+func ImageExists(ref string) (bool, error) {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return false, err
+	}
+
+	out, err := rt.Command(context.Background(), "image", "inspect", "--format", "{{.Id}}", ref).CombinedOutput()
+	if err == nil {
+		return true, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && isNoSuchImage(string(out)) {
+		return false, nil
+	}
+	return false, fmt.Errorf("%s image inspect: %w: %s", rt.Name(), err, strings.TrimSpace(string(out)))
+}
+
+// isNoSuchImage reports whether inspect output says the image does not exist,
+// as opposed to the daemon being unreachable.
+func isNoSuchImage(output string) bool {
+	output = strings.ToLower(output)
+	return strings.Contains(output, "no such image") || strings.Contains(output, "image not known") ||
+		strings.Contains(output, "no such object")
+}
+
+// ListImages returns the local images of a repository.
+func ListImages(repository string) ([]Image, error) {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := rt.Command(context.Background(), "images",
+		"--filter", "reference="+repository,
+		"--format", "{{.ID}}\t{{.Repository}}\t{{.Tag}}\t{{.CreatedSince}}\t{{.Size}}",
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s images: %w", rt.Name(), err)
+	}
+	return parseImagesOutput(string(out)), nil
+}
+
+func parseImagesOutput(output string) []Image {
+	var images []Image
+	for line := range strings.SplitSeq(strings.TrimSpace(output), "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) != 5 {
+			continue
+		}
+		images = append(images, Image{
+			ID:         parts[0],
+			Repository: parts[1],
+			Tag:        parts[2],
+			Created:    parts[3],
+			Size:       parts[4],
+		})
+	}
+	return images
+}
+
+// RemoveImages removes images by reference. Removing a tag of an image that
+// has other tags only untags it.
+func RemoveImages(refs ...string) error {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{"rmi"}, refs...)
+	if out, err := rt.Command(context.Background(), args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s rmi: %w: %s", rt.Name(), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
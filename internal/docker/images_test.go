@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBaseImage(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected string
+	}{
+		{"1.4.0", "agentbox-base:1.4.0"},
+		{"v1.4.0", "agentbox-base:1.4.0"},
+		{"1.4.0+dirty", "agentbox-base:1.4.0-dirty"},
+		{"dev", "agentbox-base:dev"},
+		{"", "agentbox-base:v"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			// act
+			result := BaseImage(tt.version)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("BaseImage(%q) = %q, want %q", tt.version, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	// arrange
+	opts := ImageBuildOptions{
+		Tags:       []string{"agentbox-base:1.4.0", "agentbox-base:latest"},
+		Labels:     map[string]string{LabelVersion: "1.4.0"},
+		Dockerfile: "/tmp/ctx/Dockerfile",
+		NoCache:    true,
+	}
+
+	// act
+	result := buildArgs("/tmp/ctx", opts)
+
+	// assert
+	expected := []string{
+		"build",
+		"--tag", "agentbox-base:1.4.0",
+		"--tag", "agentbox-base:latest",
+		"--label", "agentbox.version=1.4.0",
+		"--file", "/tmp/ctx/Dockerfile",
+		"--no-cache",
+		"/tmp/ctx",
+	}
+	if !slices.Equal(result, expected) {
+		t.Errorf("buildArgs() = %v, want %v", result, expected)
+	}
+}
+
+func TestParseImagesOutput(t *testing.T) {
+	// arrange
+	output := "3f2a9c1b0d4e\tagentbox-base\t1.4.0\t2 days ago\t1.2GB\n" +
+		"3f2a9c1b0d4e\tagentbox-base\tlatest\t2 days ago\t1.2GB\n" +
+		"malformed line\n"
+
+	// act
+	result := parseImagesOutput(output)
+
+	// assert
+	expected := []Image{
+		{ID: "3f2a9c1b0d4e", Repository: "agentbox-base", Tag: "1.4.0", Created: "2 days ago", Size: "1.2GB"},
+		{ID: "3f2a9c1b0d4e", Repository: "agentbox-base", Tag: "latest", Created: "2 days ago", Size: "1.2GB"},
+	}
+	if !slices.Equal(result, expected) {
+		t.Errorf("parseImagesOutput() = %v, want %v", result, expected)
+	}
+}
+
+func TestParseImagesOutput__empty(t *testing.T) {
+	// act
+	result := parseImagesOutput("")
+
+	// assert
+	if len(result) != 0 {
+		t.Errorf("parseImagesOutput(\"\") = %v, want empty", result)
+	}
+}
+
+func TestIsNoSuchImage(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected bool
+	}{
+		{"Error: No such image: agentbox-base:1.4.0", true},
+		{"Error: agentbox-base:1.4.0: image not known", true},
+		{"Cannot connect to the Docker daemon at unix:///var/run/docker.sock", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			// act
+			result := isNoSuchImage(tt.output)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("isNoSuchImage(%q) = %v, want %v", tt.output, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Stop stops containers. Docker kills them if they do not exit within its grace period.
+func Stop(ids ...string) error {
+	return containerAction("stop", ids, func(ctx context.Context, client *Client, id string) error {
+		return client.Stop(ctx, id, -1)
+	})
+}
+
+// Kill kills containers immediately.
+func Kill(ids ...string) error {
+	return containerAction("kill", ids, func(ctx context.Context, client *Client, id string) error {
+		return client.Kill(ctx, id)
+	})
+}
+
+// Restart restarts containers. Terminal sessions attached to them end.
+func Restart(ids ...string) error {
+	return containerAction("restart", ids, func(ctx context.Context, client *Client, id string) error {
+		return client.Restart(ctx, id, -1)
+	})
+}
+
+// Inspect returns details of a container.
+func Inspect(id string) (ContainerDetails, error) {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return ContainerDetails{}, err
+	}
+
+	if client := apiClient(); client != nil {
+		details, err := client.Inspect(context.Background(), id)
+		if err != nil {
+			return ContainerDetails{}, fmt.Errorf("inspect %s: %w", id, err)
+		}
+		return details, nil
+	}
+
+	cmd := rt.Command(context.Background(), "inspect", "--type", "container", id)
+	out, err := cmd.Output()
+	if err != nil {
+		return ContainerDetails{}, fmt.Errorf("%s inspect: %w", rt.Name(), err)
+	}
+	var details []ContainerDetails
+	if err := json.Unmarshal(out, &details); err != nil {
+		return ContainerDetails{}, fmt.Errorf("parse %s inspect: %w", rt.Name(), err)
+	}
+	if len(details) == 0 {
+		return ContainerDetails{}, fmt.Errorf("no such container: %s", id)
+	}
+	return details[0], nil
+}
+
+// Logs writes the output of a container, following it until it exits if follow is set.
+func Logs(id string, follow bool, stdout, stderr io.Writer) error {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"logs"}
+	if follow {
+		args = append(args, "--follow")
+	}
+	args = append(args, id)
+
+	cmd := rt.Command(context.Background(), args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s logs: %w", rt.Name(), err)
+	}
+	return nil
+}
+
+// containerAction applies action to each container through the Engine API,
+// or runs the CLI command if the socket is not reachable.
+func containerAction(command string, ids []string, action func(ctx context.Context, client *Client, id string) error) error {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return err
+	}
+
+	client := apiClient()
+	if client == nil {
+		return containerCommand(rt, command, ids)
+	}
+
+	ctx := context.Background()
+	for _, id := range ids {
+		if err := action(ctx, client, id); err != nil {
+			return fmt.Errorf("%s %s: %w", command, id, err)
+		}
+	}
+	return nil
+}
+
+func containerCommand(rt Runtime, action string, ids []string) error {
+	args := append([]string{action}, ids...)
+	cmd := rt.Command(context.Background(), args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", rt.Name(), action, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// fullIDLength is the length of a full container ID, Container.ID is shortened.
+const fullIDLength = 64
+
+// FindContainer returns the container whose name or session name is id, or
+// whose ID starts with id. A full ID matches its shortened ID. As with docker,
+// a prefix of several IDs is an error.
+func FindContainer(containers []Container, id string) (Container, bool, error) {
+	if id == "" {
+		return Container{}, false, nil
+	}
+	for _, c := range containers {
+		if c.Name == id || c.Session == id {
+			return c, true, nil
+		}
+	}
+
+	var matches []Container
+	for _, c := range containers {
+		if strings.HasPrefix(c.ID, id) || (len(id) == fullIDLength && strings.HasPrefix(id, c.ID)) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Container{}, false, nil
+	case 1:
+		return matches[0], true, nil
+	default:
+		return Container{}, false, fmt.Errorf("ambiguous id %q matches %d containers", id, len(matches))
+	}
+}
@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindContainer(t *testing.T) {
+	containers := []Container{
+		{ID: "abc123def456", Name: "app-agentbox-run-1f2e3d"},
+		{ID: "789abc012def", Name: "app-agentbox-run-4a5b6c"},
+		{ID: "456def789abc", Name: "review", Session: "review"},
+		{ID: "abc999000111", Name: "app-agentbox-run-7d8e9f"},
+	}
+
+	tests := []struct {
+		name     string
+		id       string
+		expected string
+		found    bool
+		wantErr  bool
+	}{
+		{"short id", "abc123def456", "abc123def456", true, false},
+		{"id prefix", "789a", "789abc012def", true, false},
+		{"full id", "abc123def456" + strings.Repeat("0", 52), "abc123def456", true, false},
+		{"longer than short id", "abc123def4567890abcdef", "", false, false},
+		{"ambiguous prefix", "abc", "", false, true},
+		{"name", "app-agentbox-run-4a5b6c", "789abc012def", true, false},
+		{"session", "review", "456def789abc", true, false},
+		{"unknown", "fff", "", false, false},
+		{"empty", "", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			c, found, err := FindContainer(containers, tt.id)
+
+			// assert
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FindContainer(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+			if found != tt.found || c.ID != tt.expected {
+				t.Errorf("FindContainer(%q) = %q, %v; want %q, %v", tt.id, c.ID, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
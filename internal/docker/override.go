@@ -0,0 +1,250 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OverrideFile returns the name of the compose file generated for a session
+// in the project state dir, so that parallel sessions do not share it.
+func OverrideFile(session string) string {
+	return "docker-compose.override." + session + ".yml"
+}
+
+// Override is a compose file generated by agentbox on every run and layered
+// on top of the project compose files. It keeps runtime settings out of the
+// files users edit.
+type Override struct {
+	// Volumes are merged with the service volumes by container path,
+	// so a volume with the same target replaces the project one.
+	Volumes []string
+	// Tmpfs lists container paths where empty tmpfs filesystems are mounted.
+	Tmpfs []string
+	// Environment is merged with the service environment.
+	Environment map[string]string
+	// Ports are published when the service is run with its ports, see RunOptions.ServicePorts.
+	Ports []string
+	// ExtraHosts are added to /etc/hosts of the agentbox service, see HostGateway.
+	ExtraHosts []string
+	// CapDrop lists the capabilities dropped from the agentbox service.
+	CapDrop []string
+	// SecurityOpt are security options of the agentbox service, such as no-new-privileges.
+	SecurityOpt []string
+	// Networks replaces the networks of the agentbox service,
+	// detaching it from the project's default network. Replacing needs
+	// the !override tag, see CheckOverrideTag.
+	Networks []string
+	// DependsOn lists services started before the agentbox service.
+	DependsOn []string
+	// Resources limits the agentbox service.
+	Resources Resources
+	// UsernsMode is the user namespace mode of the agentbox service, such as podman's keep-id.
+	UsernsMode string
+	// BuildArgs are passed to the build of the agentbox image, such as the base image.
+	BuildArgs map[string]string
+	// BuildLabels are set on the agentbox image, such as the hash of its build inputs.
+	BuildLabels map[string]string
+	// Services are additional services, such as sidecars.
+	Services []Service
+	// InternalNetworks are declared without access to outside networks.
+	InternalNetworks []string
+}
+
+// overrideTagVersion is the first compose version that supports the !override tag.
+var overrideTagVersion = [3]int{2, 24, 4}
+
+// ParseComposeVersion returns the major, minor and patch version from the output
+// of "compose version --short", such as "v2.27.0", "1.29.2" or "2.24.6-desktop.1".
+func ParseComposeVersion(output string) (version [3]int, ok bool) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return version, false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(fields[0], "v"), ".", 3)
+	if len(parts) < 2 {
+		return version, false
+	}
+	for i, part := range parts {
+		// pre-release and build suffixes, such as "6-desktop.1"
+		part, _, _ = strings.Cut(part, "-")
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return version, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
+
+// SupportsOverrideTag reports whether the compose version, given as the output
+// of "compose version --short", supports the !override tag used by Networks.
+func SupportsOverrideTag(output string) bool {
+	version, ok := ParseComposeVersion(output)
+	return ok && slices.Compare(version[:], overrideTagVersion[:]) >= 0
+}
+
+// CheckOverrideTag returns an error if the compose of rt does not support the
+// !override tag. Older versions, docker-compose v1 and podman-compose merge
+// the networks instead, which would keep the sandbox on the default network.
+func CheckOverrideTag(ctx context.Context, rt Runtime) error {
+	out, err := rt.ComposeCommand(ctx, "version", "--short").Output()
+	if err != nil {
+		return fmt.Errorf("check %s version: %w", rt.ComposeName(), err)
+	}
+	if !SupportsOverrideTag(string(out)) {
+		return fmt.Errorf("%s %s is not supported, Docker Compose %d.%d.%d or newer is required",
+			rt.ComposeName(), strings.TrimSpace(string(out)), overrideTagVersion[0], overrideTagVersion[1], overrideTagVersion[2])
+	}
+	return nil
+}
+
+// Service is an additional compose service.
+type Service struct {
+	Name     string
+	Image    string
+	Volumes  []string
+	Networks []string
+}
+
+// Render returns the override as compose YAML.
+func (o *Override) Render() []byte {
+	var b strings.Builder
+	b.WriteString("# Generated by agentbox, do not edit.\n")
+	b.WriteString("services:\n")
+	b.WriteString("  agentbox:\n")
+
+	empty := true
+	if len(o.Volumes) > 0 {
+		writeList(&b, 4, "volumes", o.Volumes)
+		empty = false
+	}
+	if len(o.Tmpfs) > 0 {
+		writeList(&b, 4, "tmpfs", o.Tmpfs)
+		empty = false
+	}
+	if len(o.Environment) > 0 {
+		writeMap(&b, 4, "environment", o.Environment)
+		empty = false
+	}
+	if len(o.Ports) > 0 {
+		writeList(&b, 4, "ports", o.Ports)
+		empty = false
+	}
+	if len(o.ExtraHosts) > 0 {
+		writeList(&b, 4, "extra_hosts", o.ExtraHosts)
+		empty = false
+	}
+	if len(o.CapDrop) > 0 {
+		writeList(&b, 4, "cap_drop", o.CapDrop)
+		empty = false
+	}
+	if len(o.SecurityOpt) > 0 {
+		writeList(&b, 4, "security_opt", o.SecurityOpt)
+		empty = false
+	}
+	if len(o.Networks) > 0 {
+		// !override replaces the list instead of merging it with the project one
+		b.WriteString("    networks: !override\n")
+		writeItems(&b, 4, o.Networks)
+		empty = false
+	}
+	if len(o.DependsOn) > 0 {
+		writeList(&b, 4, "depends_on", o.DependsOn)
+		empty = false
+	}
+	if o.Resources.CPUs > 0 {
+		fmt.Fprintf(&b, "    cpus: %s\n", strconv.FormatFloat(o.Resources.CPUs, 'f', -1, 64))
+		empty = false
+	}
+	if o.Resources.Memory > 0 {
+		fmt.Fprintf(&b, "    mem_limit: %d\n", o.Resources.Memory)
+		empty = false
+	}
+	if o.Resources.PidsLimit > 0 {
+		fmt.Fprintf(&b, "    pids_limit: %d\n", o.Resources.PidsLimit)
+		empty = false
+	}
+	if o.UsernsMode != "" {
+		fmt.Fprintf(&b, "    userns_mode: %s\n", quote(o.UsernsMode))
+		empty = false
+	}
+	if len(o.BuildArgs) > 0 || len(o.BuildLabels) > 0 {
+		b.WriteString("    build:\n")
+		writeMap(&b, 6, "args", o.BuildArgs)
+		writeMap(&b, 6, "labels", o.BuildLabels)
+		empty = false
+	}
+	if empty {
+		b.WriteString("    {}\n")
+	}
+
+	for _, svc := range o.Services {
+		fmt.Fprintf(&b, "  %s:\n", svc.Name)
+		fmt.Fprintf(&b, "    image: %s\n", quote(svc.Image))
+		if len(svc.Volumes) > 0 {
+			writeList(&b, 4, "volumes", svc.Volumes)
+		}
+		if len(svc.Networks) > 0 {
+			writeList(&b, 4, "networks", svc.Networks)
+		}
+	}
+
+	if len(o.InternalNetworks) > 0 {
+		b.WriteString("networks:\n")
+		for _, name := range o.InternalNetworks {
+			fmt.Fprintf(&b, "  %s:\n", name)
+			b.WriteString("    internal: true\n")
+		}
+	}
+
+	return []byte(b.String())
+}
+
+// WriteOverride renders the override of a session into dir and returns the file path.
+func WriteOverride(dir, session string, o *Override) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create dir %s: %w", dir, err)
+	}
+
+	path := filepath.Join(dir, OverrideFile(session))
+	if err := os.WriteFile(path, o.Render(), 0o644); err != nil {
+		return "", fmt.Errorf("write compose override: %w", err)
+	}
+	return path, nil
+}
+
+func writeList(b *strings.Builder, indent int, key string, items []string) {
+	fmt.Fprintf(b, "%s%s:\n", strings.Repeat(" ", indent), key)
+	writeItems(b, indent, items)
+}
+
+// writeMap writes a sorted mapping, nothing if it is empty.
+func writeMap(b *strings.Builder, indent int, key string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	pad := strings.Repeat(" ", indent)
+	fmt.Fprintf(b, "%s%s:\n", pad, key)
+	for _, k := range slices.Sorted(maps.Keys(values)) {
+		fmt.Fprintf(b, "%s  %s: %s\n", pad, k, quote(values[k]))
+	}
+}
+
+func writeItems(b *strings.Builder, indent int, items []string) {
+	pad := strings.Repeat(" ", indent)
+	for _, item := range items {
+		fmt.Fprintf(b, "%s  - %s\n", pad, quote(item))
+	}
+}
+
+// quote returns s as a double-quoted YAML scalar.
+// JSON string escaping is a valid subset of YAML double-quoted style.
+func quote(s string) string {
+	return strconv.Quote(s)
+}
@@ -0,0 +1,274 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOverride_Render(t *testing.T) {
+	// arrange
+	override := &Override{
+		Volumes: []string{
+			"/home/user/.agentbox/worktrees/app-1a2b3c4d/feature-x:/home/box/app",
+			`/path with "quotes":/data`,
+		},
+	}
+
+	// act
+	result := string(override.Render())
+
+	// assert
+	expected := `# Generated by agentbox, do not edit.
+services:
+  agentbox:
+    volumes:
+      - "/home/user/.agentbox/worktrees/app-1a2b3c4d/feature-x:/home/box/app"
+      - "/path with \"quotes\":/data"
+`
+	if result != expected {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestOverride_Render__empty(t *testing.T) {
+	// arrange
+	override := &Override{}
+
+	// act
+	result := string(override.Render())
+
+	// assert
+	expected := `# Generated by agentbox, do not edit.
+services:
+  agentbox:
+    {}
+`
+	if result != expected {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestOverride_Render__tmpfs(t *testing.T) {
+	// arrange
+	override := &Override{
+		Volumes: []string{"/state/ignore/empty:/home/box/app/.env:ro"},
+		Tmpfs:   []string{"/home/box/app/secrets"},
+	}
+
+	// act
+	result := string(override.Render())
+
+	// assert
+	expected := `# Generated by agentbox, do not edit.
+services:
+  agentbox:
+    volumes:
+      - "/state/ignore/empty:/home/box/app/.env:ro"
+    tmpfs:
+      - "/home/box/app/secrets"
+`
+	if result != expected {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestOverride_Render__project_config(t *testing.T) {
+	// arrange
+	override := &Override{
+		Environment: map[string]string{"NODE_ENV": "development", "DEBUG": "app:*"},
+		Ports:       []string{"127.0.0.1:3000:3000"},
+		ExtraHosts:  []string{HostGateway},
+		CapDrop:     []string{"ALL"},
+		SecurityOpt: []string{"no-new-privileges:true"},
+	}
+
+	// act
+	result := string(override.Render())
+
+	// assert
+	expected := `# Generated by agentbox, do not edit.
+services:
+  agentbox:
+    environment:
+      DEBUG: "app:*"
+      NODE_ENV: "development"
+    ports:
+      - "127.0.0.1:3000:3000"
+    extra_hosts:
+      - "host.docker.internal:host-gateway"
+    cap_drop:
+      - "ALL"
+    security_opt:
+      - "no-new-privileges:true"
+`
+	if result != expected {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestOverride_Render__resources(t *testing.T) {
+	// arrange
+	override := &Override{
+		Resources: Resources{CPUs: 1.5, Memory: 4 << 30, PidsLimit: 512},
+	}
+
+	// act
+	result := string(override.Render())
+
+	// assert
+	expected := `# Generated by agentbox, do not edit.
+services:
+  agentbox:
+    cpus: 1.5
+    mem_limit: 4294967296
+    pids_limit: 512
+`
+	if result != expected {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestOverride_Render__userns(t *testing.T) {
+	// arrange
+	override := &Override{UsernsMode: "keep-id:uid=1000,gid=1000"}
+
+	// act
+	result := string(override.Render())
+
+	// assert
+	expected := `# Generated by agentbox, do not edit.
+services:
+  agentbox:
+    userns_mode: "keep-id:uid=1000,gid=1000"
+`
+	if result != expected {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestOverride_Render__build_args(t *testing.T) {
+	// arrange
+	override := &Override{
+		BuildArgs: map[string]string{
+			"B_ARG":      "2",
+			BaseImageArg: "agentbox-base:1.4.0",
+		},
+		BuildLabels: map[string]string{LabelBuildHash: "3f2a"},
+	}
+
+	// act
+	result := string(override.Render())
+
+	// assert
+	expected := `# Generated by agentbox, do not edit.
+services:
+  agentbox:
+    build:
+      args:
+        AGENTBOX_BASE_IMAGE: "agentbox-base:1.4.0"
+        B_ARG: "2"
+      labels:
+        agentbox.build.hash: "3f2a"
+`
+	if result != expected {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestOverride_Render__sidecar(t *testing.T) {
+	// arrange
+	override := &Override{
+		Networks:  []string{"agentbox-egress"},
+		DependsOn: []string{"egress"},
+		Services: []Service{
+			{
+				Name:     "egress",
+				Image:    "ubuntu/squid:latest",
+				Volumes:  []string{"/state/squid.conf:/etc/squid/squid.conf:ro"},
+				Networks: []string{"agentbox-egress", "default"},
+			},
+		},
+		InternalNetworks: []string{"agentbox-egress"},
+	}
+
+	// act
+	result := string(override.Render())
+
+	// assert
+	expected := `# Generated by agentbox, do not edit.
+services:
+  agentbox:
+    networks: !override
+      - "agentbox-egress"
+    depends_on:
+      - "egress"
+  egress:
+    image: "ubuntu/squid:latest"
+    volumes:
+      - "/state/squid.conf:/etc/squid/squid.conf:ro"
+    networks:
+      - "agentbox-egress"
+      - "default"
+networks:
+  agentbox-egress:
+    internal: true
+`
+	if result != expected {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestWriteOverride(t *testing.T) {
+	// arrange
+	dir := filepath.Join(t.TempDir(), "state")
+	override := &Override{Volumes: []string{"/src:/dst"}}
+
+	// act
+	path, err := WriteOverride(dir, "feature-1", override)
+
+	// assert
+	if err != nil {
+		t.Fatalf("WriteOverride error: %v", err)
+	}
+	if want := filepath.Join(dir, "docker-compose.override.feature-1.yml"); path != want {
+		t.Errorf("path = %s, want %s", path, want)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(override.Render()) {
+		t.Errorf("file content = %s, want rendered override", content)
+	}
+}
+
+func TestSupportsOverrideTag(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"v2.27.0\n", true},
+		{"2.24.4", true},
+		{"2.24.6-desktop.1", true},
+		{"2.24.3", false},
+		{"3.0.1", true},
+		{"2.23.3", false},
+		{"v2.0.1", false},
+		{"1.29.2", false},
+		{"", false},
+		{"unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			// act
+			result := SupportsOverrideTag(tt.output)
+
+			// assert
+			if result != tt.want {
+				t.Errorf("SupportsOverrideTag(%q) = %v, want %v", tt.output, result, tt.want)
+			}
+		})
+	}
+}
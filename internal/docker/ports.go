@@ -0,0 +1,33 @@
+package docker
+
+import (
+	"slices"
+	"strings"
+)
+
+// HostGateway is the extra host that resolves host.docker.internal to the host,
+// which Docker Engine on Linux does not do by itself.
+const HostGateway = "host.docker.internal:host-gateway"
+
+// allInterfaces are the prefixes of ports published on all host addresses.
+var allInterfaces = []string{"0.0.0.0:", ":::", "[::]:"}
+
+// compactPorts shortens the ports of a container as listed by docker ps, e.g.
+// "0.0.0.0:3000->3000/tcp, :::3000->3000/tcp", to the published ones, e.g.
+// "3000->3000/tcp". Ports bound to all addresses are shown without the address.
+func compactPorts(ports string) string {
+	var result []string
+	for _, port := range strings.Split(ports, ",") {
+		port = strings.TrimSpace(port)
+		if !strings.Contains(port, "->") {
+			continue
+		}
+		for _, prefix := range allInterfaces {
+			port = strings.TrimPrefix(port, prefix)
+		}
+		if !slices.Contains(result, port) {
+			result = append(result, port)
+		}
+	}
+	return strings.Join(result, ", ")
+}
@@ -0,0 +1,27 @@
+package docker
+
+import "testing"
+
+func TestCompactPorts(t *testing.T) {
+	tests := []struct {
+		ports    string
+		expected string
+	}{
+		{"", ""},
+		{"0.0.0.0:3000->3000/tcp, :::3000->3000/tcp", "3000->3000/tcp"},
+		{"127.0.0.1:8080->80/tcp, 5432/tcp", "127.0.0.1:8080->80/tcp"},
+		{"[::]:53->53/udp", "53->53/udp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ports, func(t *testing.T) {
+			// act
+			result := compactPorts(tt.ports)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("compactPorts(%q) = %q, want %q", tt.ports, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// minMemory is the smallest memory limit Docker accepts.
+const minMemory = 6 * 1024 * 1024
+
+// Resources limits the resources of the sandbox container. Zero values mean no limit.
+type Resources struct {
+	// CPUs is the number of CPUs the container may use, e.g. 1.5.
+	CPUs float64
+	// Memory is the memory limit in bytes.
+	Memory int64
+	// PidsLimit is the maximum number of processes in the container.
+	PidsLimit int64
+}
+
+// IsZero reports whether no limit is set.
+func (r Resources) IsZero() bool {
+	return r == Resources{}
+}
+
+// ParseCPUs parses a number of CPUs, such as "2" or "0.5".
+func ParseCPUs(s string) (float64, error) {
+	cpus, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || cpus <= 0 || math.IsInf(cpus, 0) {
+		return 0, fmt.Errorf("invalid cpus %q: must be a positive number", s)
+	}
+	return cpus, nil
+}
+
+// ParsePidsLimit parses a maximum number of processes.
+func ParsePidsLimit(s string) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid pids limit %q: must be a positive integer", s)
+	}
+	return n, nil
+}
+
+// HostInfo describes what the Docker host supports, as reported by docker info.
+type HostInfo struct {
+	NCPU        int   `json:"NCPU"`
+	MemTotal    int64 `json:"MemTotal"`
+	CPUCfsQuota bool  `json:"CpuCfsQuota"`
+	MemoryLimit bool  `json:"MemoryLimit"`
+	PidsLimit   bool  `json:"PidsLimit"`
+}
+
+// Info returns the resources and cgroup features of the container host.
+func Info() (HostInfo, error) {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return HostInfo{}, err
+	}
+
+	if client := apiClient(); client != nil {
+		info, err := client.Info(context.Background())
+		if err != nil {
+			return HostInfo{}, fmt.Errorf("%s info: %w", rt.Name(), err)
+		}
+		return info, nil
+	}
+	return rt.Info(context.Background())
+}
+
+// Validate checks that the host can apply the limits.
+func (r Resources) Validate(host HostInfo) error {
+	var errs []error
+	if r.CPUs > 0 {
+		if !host.CPUCfsQuota {
+			errs = append(errs, errors.New("the Docker host does not support CPU limits (CFS quota)"))
+		} else if host.NCPU > 0 && r.CPUs > float64(host.NCPU) {
+			errs = append(errs, fmt.Errorf("cpus %g exceeds the %d CPUs available to Docker", r.CPUs, host.NCPU))
+		}
+	}
+	if r.Memory > 0 {
+		switch {
+		case !host.MemoryLimit:
+			errs = append(errs, errors.New("the Docker host does not support memory limits"))
+		case r.Memory < minMemory:
+			errs = append(errs, errors.New("memory must be at least 6m"))
+		case host.MemTotal > 0 && r.Memory > host.MemTotal:
+			errs = append(errs, fmt.Errorf("memory %s exceeds the %s available to Docker",
+				FormatMemory(r.Memory), FormatMemory(host.MemTotal)))
+		}
+	}
+	if r.PidsLimit > 0 && !host.PidsLimit {
+		errs = append(errs, errors.New("the Docker host does not support pids limits"))
+	}
+	return errors.Join(errs...)
+}
+
+// FormatMemory formats bytes in the largest whole Docker unit, such as "4g".
+func FormatMemory(bytes int64) string {
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10}} {
+		if bytes >= unit.size && bytes%unit.size == 0 {
+			return strconv.FormatInt(bytes/unit.size, 10) + unit.suffix
+		}
+	}
+	if bytes >= 1<<30 {
+		return strconv.FormatFloat(float64(bytes)/(1<<30), 'f', 1, 64) + "g"
+	}
+	if bytes >= 1<<20 {
+		return strconv.FormatFloat(float64(bytes)/(1<<20), 'f', 1, 64) + "m"
+	}
+	return strconv.FormatInt(bytes, 10) + "b"
+}
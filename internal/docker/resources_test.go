@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCPUs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		wantErr  bool
+	}{
+		{"2", 2, false},
+		{"0.5", 0.5, false},
+		{" 1.5 ", 1.5, false},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"two", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			// act
+			result, err := ParseCPUs(tt.input)
+
+			// assert
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCPUs(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("ParseCPUs(%q) = %g, want %g", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParsePidsLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{"512", 512, false},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"1.5", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			// act
+			result, err := ParsePidsLimit(tt.input)
+
+			// assert
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePidsLimit(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("ParsePidsLimit(%q) = %d, want %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResources_Validate(t *testing.T) {
+	host := HostInfo{NCPU: 8, MemTotal: 16 << 30, CPUCfsQuota: true, MemoryLimit: true, PidsLimit: true}
+
+	tests := []struct {
+		name      string
+		resources Resources
+		host      HostInfo
+		errPart   string
+	}{
+		{"no limits", Resources{}, HostInfo{}, ""},
+		{"within host", Resources{CPUs: 4, Memory: 8 << 30, PidsLimit: 512}, host, ""},
+		{"too many cpus", Resources{CPUs: 16}, host, "exceeds the 8 CPUs"},
+		{"too much memory", Resources{Memory: 32 << 30}, host, "memory 32g exceeds the 16g"},
+		{"too little memory", Resources{Memory: 1 << 20}, host, "at least 6m"},
+		{"no cpu support", Resources{CPUs: 1}, HostInfo{MemoryLimit: true}, "CPU limits"},
+		{"no memory support", Resources{Memory: 1 << 30}, HostInfo{CPUCfsQuota: true}, "memory limits"},
+		{"no pids support", Resources{PidsLimit: 100}, HostInfo{}, "pids limits"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			err := tt.resources.Validate(tt.host)
+
+			// assert
+			if tt.errPart == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestFormatMemory(t *testing.T) {
+	tests := []struct {
+		input    int64
+		expected string
+	}{
+		{4 << 30, "4g"},
+		{512 << 20, "512m"},
+		{64 << 10, "64k"},
+		{3 << 29, "1536m"},
+		{16_700_000_000, "15.6g"},
+		{100, "100b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			// act
+			result := FormatMemory(tt.input)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("FormatMemory(%d) = %s, want %s", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
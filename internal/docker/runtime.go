@@ -0,0 +1,285 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Container runtime names accepted by SelectRuntime.
+const (
+	RuntimeAuto   = "auto"
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// Runtimes returns the accepted runtime names.
+func Runtimes() []string {
+	return []string{RuntimeAuto, RuntimeDocker, RuntimePodman}
+}
+
+// containerUID is the uid of the box user in the sandbox image.
+const containerUID = 1000
+
+// Runtime is a container engine together with its compose implementation.
+type Runtime interface {
+	// Name is the engine name, "docker" or "podman".
+	Name() string
+	// Command returns an engine CLI command, such as "docker ps".
+	Command(ctx context.Context, args ...string) *exec.Cmd
+	// ComposeName is the compose command used in messages, e.g. "docker-compose".
+	ComposeName() string
+	// ComposeCommand returns a compose command, such as "docker compose run".
+	ComposeCommand(ctx context.Context, args ...string) *exec.Cmd
+	// LabelFormat returns the ps --format expression that prints a label.
+	LabelFormat(label string) string
+	// Socket returns the Engine API compatible socket, or "" if there is none.
+	Socket() string
+	// UsernsMode returns the compose userns_mode of the sandbox, "" for the default.
+	UsernsMode() string
+	// Info returns the host resources through the CLI.
+	Info(ctx context.Context) (HostInfo, error)
+}
+
+// engine is the part shared by the runtimes: the CLI binary and the compose command.
+type engine struct {
+	binary  string
+	compose []string
+}
+
+func (e engine) Command(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, e.binary, args...)
+}
+
+func (e engine) ComposeName() string {
+	return strings.Join(e.compose, " ")
+}
+
+func (e engine) ComposeCommand(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, e.compose[0], append(slices.Clone(e.compose[1:]), args...)...)
+}
+
+type dockerRuntime struct {
+	engine
+}
+
+func (r dockerRuntime) Name() string {
+	return RuntimeDocker
+}
+
+func (r dockerRuntime) LabelFormat(label string) string {
+	return fmt.Sprintf("{{.Label %q}}", label)
+}
+
+func (r dockerRuntime) Socket() string {
+	return SocketPath()
+}
+
+func (r dockerRuntime) UsernsMode() string {
+	return ""
+}
+
+func (r dockerRuntime) Info(ctx context.Context) (HostInfo, error) {
+	out, err := r.Command(ctx, "info", "--format", "{{json .}}").Output()
+	if err != nil {
+		return HostInfo{}, fmt.Errorf("docker info: %w", err)
+	}
+
+	var info HostInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		return HostInfo{}, fmt.Errorf("parse docker info: %w", err)
+	}
+	return info, nil
+}
+
+type podmanRuntime struct {
+	engine
+	rootless bool
+}
+
+func (r podmanRuntime) Name() string {
+	return RuntimePodman
+}
+
+func (r podmanRuntime) LabelFormat(label string) string {
+	// podman ps has no .Label function
+	return fmt.Sprintf("{{index .Labels %q}}", label)
+}
+
+func (r podmanRuntime) Socket() string {
+	return podmanSocketPath(r.rootless)
+}
+
+// UsernsMode maps the host user to the box user in rootless mode, so mounted
+// files keep their owner instead of appearing to belong to root.
+func (r podmanRuntime) UsernsMode() string {
+	if !r.rootless {
+		return ""
+	}
+	return fmt.Sprintf("keep-id:uid=%d,gid=%d", containerUID, containerUID)
+}
+
+func (r podmanRuntime) Info(ctx context.Context) (HostInfo, error) {
+	out, err := r.Command(ctx, "info", "--format", "json").Output()
+	if err != nil {
+		return HostInfo{}, fmt.Errorf("podman info: %w", err)
+	}
+	info, err := parsePodmanInfo(out)
+	if err != nil {
+		return HostInfo{}, fmt.Errorf("parse podman info: %w", err)
+	}
+	return info, nil
+}
+
+// parsePodmanInfo converts podman info output into the docker info fields.
+func parsePodmanInfo(data []byte) (HostInfo, error) {
+	var info struct {
+		Host struct {
+			CPUs              int      `json:"cpus"`
+			MemTotal          int64    `json:"memTotal"`
+			CgroupControllers []string `json:"cgroupControllers"`
+		} `json:"host"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return HostInfo{}, err
+	}
+
+	controllers := info.Host.CgroupControllers
+	return HostInfo{
+		NCPU:        info.Host.CPUs,
+		MemTotal:    info.Host.MemTotal,
+		CPUCfsQuota: slices.Contains(controllers, "cpu"),
+		MemoryLimit: slices.Contains(controllers, "memory"),
+		PidsLimit:   slices.Contains(controllers, "pids"),
+	}, nil
+}
+
+// podmanSocketPath returns the Docker compatible API socket of podman from
+// CONTAINER_HOST or the default locations, or "" if the service is not running.
+func podmanSocketPath(rootless bool) string {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		if path, ok := strings.CutPrefix(host, "unix://"); ok {
+			return path
+		}
+		return ""
+	}
+
+	path := "/run/podman/podman.sock"
+	if rootless {
+		dir := os.Getenv("XDG_RUNTIME_DIR")
+		if dir == "" {
+			return ""
+		}
+		path = filepath.Join(dir, "podman", "podman.sock")
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		return path
+	}
+	return ""
+}
+
+// detector looks up the installed tools. It is replaced in tests.
+type detector struct {
+	lookPath func(file string) (string, error)
+	// output runs a command and returns its stdout.
+	output func(name string, args ...string) (string, error)
+}
+
+var systemDetector = detector{
+	lookPath: exec.LookPath,
+	output: func(name string, args ...string) (string, error) {
+		out, err := exec.CommandContext(context.Background(), name, args...).Output()
+		return string(out), err
+	},
+}
+
+// detect returns the runtime with the given name, or the first installed one
+// for RuntimeAuto: docker, then podman.
+func (d detector) detect(name string) (Runtime, error) {
+	switch name {
+	case "", RuntimeAuto:
+		if _, err := d.lookPath("docker"); err == nil && !d.isPodmanShim() {
+			return d.docker(), nil
+		}
+		if _, err := d.lookPath("podman"); err == nil {
+			return d.podman("podman"), nil
+		}
+		// podman-docker installs a docker command that runs podman
+		if _, err := d.lookPath("docker"); err == nil {
+			return d.podman("docker"), nil
+		}
+		return nil, fmt.Errorf("no container runtime found, install Docker or Podman")
+	case RuntimeDocker:
+		if _, err := d.lookPath("docker"); err != nil {
+			return nil, fmt.Errorf("docker not found in PATH")
+		}
+		return d.docker(), nil
+	case RuntimePodman:
+		if _, err := d.lookPath("podman"); err != nil {
+			return nil, fmt.Errorf("podman not found in PATH")
+		}
+		return d.podman("podman"), nil
+	default:
+		return nil, fmt.Errorf("unknown runtime %q, expected one of: %s", name, strings.Join(Runtimes(), ", "))
+	}
+}
+
+func (d detector) isPodmanShim() bool {
+	out, err := d.output("docker", "--version")
+	return err == nil && strings.Contains(strings.ToLower(out), "podman")
+}
+
+// docker prefers the compose v2 plugin and falls back to docker-compose v1.
+func (d detector) docker() Runtime {
+	compose := []string{"docker", "compose"}
+	if _, err := d.output("docker", "compose", "version"); err != nil {
+		if _, err := d.lookPath("docker-compose"); err == nil {
+			compose = []string{"docker-compose"}
+		}
+	}
+	return dockerRuntime{engine{binary: "docker", compose: compose}}
+}
+
+// podman prefers "podman compose" and falls back to podman-compose.
+func (d detector) podman(binary string) Runtime {
+	compose := []string{binary, "compose"}
+	if _, err := d.output(binary, "compose", "version"); err != nil {
+		if _, err := d.lookPath("podman-compose"); err == nil {
+			compose = []string{"podman-compose"}
+		}
+	}
+	out, err := d.output(binary, "info", "--format", "{{.Host.Security.Rootless}}")
+	rootless := err == nil && strings.TrimSpace(out) == "true"
+	return podmanRuntime{engine: engine{binary: binary, compose: compose}, rootless: rootless}
+}
+
+var (
+	runtimeName    string
+	currentRuntime Runtime
+	runtimeErr     error
+	runtimeOnce    sync.Once
+)
+
+// SelectRuntime sets the runtime used by the package, see Runtimes. It must be
+// called before any container operation; by default the runtime is detected.
+func SelectRuntime(name string) error {
+	if name != "" && !slices.Contains(Runtimes(), name) {
+		return fmt.Errorf("unknown runtime %q, expected one of: %s", name, strings.Join(Runtimes(), ", "))
+	}
+	runtimeName = name
+	return nil
+}
+
+// CurrentRuntime returns the selected or detected runtime.
+func CurrentRuntime() (Runtime, error) {
+	runtimeOnce.Do(func() {
+		currentRuntime, runtimeErr = systemDetector.detect(runtimeName)
+	})
+	return currentRuntime, runtimeErr
+}
@@ -0,0 +1,205 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeDetector returns a detector that finds the given executables and
+// answers the given commands, all other commands fail.
+func fakeDetector(paths []string, outputs map[string]string) detector {
+	return detector{
+		lookPath: func(file string) (string, error) {
+			if slices.Contains(paths, file) {
+				return "/usr/bin/" + file, nil
+			}
+			return "", errors.New("not found")
+		},
+		output: func(name string, args ...string) (string, error) {
+			out, ok := outputs[strings.Join(append([]string{name}, args...), " ")]
+			if !ok {
+				return "", errors.New("exit status 1")
+			}
+			return out, nil
+		},
+	}
+}
+
+func TestDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name            string
+		runtime         string
+		paths           []string
+		outputs         map[string]string
+		expectedName    string
+		expectedCompose string
+		expectedUserns  string
+	}{
+		{
+			name:    "docker with compose plugin",
+			runtime: RuntimeAuto,
+			paths:   []string{"docker", "docker-compose"},
+			outputs: map[string]string{
+				"docker --version":       "Docker version 28.1.1",
+				"docker compose version": "Docker Compose version v2.35.1",
+			},
+			expectedName:    RuntimeDocker,
+			expectedCompose: "docker compose",
+		},
+		{
+			name:            "docker with compose v1",
+			runtime:         "",
+			paths:           []string{"docker", "docker-compose"},
+			outputs:         map[string]string{"docker --version": "Docker version 20.10.24"},
+			expectedName:    RuntimeDocker,
+			expectedCompose: "docker-compose",
+		},
+		{
+			name:    "rootless podman",
+			runtime: RuntimeAuto,
+			paths:   []string{"podman", "podman-compose"},
+			outputs: map[string]string{
+				"podman info --format {{.Host.Security.Rootless}}": "true\n",
+			},
+			expectedName:    RuntimePodman,
+			expectedCompose: "podman-compose",
+			expectedUserns:  "keep-id:uid=1000,gid=1000",
+		},
+		{
+			name:    "podman behind a docker command",
+			runtime: RuntimeAuto,
+			paths:   []string{"docker"},
+			outputs: map[string]string{
+				"docker --version":       "podman version 5.4.0",
+				"docker compose version": "docker-compose version 2.35.1",
+			},
+			expectedName:    RuntimePodman,
+			expectedCompose: "docker compose",
+		},
+		{
+			name:    "explicit podman",
+			runtime: RuntimePodman,
+			paths:   []string{"docker", "podman"},
+			outputs: map[string]string{
+				"podman compose version":                           "podman-compose version 1.3.0",
+				"podman info --format {{.Host.Security.Rootless}}": "false\n",
+			},
+			expectedName:    RuntimePodman,
+			expectedCompose: "podman compose",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			d := fakeDetector(tt.paths, tt.outputs)
+
+			// act
+			rt, err := d.detect(tt.runtime)
+
+			// assert
+			if err != nil {
+				t.Fatalf("detect error: %v", err)
+			}
+			if rt.Name() != tt.expectedName {
+				t.Errorf("Name() = %q, want %q", rt.Name(), tt.expectedName)
+			}
+			if rt.ComposeName() != tt.expectedCompose {
+				t.Errorf("ComposeName() = %q, want %q", rt.ComposeName(), tt.expectedCompose)
+			}
+			if rt.UsernsMode() != tt.expectedUserns {
+				t.Errorf("UsernsMode() = %q, want %q", rt.UsernsMode(), tt.expectedUserns)
+			}
+		})
+	}
+}
+
+func TestDetector_Detect__errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		runtime  string
+		paths    []string
+		expected string
+	}{
+		{"nothing installed", RuntimeAuto, nil, "no container runtime found, install Docker or Podman"},
+		{"explicit runtime missing", RuntimePodman, []string{"docker"}, "podman not found in PATH"},
+		{"unknown runtime", "lxc", []string{"docker"}, `unknown runtime "lxc", expected one of: auto, docker, podman`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			d := fakeDetector(tt.paths, nil)
+
+			// act
+			_, err := d.detect(tt.runtime)
+
+			// assert
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("detect error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRuntime_ComposeCommand(t *testing.T) {
+	// arrange
+	rt := dockerRuntime{engine{binary: "docker", compose: []string{"docker", "compose"}}}
+
+	// act
+	cmd := rt.ComposeCommand(context.Background(), "-f", "a.yml", "build")
+
+	// assert
+	expected := []string{"docker", "compose", "-f", "a.yml", "build"}
+	if !slices.Equal(cmd.Args, expected) {
+		t.Errorf("Args = %v, want %v", cmd.Args, expected)
+	}
+}
+
+func TestRuntime_LabelFormat(t *testing.T) {
+	// arrange
+	docker := dockerRuntime{}
+	podman := podmanRuntime{}
+
+	// act
+	dockerFormat := docker.LabelFormat(LabelWorktree)
+	podmanFormat := podman.LabelFormat(LabelWorktree)
+
+	// assert
+	if dockerFormat != `{{.Label "agentbox.worktree"}}` {
+		t.Errorf("docker LabelFormat = %s", dockerFormat)
+	}
+	if podmanFormat != `{{index .Labels "agentbox.worktree"}}` {
+		t.Errorf("podman LabelFormat = %s", podmanFormat)
+	}
+}
+
+func TestParsePodmanInfo(t *testing.T) {
+	// arrange
+	data := []byte(`{"host":{"cpus":8,"memTotal":16777216000,"cgroupControllers":["cpu","memory","pids"]}}`)
+
+	// act
+	info, err := parsePodmanInfo(data)
+
+	// assert
+	if err != nil {
+		t.Fatalf("parsePodmanInfo error: %v", err)
+	}
+	expected := HostInfo{NCPU: 8, MemTotal: 16777216000, CPUCfsQuota: true, MemoryLimit: true, PidsLimit: true}
+	if info != expected {
+		t.Errorf("parsePodmanInfo = %+v, want %+v", info, expected)
+	}
+}
+
+func TestSelectRuntime__unknown(t *testing.T) {
+	// act
+	err := SelectRuntime("lxc")
+
+	// assert
+	if err == nil {
+		t.Error("SelectRuntime accepted an unknown runtime")
+	}
+}
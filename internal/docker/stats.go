@@ -0,0 +1,249 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Stats is the resource usage of a container, as shown by docker stats.
+type Stats struct {
+	// CPUPercent is relative to one CPU, so it can exceed 100 on several CPUs.
+	CPUPercent float64
+	// MemoryUsage excludes the page cache, MemoryLimit is the host memory without a limit.
+	MemoryUsage int64
+	MemoryLimit int64
+}
+
+// apiStats is the part of the stats response used to compute Stats.
+type apiStats struct {
+	CPUStats    apiCPUStats `json:"cpu_stats"`
+	PreCPUStats apiCPUStats `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage int64            `json:"usage"`
+		Limit int64            `json:"limit"`
+		Stats map[string]int64 `json:"stats"`
+	} `json:"memory_stats"`
+}
+
+type apiCPUStats struct {
+	CPUUsage struct {
+		TotalUsage  uint64   `json:"total_usage"`
+		PercpuUsage []uint64 `json:"percpu_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  int    `json:"online_cpus"`
+}
+
+// Stats returns a single sample of the resource usage of a container.
+// The daemon takes about a second to measure CPU usage.
+func (c *Client) Stats(ctx context.Context, id string) (Stats, error) {
+	var stats apiStats
+	query := url.Values{"stream": {"false"}}
+	if err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/stats", query, nil, &stats); err != nil {
+		return Stats{}, err
+	}
+	return stats.convert(), nil
+}
+
+// convert computes the usage the way the docker CLI does.
+func (s apiStats) convert() Stats {
+	var result Stats
+
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	cpus := s.CPUStats.OnlineCPUs
+	if cpus == 0 {
+		cpus = len(s.CPUStats.CPUUsage.PercpuUsage)
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		result.CPUPercent = cpuDelta / systemDelta * float64(cpus) * 100
+	}
+
+	// the page cache can be reclaimed, cgroup v1 and v2 report it under different keys
+	usage := s.MemoryStats.Usage
+	for _, key := range []string{"total_inactive_file", "inactive_file"} {
+		if cache, ok := s.MemoryStats.Stats[key]; ok && cache < usage {
+			usage -= cache
+			break
+		}
+	}
+	result.MemoryUsage = usage
+	result.MemoryLimit = s.MemoryStats.Limit
+	return result
+}
+
+// Top returns the process titles and rows of a container.
+func (c *Client) Top(ctx context.Context, id string) (titles []string, processes [][]string, err error) {
+	var resp struct {
+		Titles    []string   `json:"Titles"`
+		Processes [][]string `json:"Processes"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/top", nil, nil, &resp); err != nil {
+		return nil, nil, err
+	}
+	return resp.Titles, resp.Processes, nil
+}
+
+// ContainerStats returns the resource usage of a running container.
+func ContainerStats(id string) (Stats, error) {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	if client := apiClient(); client != nil {
+		stats, err := client.Stats(context.Background(), id)
+		if err != nil {
+			return Stats{}, fmt.Errorf("stats %s: %w", id, err)
+		}
+		return stats, nil
+	}
+
+	cmd := rt.Command(context.Background(), "stats", "--no-stream", "--format", "{{.CPUPerc}}\t{{.MemUsage}}", id)
+	out, err := cmd.Output()
+	if err != nil {
+		return Stats{}, fmt.Errorf("%s stats: %w", rt.Name(), err)
+	}
+	stats, err := parseStatsOutput(string(out))
+	if err != nil {
+		return Stats{}, fmt.Errorf("parse %s stats: %w", rt.Name(), err)
+	}
+	return stats, nil
+}
+
+// parseStatsOutput parses a "12.5%\t100MiB / 2GiB" line of docker or podman stats.
+func parseStatsOutput(output string) (Stats, error) {
+	cpu, memory, ok := strings.Cut(strings.TrimSpace(output), "\t")
+	if !ok {
+		return Stats{}, fmt.Errorf("unexpected output %q", output)
+	}
+
+	var stats Stats
+	var err error
+	if stats.CPUPercent, err = strconv.ParseFloat(strings.TrimSuffix(cpu, "%"), 64); err != nil {
+		return Stats{}, fmt.Errorf("invalid CPU usage %q", cpu)
+	}
+
+	usage, limit, ok := strings.Cut(memory, "/")
+	if !ok {
+		return Stats{}, fmt.Errorf("invalid memory usage %q", memory)
+	}
+	if stats.MemoryUsage, err = parseSize(usage); err != nil {
+		return Stats{}, err
+	}
+	if stats.MemoryLimit, err = parseSize(limit); err != nil {
+		return Stats{}, err
+	}
+	return stats, nil
+}
+
+// sizeUnits are the units of docker (binary) and podman (decimal) sizes, longest suffix first.
+var sizeUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// parseSize parses sizes such as "100MiB" or "2.1GB".
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	for _, unit := range sizeUnits {
+		number, ok := strings.CutSuffix(s, unit.suffix)
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil {
+			break
+		}
+		return int64(value * unit.bytes), nil
+	}
+	return 0, fmt.Errorf("invalid size %q", s)
+}
+
+// HumanSize formats bytes with binary units like docker stats, e.g. "512.3MiB".
+func HumanSize(bytes int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(bytes)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	return fmt.Sprintf("%.1f%s", value, units[i])
+}
+
+// Processes returns the command lines of the processes running in a container.
+func Processes(id string) ([]string, error) {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return nil, err
+	}
+
+	if client := apiClient(); client != nil {
+		titles, processes, err := client.Top(context.Background(), id)
+		if err != nil {
+			return nil, fmt.Errorf("top %s: %w", id, err)
+		}
+		return commandColumn(titles, processes), nil
+	}
+
+	out, err := rt.Command(context.Background(), "top", id).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s top: %w", rt.Name(), err)
+	}
+	return parseTopOutput(string(out)), nil
+}
+
+// commandColumn returns the command column of top rows: CMD for docker and
+// COMMAND for podman, or the last column.
+func commandColumn(titles []string, processes [][]string) []string {
+	column := len(titles) - 1
+	for i, title := range titles {
+		if title == "CMD" || title == "COMMAND" {
+			column = i
+		}
+	}
+
+	commands := make([]string, 0, len(processes))
+	for _, process := range processes {
+		if column >= 0 && column < len(process) {
+			commands = append(commands, process[column])
+		}
+	}
+	return commands
+}
+
+// parseTopOutput parses the table printed by docker top or podman top. Only
+// the command, the last column, may contain spaces.
+func parseTopOutput(output string) []string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+
+	titles := strings.Fields(lines[0])
+	processes := make([][]string, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < len(titles) {
+			continue
+		}
+		last := len(titles) - 1
+		row := slices.Clone(fields[:last])
+		row = append(row, strings.Join(fields[last:], " "))
+		processes = append(processes, row)
+	}
+	return commandColumn(titles, processes)
+}
@@ -0,0 +1,143 @@
+package docker
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestAPIStats_Convert(t *testing.T) {
+	// arrange
+	data := `{
+		"cpu_stats": {"cpu_usage": {"total_usage": 3000000000}, "system_cpu_usage": 20000000000, "online_cpus": 4},
+		"precpu_stats": {"cpu_usage": {"total_usage": 2000000000}, "system_cpu_usage": 10000000000},
+		"memory_stats": {"usage": 300000000, "limit": 2000000000, "stats": {"inactive_file": 100000000}}
+	}`
+	var stats apiStats
+	if err := json.Unmarshal([]byte(data), &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	result := stats.convert()
+
+	// assert
+	expected := Stats{CPUPercent: 40, MemoryUsage: 200000000, MemoryLimit: 2000000000}
+	if result != expected {
+		t.Errorf("convert = %+v, want %+v", result, expected)
+	}
+}
+
+func TestParseStatsOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected Stats
+	}{
+		{"docker", "12.50%\t100MiB / 2GiB\n", Stats{CPUPercent: 12.5, MemoryUsage: 100 << 20, MemoryLimit: 2 << 30}},
+		{"podman", "0.75%\t1.5MB / 2.1GB\n", Stats{CPUPercent: 0.75, MemoryUsage: 1500000, MemoryLimit: 2100000000}},
+		{"bytes", "0.00%\t512B / 1kB", Stats{MemoryUsage: 512, MemoryLimit: 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result, err := parseStatsOutput(tt.output)
+
+			// assert
+			if err != nil {
+				t.Fatalf("parseStatsOutput error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("parseStatsOutput = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseStatsOutput__invalid(t *testing.T) {
+	for _, output := range []string{"", "--\t-- / --", "1%\t5 parsecs / 1GiB"} {
+		t.Run(output, func(t *testing.T) {
+			// act
+			_, err := parseStatsOutput(output)
+
+			// assert
+			if err == nil {
+				t.Errorf("parseStatsOutput(%q) should fail", output)
+			}
+		})
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{512, "512B"},
+		{1536, "1.5KiB"},
+		{300 << 20, "300.0MiB"},
+		{3 << 30, "3.0GiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			// act
+			result := HumanSize(tt.bytes)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("HumanSize(%d) = %q, want %q", tt.bytes, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTopOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{
+			name: "docker",
+			output: "UID    PID    PPID   C    STIME   TTY     TIME       CMD\n" +
+				"1000   4242   4200   0    12:00   pts/0   00:00:00   /bin/bash\n" +
+				"1000   4300   4242   3    12:01   pts/0   00:00:05   node /opt/agentbox/bin/claude/2.0.1/cli.js --resume\n",
+			expected: []string{"/bin/bash", "node /opt/agentbox/bin/claude/2.0.1/cli.js --resume"},
+		},
+		{
+			name: "podman",
+			output: "USER   PID   PPID   %CPU    ELAPSED   TTY     TIME   COMMAND\n" +
+				"box    1     0      0.000   5m2s      pts/0   0s     /bin/bash\n",
+			expected: []string{"/bin/bash"},
+		},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result := parseTopOutput(tt.output)
+
+			// assert
+			if !slices.Equal(result, tt.expected) {
+				t.Errorf("parseTopOutput = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCommandColumn(t *testing.T) {
+	// arrange
+	titles := []string{"UID", "PID", "CMD", "EXTRA"}
+	processes := [][]string{{"1000", "1", "/bin/bash", "x"}, {"1000"}}
+
+	// act
+	result := commandColumn(titles, processes)
+
+	// assert
+	if !slices.Equal(result, []string{"/bin/bash"}) {
+		t.Errorf("commandColumn = %q", result)
+	}
+}
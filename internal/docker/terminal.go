@@ -0,0 +1,96 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// ExitError reports a command in a container that exited with a non-zero code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// attachAPI runs an interactive command in a container through the Engine
+// API, connecting it to the current terminal like docker exec -it.
+func attachAPI(client *Client, containerID string, opts AttachOptions) error {
+	ctx := context.Background()
+	command := opts.Command
+	if len(command) == 0 {
+		command = DefaultAttachCommand
+	}
+
+	execID, err := client.ExecCreate(ctx, containerID, ExecConfig{
+		Cmd:          command,
+		User:         opts.User,
+		WorkingDir:   opts.WorkDir,
+		Tty:          true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	conn, err := client.ExecStart(ctx, execID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stdin := int(os.Stdin.Fd())
+	if term.IsTerminal(stdin) {
+		state, err := term.MakeRaw(stdin)
+		if err == nil {
+			defer func() { _ = term.Restore(stdin, state) }()
+		}
+	}
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+	done := make(chan struct{})
+	defer close(done)
+	resize := func() {
+		width, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err == nil {
+			_ = client.ExecResize(ctx, execID, width, height)
+		}
+	}
+	resize()
+	go func() {
+		for {
+			select {
+			case <-winch:
+				resize()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// agentbox exits right after the session, so the blocked input copy is not waited for
+	go func() { _, _ = io.Copy(conn, os.Stdin) }()
+	if _, err := io.Copy(os.Stdout, conn); err != nil {
+		return err
+	}
+
+	code, err := client.ExecExitCode(ctx, execID)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
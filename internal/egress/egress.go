@@ -0,0 +1,251 @@
+package egress
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ProxyService is the compose service name of the filtering proxy.
+	ProxyService = "egress"
+	// ProxyImage is the image of the filtering proxy, pinned so that an update
+	// cannot change how the generated configuration is read.
+	ProxyImage = "ubuntu/squid:5.2-22.04_beta"
+	// ProxyPort is the port the proxy listens on.
+	ProxyPort = 3128
+	// Network is the internal compose network shared by the sandbox and the proxy.
+	Network = "agentbox-egress"
+
+	configFile = "squid.conf"
+	logsDir    = "logs"
+	accessLog  = "access.log"
+
+	containerConfigPath = "/etc/squid/squid.conf"
+	containerLogsDir    = "/var/log/squid"
+)
+
+// ProxyURL returns the proxy address as seen from the sandbox container.
+func ProxyURL() string {
+	return "http://" + ProxyService + ":" + strconv.Itoa(ProxyPort)
+}
+
+// ReconfigureCommand returns the command that makes a running proxy reread its configuration.
+func ReconfigureCommand() []string {
+	return []string{"squid", "-k", "reconfigure"}
+}
+
+// ProxyEnv returns environment variables that route sandbox traffic through the proxy.
+// Both spellings are set because tools disagree on the case.
+func ProxyEnv() map[string]string {
+	proxy := ProxyURL()
+	noProxy := "localhost,127.0.0.1," + ProxyService
+
+	return map[string]string{
+		"HTTP_PROXY":  proxy,
+		"HTTPS_PROXY": proxy,
+		"NO_PROXY":    noProxy,
+		"http_proxy":  proxy,
+		"https_proxy": proxy,
+		"no_proxy":    noProxy,
+	}
+}
+
+// Normalize lowercases domains, drops duplicates and entries covered by a
+// wildcard (".example.com" covers "example.com" and "api.example.com").
+// Squid refuses overlapping entries, so the result is safe to use in an ACL.
+func Normalize(domains []string) []string {
+	seen := make(map[string]bool)
+	var wildcards []string
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimSpace(d))
+		d = strings.TrimPrefix(d, "*")
+		if d == "" || d == "." || seen[d] {
+			continue
+		}
+		seen[d] = true
+		if strings.HasPrefix(d, ".") {
+			wildcards = append(wildcards, d)
+		}
+	}
+
+	covered := func(d string) bool {
+		for _, w := range wildcards {
+			if d == w {
+				continue
+			}
+			if strings.HasSuffix(d, w) || d == w[1:] {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := make([]string, 0, len(seen))
+	for d := range seen {
+		if !covered(d) {
+			result = append(result, d)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// SquidConfig returns a squid configuration that only allows HTTP(S) requests to domains.
+func SquidConfig(domains []string) []byte {
+	var b strings.Builder
+	b.WriteString("# Generated by agentbox, do not edit.\n")
+	fmt.Fprintf(&b, "http_port %d\n", ProxyPort)
+	b.WriteString("\n")
+
+	b.WriteString("acl SSL_ports port 443\n")
+	b.WriteString("acl Safe_ports port 80 443\n")
+	b.WriteString("acl CONNECT method CONNECT\n")
+	allowed := Normalize(domains)
+	for _, d := range allowed {
+		fmt.Fprintf(&b, "acl allowed dstdomain %s\n", d)
+	}
+	b.WriteString("\n")
+
+	b.WriteString("http_access deny !Safe_ports\n")
+	b.WriteString("http_access deny CONNECT !SSL_ports\n")
+	if len(allowed) > 0 {
+		b.WriteString("http_access allow allowed\n")
+	}
+	b.WriteString("http_access deny all\n")
+	b.WriteString("\n")
+
+	b.WriteString("cache deny all\n")
+	fmt.Fprintf(&b, "access_log stdio:%s/%s squid\n", containerLogsDir, accessLog)
+	b.WriteString("cache_log /dev/null\n")
+	return []byte(b.String())
+}
+
+// Files are the host paths of the proxy configuration.
+type Files struct {
+	Config  string
+	LogsDir string
+}
+
+// Volumes returns compose volumes that mount the files into the proxy container.
+func (f Files) Volumes() []string {
+	return []string{
+		f.Config + ":" + containerConfigPath + ":ro",
+		f.LogsDir + ":" + containerLogsDir,
+	}
+}
+
+// AccessLog returns the host path of the proxy access log.
+func (f Files) AccessLog() string {
+	return filepath.Join(f.LogsDir, accessLog)
+}
+
+// WriteConfig writes the proxy configuration for domains into dir and reports
+// whether it differs from the configuration written before, which a running
+// proxy then has to reread. The file is rewritten in place, because the proxy
+// container mounts the file rather than the directory.
+func WriteConfig(dir string, domains []string) (Files, bool, error) {
+	files := Files{
+		Config:  filepath.Join(dir, configFile),
+		LogsDir: filepath.Join(dir, logsDir),
+	}
+
+	if err := os.MkdirAll(files.LogsDir, 0o755); err != nil {
+		return Files{}, false, fmt.Errorf("create egress logs dir: %w", err)
+	}
+	// the proxy runs as its own user inside the container
+	if err := os.Chmod(files.LogsDir, 0o777); err != nil {
+		return Files{}, false, fmt.Errorf("chmod egress logs dir: %w", err)
+	}
+
+	config := SquidConfig(domains)
+	if current, err := os.ReadFile(files.Config); err == nil && bytes.Equal(current, config) {
+		return files, false, nil
+	}
+	if err := os.WriteFile(files.Config, config, 0o644); err != nil {
+		return Files{}, false, fmt.Errorf("write egress config: %w", err)
+	}
+	return files, true, nil
+}
+
+// Denied is a host the proxy refused to connect to.
+type Denied struct {
+	Host  string
+	Count int
+}
+
+// ParseDenied returns hosts denied by the proxy at or after since, most frequent first.
+// It reads squid's native access log format.
+func ParseDenied(r io.Reader, since time.Time) ([]Denied, error) {
+	counts := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// time elapsed client code/status bytes method url ...
+		if len(fields) < 7 || !strings.HasPrefix(fields[3], "TCP_DENIED/") {
+			continue
+		}
+
+		ts, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		if time.UnixMilli(int64(ts * 1000)).Before(since) {
+			continue
+		}
+
+		if host := requestHost(fields[6]); host != "" {
+			counts[host]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read egress log: %w", err)
+	}
+
+	denied := make([]Denied, 0, len(counts))
+	for host, count := range counts {
+		denied = append(denied, Denied{Host: host, Count: count})
+	}
+	sort.Slice(denied, func(i, j int) bool {
+		if denied[i].Count != denied[j].Count {
+			return denied[i].Count > denied[j].Count
+		}
+		return denied[i].Host < denied[j].Host
+	})
+	return denied, nil
+}
+
+// ReadDenied is ParseDenied for the access log of files. A missing log means nothing was denied.
+func ReadDenied(files Files, since time.Time) ([]Denied, error) {
+	f, err := os.Open(files.AccessLog())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open egress log: %w", err)
+	}
+	defer f.Close()
+
+	return ParseDenied(f, since)
+}
+
+// requestHost extracts the host from a logged URL: "host:443" for CONNECT,
+// "http://host/path" for plain requests.
+func requestHost(url string) string {
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+	}
+	host, _, _ := strings.Cut(url, "/")
+	if i := strings.LastIndex(host, ":"); i != -1 {
+		host = host[:i]
+	}
+	return strings.ToLower(host)
+}
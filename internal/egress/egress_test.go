@@ -0,0 +1,156 @@
+package egress
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	// arrange
+	domains := []string{
+		"API.example.com",
+		".example.com",
+		"example.com",
+		"*.npmjs.org",
+		"registry.npmjs.org",
+		"pypi.org",
+		" pypi.org ",
+		"",
+	}
+
+	// act
+	result := Normalize(domains)
+
+	// assert
+	expected := []string{".example.com", ".npmjs.org", "pypi.org"}
+	if !slices.Equal(result, expected) {
+		t.Errorf("Normalize = %v, want %v", result, expected)
+	}
+}
+
+func TestSquidConfig(t *testing.T) {
+	// act
+	config := string(SquidConfig([]string{"api.anthropic.com", ".pypi.org"}))
+
+	// assert
+	expectedLines := []string{
+		"http_port 3128",
+		"acl allowed dstdomain .pypi.org",
+		"acl allowed dstdomain api.anthropic.com",
+		"http_access deny CONNECT !SSL_ports",
+		"http_access allow allowed",
+		"http_access deny all",
+		"access_log stdio:/var/log/squid/access.log squid",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(config, line+"\n") {
+			t.Errorf("config missing line %q:\n%s", line, config)
+		}
+	}
+	if strings.Index(config, "http_access allow allowed") > strings.Index(config, "http_access deny all") {
+		t.Error("allow rule must precede deny all")
+	}
+}
+
+func TestSquidConfig__no_domains(t *testing.T) {
+	// act
+	config := string(SquidConfig(nil))
+
+	// assert
+	if strings.Contains(config, "allowed") {
+		t.Errorf("config should not reference an empty acl:\n%s", config)
+	}
+}
+
+func TestParseDenied(t *testing.T) {
+	// arrange
+	log := strings.Join([]string{
+		"1700000000.100      0 172.20.0.3 TCP_DENIED/403 3900 CONNECT evil.example.com:443 - HIER_NONE/- text/html",
+		"1700000100.100    120 172.20.0.3 TCP_TUNNEL/200 5120 CONNECT api.anthropic.com:443 - HIER_DIRECT/1.2.3.4 -",
+		"1700000100.200      0 172.20.0.3 TCP_DENIED/403 3900 CONNECT registry.npmjs.org:443 - HIER_NONE/- text/html",
+		"1700000100.300      0 172.20.0.3 TCP_DENIED/403 3900 GET http://example.org/file - HIER_NONE/- text/html",
+		"1700000100.400      0 172.20.0.3 TCP_DENIED/403 3900 CONNECT registry.npmjs.org:443 - HIER_NONE/- text/html",
+		"garbage",
+	}, "\n")
+	since := time.Unix(1700000050, 0)
+
+	// act
+	denied, err := ParseDenied(strings.NewReader(log), since)
+
+	// assert
+	if err != nil {
+		t.Fatalf("ParseDenied error: %v", err)
+	}
+	expected := []Denied{
+		{Host: "registry.npmjs.org", Count: 2},
+		{Host: "example.org", Count: 1},
+	}
+	if !slices.Equal(denied, expected) {
+		t.Errorf("ParseDenied = %v, want %v", denied, expected)
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	// arrange
+	dir := t.TempDir()
+
+	// act
+	files, changed, err := WriteConfig(dir, []string{"api.openai.com"})
+
+	// assert
+	if err != nil {
+		t.Fatalf("WriteConfig error: %v", err)
+	}
+	if !changed {
+		t.Error("changed = false, want true for a new config")
+	}
+	if _, err := os.Stat(files.Config); err != nil {
+		t.Errorf("config not written: %v", err)
+	}
+	info, err := os.Stat(files.LogsDir)
+	if err != nil {
+		t.Fatalf("logs dir not created: %v", err)
+	}
+	if info.Mode().Perm() != 0o777 {
+		t.Errorf("logs dir mode = %o, want 777", info.Mode().Perm())
+	}
+
+	volumes := files.Volumes()
+	if volumes[0] != filepath.Join(dir, "squid.conf")+":/etc/squid/squid.conf:ro" {
+		t.Errorf("config volume = %s", volumes[0])
+	}
+}
+
+func TestWriteConfig__changed(t *testing.T) {
+	// arrange
+	dir := t.TempDir()
+	if _, _, err := WriteConfig(dir, []string{"api.openai.com"}); err != nil {
+		t.Fatalf("WriteConfig error: %v", err)
+	}
+
+	// act
+	_, same, sameErr := WriteConfig(dir, []string{"api.openai.com"})
+	_, other, otherErr := WriteConfig(dir, []string{"api.openai.com", "github.com"})
+
+	// assert
+	if sameErr != nil || same {
+		t.Errorf("same domains: changed = %v, error = %v, want false", same, sameErr)
+	}
+	if otherErr != nil || !other {
+		t.Errorf("other domains: changed = %v, error = %v, want true", other, otherErr)
+	}
+}
+
+func TestReadDenied__missing_log(t *testing.T) {
+	// act
+	denied, err := ReadDenied(Files{LogsDir: t.TempDir()}, time.Time{})
+
+	// assert
+	if err != nil || denied != nil {
+		t.Errorf("ReadDenied = %v, %v, want nil, nil", denied, err)
+	}
+}
@@ -0,0 +1,243 @@
+package git
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CheckpointRefPrefix is the hidden ref namespace holding checkpoints, one ref per session.
+const CheckpointRefPrefix = "refs/agentbox/checkpoints/"
+
+// ErrCheckpointNotFound is returned when no checkpoint matches an id.
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// Checkpoint is a snapshot of the work tree stored as a commit.
+type Checkpoint struct {
+	Commit  string
+	Session string
+	Time    time.Time
+	Message string
+}
+
+// sessionNameRe matches the characters not kept from a name in a session id.
+var sessionNameRe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// NewSessionID returns the id of a checkpoint session: the start time, the name
+// of the session or worktree if any, and a random suffix, so that sessions
+// started in the same second, e.g. in several worktrees, get their own ref.
+func NewSessionID(name string) string {
+	id := time.Now().Format("20060102-150405")
+	if name = strings.Trim(sessionNameRe.ReplaceAllString(name, "-"), "-"); name != "" {
+		id += "-" + name
+	}
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return id + "-" + hex.EncodeToString(suffix)
+}
+
+// ID returns the short id used to refer to the checkpoint.
+func (c Checkpoint) ID() string {
+	return c.Commit[:min(len(c.Commit), 12)]
+}
+
+// identity is used when the repository has no user configured.
+var identity = []string{
+	"GIT_AUTHOR_NAME=agentbox",
+	"GIT_AUTHOR_EMAIL=agentbox@localhost",
+	"GIT_COMMITTER_NAME=agentbox",
+	"GIT_COMMITTER_EMAIL=agentbox@localhost",
+}
+
+// Snapshot records the work tree of dir, including untracked but not ignored
+// files, as a new checkpoint of the session. The user's index is not touched.
+// Returns false if nothing changed since the session's previous checkpoint.
+func Snapshot(dir, session, message string) (Checkpoint, bool, error) {
+	tree, err := snapshotTree(dir)
+	if err != nil {
+		return Checkpoint{}, false, fmt.Errorf("snapshot work tree: %w", err)
+	}
+
+	ref := CheckpointRefPrefix + session
+	args := []string{"commit-tree", tree, "-m", message}
+	if parent, err := Run(dir, "rev-parse", "--verify", "--quiet", ref); err == nil {
+		parentTree, err := Run(dir, "rev-parse", parent+"^{tree}")
+		if err == nil && parentTree == tree {
+			return Checkpoint{}, false, nil
+		}
+		args = append(args, "-p", parent)
+	}
+
+	commit, err := RunWithEnv(dir, identity, args...)
+	if err != nil {
+		return Checkpoint{}, false, fmt.Errorf("create checkpoint commit: %w", err)
+	}
+	if _, err := Run(dir, "update-ref", ref, commit); err != nil {
+		return Checkpoint{}, false, fmt.Errorf("update checkpoint ref: %w", err)
+	}
+
+	return Checkpoint{Commit: commit, Session: session, Time: time.Now(), Message: message}, true, nil
+}
+
+// snapshotTree writes the work tree into a tree object using a temporary index.
+func snapshotTree(dir string) (string, error) {
+	tmp, err := os.CreateTemp("", "agentbox-index-")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	// starting from the real index lets git reuse its stat cache
+	indexPath, err := Run(dir, "rev-parse", "--path-format=absolute", "--git-path", "index")
+	if err != nil {
+		return "", err
+	}
+	if err := copyFile(indexPath, tmpPath); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		// a fresh repository has no index yet
+		_ = os.Remove(tmpPath)
+	}
+
+	env := []string{"GIT_INDEX_FILE=" + tmpPath}
+	if _, err := RunWithEnv(dir, env, "add", "--all", "--", ":/"); err != nil {
+		return "", err
+	}
+	return RunWithEnv(dir, env, "write-tree")
+}
+
+// ListCheckpoints returns all checkpoints of the repository, newest first.
+func ListCheckpoints(dir string) ([]Checkpoint, error) {
+	refs, err := Run(dir, "for-each-ref", "--format=%(refname)", CheckpointRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []Checkpoint
+	for ref := range strings.SplitSeq(refs, "\n") {
+		if ref == "" {
+			continue
+		}
+		out, err := Run(dir, "log", "--format=%H%x09%ct%x09%s", ref)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, parseCheckpointLog(strings.TrimPrefix(ref, CheckpointRefPrefix), out)...)
+	}
+
+	sort.SliceStable(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Time.After(checkpoints[j].Time)
+	})
+	return checkpoints, nil
+}
+
+func parseCheckpointLog(session, output string) []Checkpoint {
+	var checkpoints []Checkpoint
+	for line := range strings.SplitSeq(output, "\n") {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		ts, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		checkpoints = append(checkpoints, Checkpoint{
+			Commit:  parts[0],
+			Session: session,
+			Time:    time.Unix(ts, 0),
+			Message: parts[2],
+		})
+	}
+	return checkpoints
+}
+
+// FindCheckpoint returns the checkpoint whose commit starts with id.
+func FindCheckpoint(dir, id string) (Checkpoint, error) {
+	checkpoints, err := ListCheckpoints(dir)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	var found []Checkpoint
+	for _, c := range checkpoints {
+		if id != "" && strings.HasPrefix(c.Commit, id) {
+			found = append(found, c)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Checkpoint{}, fmt.Errorf("%w: %s", ErrCheckpointNotFound, id)
+	case 1:
+		return found[0], nil
+	default:
+		return Checkpoint{}, fmt.Errorf("checkpoint id %s is ambiguous", id)
+	}
+}
+
+// RestoreCheckpoint makes the work tree match the checkpoint: files are
+// restored, and files that did not exist at the checkpoint are removed.
+// Ignored files and the index are left alone.
+func RestoreCheckpoint(dir string, c Checkpoint) error {
+	top, err := TopLevel(dir)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := Run(top, "ls-tree", "-r", "-z", "--name-only", c.Commit)
+	if err != nil {
+		return fmt.Errorf("list checkpoint files: %w", err)
+	}
+	current, err := Run(top, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return fmt.Errorf("list work tree files: %w", err)
+	}
+
+	keep := make(map[string]bool)
+	for name := range strings.SplitSeq(snapshot, "\x00") {
+		keep[name] = true
+	}
+	for name := range strings.SplitSeq(current, "\x00") {
+		if name == "" || keep[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(top, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", name, err)
+		}
+	}
+
+	if _, err := Run(top, "restore", "--source="+c.Commit, "--worktree", "--", ":/"); err != nil {
+		return fmt.Errorf("restore files: %w", err)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
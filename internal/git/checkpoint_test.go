@@ -0,0 +1,179 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestNewSessionID(t *testing.T) {
+	// act
+	first := NewSessionID("feature/login x")
+	second := NewSessionID("feature/login x")
+	unnamed := NewSessionID("")
+
+	// assert
+	if !regexp.MustCompile(`^\d{8}-\d{6}-feature-login-x-[0-9a-f]{6}$`).MatchString(first) {
+		t.Errorf("NewSessionID() = %q, want time, name and suffix", first)
+	}
+	if first == second {
+		t.Errorf("NewSessionID() returned %q twice", first)
+	}
+	if !regexp.MustCompile(`^\d{8}-\d{6}-[0-9a-f]{6}$`).MatchString(unnamed) {
+		t.Errorf("NewSessionID() = %q, want time and suffix", unnamed)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	// arrange
+	repo := initRepo(t)
+	writeFile(t, filepath.Join(repo, "untracked.txt"), "draft\n")
+
+	// act
+	first, created, err := Snapshot(repo, "s1", "session start")
+
+	// assert
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	if !created {
+		t.Fatal("first snapshot should be created")
+	}
+	files, err := Run(repo, "ls-tree", "-r", "--name-only", first.Commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files != "untracked.txt" {
+		t.Errorf("snapshot files = %q, want untracked.txt", files)
+	}
+	status, err := Run(repo, "status", "--porcelain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "?? untracked.txt" {
+		t.Errorf("user index changed, status = %q", status)
+	}
+}
+
+func TestSnapshot__unchanged(t *testing.T) {
+	// arrange
+	repo := initRepo(t)
+	writeFile(t, filepath.Join(repo, "a.txt"), "a\n")
+	if _, _, err := Snapshot(repo, "s1", "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	_, created, err := Snapshot(repo, "s1", "second")
+
+	// assert
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	if created {
+		t.Error("snapshot of an unchanged tree should be skipped")
+	}
+}
+
+func TestListCheckpoints(t *testing.T) {
+	// arrange
+	repo := initRepo(t)
+	writeFile(t, filepath.Join(repo, "a.txt"), "1\n")
+	if _, _, err := Snapshot(repo, "s1", "first"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(repo, "a.txt"), "2\n")
+	if _, _, err := Snapshot(repo, "s1", "second"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Snapshot(repo, "s2", "other session"); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	checkpoints, err := ListCheckpoints(repo)
+
+	// assert
+	if err != nil {
+		t.Fatalf("ListCheckpoints error: %v", err)
+	}
+	if len(checkpoints) != 3 {
+		t.Fatalf("len(checkpoints) = %d, want 3", len(checkpoints))
+	}
+	sessions := map[string]int{}
+	for _, c := range checkpoints {
+		sessions[c.Session]++
+	}
+	if sessions["s1"] != 2 || sessions["s2"] != 1 {
+		t.Errorf("checkpoints per session = %v", sessions)
+	}
+}
+
+func TestRestoreCheckpoint(t *testing.T) {
+	// arrange
+	repo := initRepo(t)
+	writeFile(t, filepath.Join(repo, "keep.txt"), "original\n")
+	checkpoint, _, err := Snapshot(repo, "s1", "session start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(repo, "keep.txt"), "wrecked\n")
+	writeFile(t, filepath.Join(repo, "junk.txt"), "junk\n")
+
+	// act
+	err = RestoreCheckpoint(repo, checkpoint)
+
+	// assert
+	if err != nil {
+		t.Fatalf("RestoreCheckpoint error: %v", err)
+	}
+	if content := readFile(t, filepath.Join(repo, "keep.txt")); content != "original\n" {
+		t.Errorf("keep.txt = %q, want original", content)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "junk.txt")); !os.IsNotExist(err) {
+		t.Error("junk.txt should be removed")
+	}
+}
+
+func TestFindCheckpoint(t *testing.T) {
+	// arrange
+	repo := initRepo(t)
+	writeFile(t, filepath.Join(repo, "a.txt"), "a\n")
+	checkpoint, _, err := Snapshot(repo, "s1", "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	found, err := FindCheckpoint(repo, checkpoint.ID())
+	_, missingErr := FindCheckpoint(repo, "deadbeef")
+
+	// assert
+	if err != nil || found.Commit != checkpoint.Commit {
+		t.Errorf("FindCheckpoint = %v, %v, want %s", found, err, checkpoint.Commit)
+	}
+	if !errors.Is(missingErr, ErrCheckpointNotFound) {
+		t.Errorf("FindCheckpoint(missing) error = %v, want ErrCheckpointNotFound", missingErr)
+	}
+}
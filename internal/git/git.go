@@ -0,0 +1,87 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotRepository is returned when a directory is not inside a git work tree.
+var ErrNotRepository = errors.New("not a git repository")
+
+// Run executes git in dir and returns trimmed stdout.
+// The error contains git's stderr message when available.
+func Run(dir string, args ...string) (string, error) {
+	return RunWithEnv(dir, nil, args...)
+}
+
+// RunWithEnv is like Run but adds env ("KEY=VALUE") to the git environment.
+func RunWithEnv(dir string, env []string, args ...string) (string, error) {
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(cmd.Environ(), env...)
+	}
+
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// ConfigValue returns the value of a git config key as seen in dir, empty if it is not set.
+func ConfigValue(dir, key string) string {
+	value, err := Run(dir, "config", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// TopLevel returns the root of the work tree containing dir.
+func TopLevel(dir string) (string, error) {
+	out, err := Run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", ErrNotRepository
+	}
+	return out, nil
+}
+
+// CommonDir returns the absolute path of the .git directory shared by all worktrees.
+func CommonDir(dir string) (string, error) {
+	out, err := Run(dir, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", ErrNotRepository
+	}
+	if !filepath.IsAbs(out) {
+		out = filepath.Join(dir, out)
+	}
+	return filepath.Clean(out), nil
+}
+
+// BranchExists reports whether a local branch exists.
+func BranchExists(dir, branch string) bool {
+	_, err := Run(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// IsClean reports whether the work tree has no uncommitted or untracked changes.
+func IsClean(dir string) (bool, error) {
+	out, err := Run(dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return out == "", nil
+}
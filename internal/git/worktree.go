@@ -0,0 +1,102 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Worktree is an entry of `git worktree list`.
+type Worktree struct {
+	Path   string
+	Branch string
+	Head   string
+}
+
+// AddWorktree creates a worktree at path checked out on branch.
+// The branch is created from HEAD if it does not exist yet.
+func AddWorktree(repoDir, path, branch string) error {
+	args := []string{"worktree", "add"}
+	if BranchExists(repoDir, branch) {
+		args = append(args, path, branch)
+	} else {
+		args = append(args, "-b", branch, path)
+	}
+
+	if _, err := Run(repoDir, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListWorktrees returns all worktrees of the repository, including the main one.
+func ListWorktrees(repoDir string) ([]Worktree, error) {
+	out, err := Run(repoDir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	return parseWorktreeList(out), nil
+}
+
+func parseWorktreeList(output string) []Worktree {
+	var worktrees []Worktree
+	for block := range strings.SplitSeq(output, "\n\n") {
+		var wt Worktree
+		for line := range strings.SplitSeq(strings.TrimSpace(block), "\n") {
+			key, value, _ := strings.Cut(line, " ")
+			switch key {
+			case "worktree":
+				wt.Path = value
+			case "HEAD":
+				wt.Head = value
+			case "branch":
+				wt.Branch = strings.TrimPrefix(value, "refs/heads/")
+			}
+		}
+		if wt.Path != "" {
+			worktrees = append(worktrees, wt)
+		}
+	}
+	return worktrees
+}
+
+// RemoveWorktree removes the worktree at path.
+func RemoveWorktree(repoDir, path string, force bool) error {
+	args := []string{"worktree", "remove"}
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, path)
+
+	if _, err := Run(repoDir, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteBranch deletes a local branch. Unless force is set, git refuses to
+// delete a branch that is not merged.
+func DeleteBranch(repoDir, branch string, force bool) error {
+	flag := "-d"
+	if force {
+		flag = "-D"
+	}
+	if _, err := Run(repoDir, "branch", flag, branch); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Merge merges branch into the current branch of repoDir.
+func Merge(repoDir, branch string, squash bool) (string, error) {
+	args := []string{"merge"}
+	if squash {
+		args = append(args, "--squash")
+	}
+	args = append(args, branch)
+
+	out, err := Run(repoDir, args...)
+	if err != nil {
+		return "", fmt.Errorf("merge %s: %w", branch, err)
+	}
+	return out, nil
+}
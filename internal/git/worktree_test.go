@@ -0,0 +1,141 @@
+package git
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseWorktreeList(t *testing.T) {
+	// arrange
+	output := "worktree /home/user/project\n" +
+		"HEAD 1111111111111111111111111111111111111111\n" +
+		"branch refs/heads/main\n" +
+		"\n" +
+		"worktree /home/user/.agentbox/worktrees/project-1a2b3c4d/feature-x\n" +
+		"HEAD 2222222222222222222222222222222222222222\n" +
+		"branch refs/heads/agentbox/feature-x\n" +
+		"\n" +
+		"worktree /tmp/detached\n" +
+		"HEAD 3333333333333333333333333333333333333333\n" +
+		"detached\n"
+
+	// act
+	worktrees := parseWorktreeList(output)
+
+	// assert
+	expected := []Worktree{
+		{Path: "/home/user/project", Branch: "main", Head: "1111111111111111111111111111111111111111"},
+		{
+			Path:   "/home/user/.agentbox/worktrees/project-1a2b3c4d/feature-x",
+			Branch: "agentbox/feature-x",
+			Head:   "2222222222222222222222222222222222222222",
+		},
+		{Path: "/tmp/detached", Head: "3333333333333333333333333333333333333333"},
+	}
+
+	if len(worktrees) != len(expected) {
+		t.Fatalf("len(worktrees) = %d, want %d", len(worktrees), len(expected))
+	}
+	for i, wt := range worktrees {
+		if wt != expected[i] {
+			t.Errorf("worktrees[%d] = %+v, want %+v", i, wt, expected[i])
+		}
+	}
+}
+
+// initRepo creates a repository with a single commit.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if _, err := Run(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestAddWorktree(t *testing.T) {
+	// arrange
+	repo := initRepo(t)
+	path := filepath.Join(t.TempDir(), "feature-x")
+
+	// act
+	err := AddWorktree(repo, path, "agentbox/feature-x")
+
+	// assert
+	if err != nil {
+		t.Fatalf("AddWorktree error: %v", err)
+	}
+	if !BranchExists(repo, "agentbox/feature-x") {
+		t.Error("branch agentbox/feature-x was not created")
+	}
+
+	worktrees, err := ListWorktrees(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worktrees) != 2 {
+		t.Fatalf("len(worktrees) = %d, want 2", len(worktrees))
+	}
+	if worktrees[1].Branch != "agentbox/feature-x" {
+		t.Errorf("worktree branch = %s, want agentbox/feature-x", worktrees[1].Branch)
+	}
+}
+
+func TestAddWorktree__existing_branch(t *testing.T) {
+	// arrange
+	repo := initRepo(t)
+	if _, err := Run(repo, "branch", "agentbox/feature-x"); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "feature-x")
+
+	// act
+	err := AddWorktree(repo, path, "agentbox/feature-x")
+
+	// assert
+	if err != nil {
+		t.Fatalf("AddWorktree error: %v", err)
+	}
+}
+
+func TestCommonDir(t *testing.T) {
+	// arrange
+	repo := initRepo(t)
+	path := filepath.Join(t.TempDir(), "feature-x")
+	if err := AddWorktree(repo, path, "agentbox/feature-x"); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	commonDir, err := CommonDir(path)
+
+	// assert
+	if err != nil {
+		t.Fatalf("CommonDir error: %v", err)
+	}
+	expected, _ := filepath.EvalSymlinks(filepath.Join(repo, ".git"))
+	actual, _ := filepath.EvalSymlinks(commonDir)
+	if actual != expected {
+		t.Errorf("CommonDir = %s, want %s", actual, expected)
+	}
+}
+
+func TestTopLevel__not_repository(t *testing.T) {
+	// act
+	_, err := TopLevel(t.TempDir())
+
+	// assert
+	if err == nil {
+		t.Error("expected ErrNotRepository")
+	}
+}
@@ -0,0 +1,269 @@
+// Package gitcredential lets git in the sandbox get credentials from git on the
+// host, for an allowlist of hosts only.
+package gitcredential
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ContainerDir is where the proxy directory is mounted inside the container.
+	ContainerDir = "/run/agentbox/git"
+	// HelperPath is the credential helper inside the container, set as credential.helper.
+	HelperPath = ContainerDir + "/credential-helper"
+
+	socketFile = "credential.sock"
+	helperFile = "credential-helper"
+
+	// tokenHeader carries the token of the session, see Proxy.token.
+	tokenHeader = "X-Agentbox-Token"
+
+	// fillTimeout limits how long a host credential helper may take.
+	fillTimeout = 30 * time.Second
+	// maxRequestSize limits the credential description read from the sandbox.
+	maxRequestSize = 64 << 10
+)
+
+// helperTemplate is the credential helper of the sandbox, formatted with the
+// token. Only get is forwarded, credentials are stored and erased by the
+// helpers on the host.
+const helperTemplate = `#!/bin/sh
+# Generated by agentbox, do not edit.
+# Asks git on the host for credentials of the hosts allowed in .agentbox.toml.
+[ "$1" = get ] || exit 0
+curl -sf --unix-socket ` + ContainerDir + "/" + socketFile + ` -H '` + tokenHeader + `: %s' --data-binary @- http://agentbox/get || true
+`
+
+// Proxy answers credential requests of the sandbox with 'git credential fill'
+// on the host. It listens on a unix socket in a directory mounted into the container.
+type Proxy struct {
+	dir   string
+	hosts []string
+	// token is a random secret of the session that requests must carry,
+	// it is only written to the helper in the proxy directory.
+	token  string
+	server *http.Server
+	// fill returns the credentials for a request in the git credential format.
+	fill func(ctx context.Context, request []byte) ([]byte, error)
+
+	mu      sync.Mutex
+	refused []string
+}
+
+// Start creates the proxy directory under runDir, a directory private to the
+// user, with the socket and the helper, and serves requests until Close.
+// Only the user can reach the socket, the sandbox runs with the same uid.
+// Credentials are only given for hosts, where a leading dot matches subdomains.
+func Start(runDir string, hosts []string) (*Proxy, error) {
+	if err := os.MkdirAll(runDir, 0o700); err != nil {
+		return nil, fmt.Errorf("create credential proxy dir: %w", err)
+	}
+	// created with mode 0700
+	dir, err := os.MkdirTemp(runDir, "git-")
+	if err != nil {
+		return nil, fmt.Errorf("create credential proxy dir: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("generate credential proxy token: %w", err)
+	}
+	token := hex.EncodeToString(secret)
+	helper := fmt.Sprintf(helperTemplate, token)
+	if err := os.WriteFile(filepath.Join(dir, helperFile), []byte(helper), 0o700); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("write credential helper: %w", err)
+	}
+
+	socket := filepath.Join(dir, socketFile)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("listen on %s: %w", socket, err)
+	}
+	if err := os.Chmod(socket, 0o600); err != nil {
+		_ = listener.Close()
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("listen on %s: %w", socket, err)
+	}
+
+	p := &Proxy{dir: dir, hosts: hosts, token: token, fill: fill}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /get", p.handleGet)
+	p.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = p.server.Serve(listener) }()
+	return p, nil
+}
+
+// Volume returns the compose volume that mounts the proxy directory.
+func (p *Proxy) Volume() string {
+	return p.dir + ":" + ContainerDir
+}
+
+// Refused returns the hosts whose credentials the sandbox asked for but
+// were not allowed, in the order of the first request.
+func (p *Proxy) Refused() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.refused)
+}
+
+// Close stops the proxy and removes its directory.
+func (p *Proxy) Close() error {
+	err := p.server.Close()
+	if removeErr := os.RemoveAll(p.dir); removeErr != nil {
+		return fmt.Errorf("remove credential proxy dir: %w", removeErr)
+	}
+	return err
+}
+
+func (p *Proxy) handleGet(w http.ResponseWriter, r *http.Request) {
+	if p.token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(tokenHeader)), []byte(p.token)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	request, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	attrs, err := parse(request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// plain http would send the credentials unencrypted
+	if attrs["protocol"] != "https" {
+		http.Error(w, "only https credentials are forwarded", http.StatusForbidden)
+		return
+	}
+	host := hostname(attrs["host"])
+	if !Allowed(p.hosts, host) {
+		p.mu.Lock()
+		if !slices.Contains(p.refused, host) {
+			p.refused = append(p.refused, host)
+		}
+		p.mu.Unlock()
+		http.Error(w, "host is not allowed", http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), fillTimeout)
+	defer cancel()
+	// rebuilt from the checked attributes, so git fills exactly what was allowed
+	credentials, err := p.fill(ctx, format(attrs))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	_, _ = w.Write(credentials)
+}
+
+// Allowed reports whether host, which may include a port, matches an entry of
+// hosts. A leading dot matches the domain and its subdomains.
+func Allowed(hosts []string, host string) bool {
+	host = hostname(host)
+	if host == "" {
+		return false
+	}
+	for _, entry := range hosts {
+		entry = strings.ToLower(entry)
+		if host == entry || (strings.HasPrefix(entry, ".") && (host == entry[1:] || strings.HasSuffix(host, entry))) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostname returns host without the port, in lower case.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// forwarded are the attributes of a credential description passed to git on
+// the host. Others, such as url, would override the checked host.
+var forwarded = []string{"protocol", "host", "path", "username"}
+
+// parse reads the attributes of a credential description, "key=value" lines.
+// Attributes that are not forwarded are dropped, except url, which git applies
+// over the other ones. Repeated attributes other than arrays such as
+// "capability[]", and values with CR or NUL, are rejected.
+func parse(request []byte) (map[string]string, error) {
+	attrs := make(map[string]string)
+	seen := make(map[string]bool)
+	// split on LF only, a trailing CR is part of the value and rejected
+	for line := range strings.SplitSeq(string(request), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if key == "url" {
+			return nil, errors.New("url attribute is not supported")
+		}
+		if strings.ContainsAny(value, "\r\x00") {
+			return nil, fmt.Errorf("invalid %s attribute", key)
+		}
+		if strings.HasSuffix(key, "[]") {
+			continue
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate %s attribute", key)
+		}
+		seen[key] = true
+		if slices.Contains(forwarded, key) {
+			attrs[key] = value
+		}
+	}
+	return attrs, nil
+}
+
+// format writes the forwarded attributes as a credential description.
+func format(attrs map[string]string) []byte {
+	var b bytes.Buffer
+	for _, key := range forwarded {
+		if value, ok := attrs[key]; ok {
+			fmt.Fprintf(&b, "%s=%s\n", key, value)
+		}
+	}
+	return b.Bytes()
+}
+
+// fill runs 'git credential fill' on the host without prompting, so only
+// stored credentials are returned.
+func fill(ctx context.Context, request []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never")
+	cmd.Stdin = bytes.NewReader(request)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return nil, errors.New(strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("git credential fill: %w", err)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,192 @@
+package gitcredential
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// runDir returns a short private directory, unix socket paths are limited to
+// about 100 characters and t.TempDir is too long on macOS.
+func runDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "agentbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+func TestAllowed(t *testing.T) {
+	hosts := []string{"github.com", ".example.com"}
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{"github.com", true},
+		{"GitHub.com", true},
+		{"github.com:443", true},
+		{"gist.github.com", false},
+		{"example.com", true},
+		{"git.example.com", true},
+		{"badexample.com", false},
+		{"gitlab.com", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			// act
+			result := Allowed(hosts, tt.host)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("Allowed(%q) = %v, want %v", tt.host, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProxy(t *testing.T) {
+	// arrange
+	proxy, err := Start(runDir(t), []string{"github.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = proxy.Close() })
+	var filled []string
+	proxy.fill = func(_ context.Context, request []byte) ([]byte, error) {
+		filled = append(filled, string(request))
+		return []byte("protocol=https\nhost=github.com\nusername=box\npassword=secret\n"), nil
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", filepath.Join(proxy.dir, socketFile))
+		},
+	}}
+	post := func(token, request string) (int, string) {
+		req, err := http.NewRequest(http.MethodPost, "http://agentbox/get", strings.NewReader(request))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(tokenHeader, token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	get := func(request string) (int, string) { return post(proxy.token, request) }
+
+	// act
+	allowedStatus, allowedBody := get("protocol=https\nhost=github.com\npath=org/repo.git\ncapability[]=authtype\nwwwauth[]=Basic\n")
+	refusedStatus, _ := get("protocol=https\nhost=gitlab.com\n")
+	plainStatus, _ := get("protocol=http\nhost=github.com\n")
+	noTokenStatus, _ := post("", "protocol=https\nhost=github.com\n")
+	wrongTokenStatus, _ := post("wrong", "protocol=https\nhost=github.com\n")
+
+	// assert
+	if allowedStatus != http.StatusOK || !strings.Contains(allowedBody, "password=secret") {
+		t.Errorf("allowed host = %d %q, want credentials", allowedStatus, allowedBody)
+	}
+	if refusedStatus != http.StatusForbidden || plainStatus != http.StatusForbidden {
+		t.Errorf("refused host = %d, plain http = %d, want %d", refusedStatus, plainStatus, http.StatusForbidden)
+	}
+	if noTokenStatus != http.StatusUnauthorized || wrongTokenStatus != http.StatusUnauthorized {
+		t.Errorf("no token = %d, wrong token = %d, want %d", noTokenStatus, wrongTokenStatus, http.StatusUnauthorized)
+	}
+	if !slices.Equal(filled, []string{"protocol=https\nhost=github.com\npath=org/repo.git\n"}) {
+		t.Errorf("filled = %q, want one request with the forwarded attributes", filled)
+	}
+	if !slices.Equal(proxy.Refused(), []string{"gitlab.com"}) {
+		t.Errorf("Refused() = %v, want [gitlab.com]", proxy.Refused())
+	}
+	helper, err := os.ReadFile(filepath.Join(proxy.dir, helperFile))
+	if err != nil || !strings.Contains(string(helper), tokenHeader+": "+proxy.token) {
+		t.Errorf("helper = %q, %v; want it to send the token", helper, err)
+	}
+	for name, want := range map[string]os.FileMode{"": 0o700, helperFile: 0o700, socketFile: 0o600} {
+		info, err := os.Stat(filepath.Join(proxy.dir, name))
+		if err != nil {
+			t.Errorf("stat %q: %v", name, err)
+		} else if info.Mode().Perm() != want {
+			t.Errorf("mode of %q = %v, want %v", name, info.Mode().Perm(), want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		wantErr bool
+	}{
+		{"url overrides host", "protocol=https\nhost=github.com\nurl=https://evil.example/\n", true},
+		{"duplicate host", "protocol=https\nhost=github.com\nhost=evil.example\n", true},
+		{"carriage return", "protocol=https\nhost=github.com\r\n", true},
+		{"nul", "protocol=https\nhost=github.com\x00evil.example\n", true},
+		{"arrays", "protocol=https\nhost=github.com\ncapability[]=authtype\ncapability[]=state\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			_, err := parse([]byte(tt.request))
+
+			// assert
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProxy__url_override(t *testing.T) {
+	// arrange
+	proxy := &Proxy{hosts: []string{"github.com"}, token: "secret"}
+	proxy.fill = func(context.Context, []byte) ([]byte, error) {
+		t.Error("fill must not be called")
+		return nil, nil
+	}
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/get",
+		strings.NewReader("protocol=https\nhost=github.com\nurl=https://evil.example/\n"))
+	request.Header.Set(tokenHeader, "secret")
+
+	// act
+	proxy.handleGet(recorder, request)
+
+	// assert
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestProxy_Close(t *testing.T) {
+	// arrange
+	proxy, err := Start(runDir(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	err = proxy.Close()
+
+	// assert
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(proxy.dir); !os.IsNotExist(err) {
+		t.Errorf("proxy dir still exists: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+// Package ignore matches project files against gitignore-style patterns
+// from the .agentboxignore file.
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// File is the name of the ignore file in the project root.
+const File = ".agentboxignore"
+
+// Matcher matches paths against an ordered list of patterns. As in gitignore,
+// the last matching pattern decides, and "!" patterns re-include paths.
+type Matcher struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// basename patterns have no slash and match a name at any depth
+	basename bool
+}
+
+// Match is a path hidden by the matcher.
+type Match struct {
+	// Path is relative to the walked root and uses forward slashes.
+	Path  string
+	IsDir bool
+}
+
+// Load reads the ignore file of the project. It returns nil if there is no file.
+func Load(projectDir string) (*Matcher, error) {
+	f, err := os.Open(filepath.Join(projectDir, File))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", File, err)
+	}
+	return m, nil
+}
+
+// Parse reads patterns, one per line. Blank lines and lines starting with "#" are skipped.
+func Parse(r io.Reader) (*Matcher, error) {
+	m := &Matcher{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		p, ok, err := parsePattern(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	return m, scanner.Err()
+}
+
+func parsePattern(line string) (pattern, bool, error) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false, nil
+	}
+
+	var p pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		// "\#" and "\!" match names starting with these characters
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return pattern{}, false, nil
+	}
+
+	p.basename = !strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return pattern{}, false, fmt.Errorf("invalid pattern %q: %w", line, err)
+	}
+	p.re = re
+	return p, true, nil
+}
+
+// globToRegexp converts a gitignore glob into a regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// Match reports whether the path, relative to the project root and using
+// forward slashes, is ignored.
+func (m *Matcher) Match(name string, isDir bool) bool {
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		subject := name
+		if p.basename {
+			subject = path.Base(name)
+		}
+		if p.re.MatchString(subject) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// Walk returns the ignored files and directories under root. Ignored
+// directories are not descended into, and .git is skipped.
+func (m *Matcher) Walk(root string) ([]Match, error) {
+	var matches []Match
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !m.Match(rel, d.IsDir()) {
+			return nil
+		}
+
+		matches = append(matches, Match{Path: rel, IsDir: d.IsDir()})
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find ignored files: %w", err)
+	}
+	return matches, nil
+}
@@ -0,0 +1,133 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustParse(t *testing.T, patterns string) *Matcher {
+	t.Helper()
+	m, err := Parse(strings.NewReader(patterns))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	return m
+}
+
+func TestMatcher_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"basename at root", ".env", ".env", false, true},
+		{"basename nested", ".env", "services/api/.env", false, true},
+		{"wildcard", "*.pem", "certs/server.pem", false, true},
+		{"wildcard does not cross dirs", "secrets/*.json", "secrets/prod/db.json", false, false},
+		{"anchored path", "secrets/*.json", "secrets/db.json", false, true},
+		{"anchored not nested", "secrets/*.json", "app/secrets/db.json", false, false},
+		{"leading slash", "/.env", "app/.env", false, false},
+		{"double star prefix", "**/id_rsa", "home/.ssh/id_rsa", false, true},
+		{"double star middle", "config/**/*.key", "config/a/b/c.key", false, true},
+		{"double star suffix", "private/**", "private/a/b", false, true},
+		{"dir only matches dir", "secrets/", "secrets", true, true},
+		{"dir only skips file", "secrets/", "secrets", false, false},
+		{"question mark", "key?.txt", "key1.txt", false, true},
+		{"char class", "key[0-9].txt", "keyA.txt", false, false},
+		{"negated char class", "key[!0-9].txt", "keyA.txt", false, true},
+		{"negation", ".env*\n!.env.example", ".env.example", false, false},
+		{"negation keeps others", ".env*\n!.env.example", ".env.local", false, true},
+		{"comment", "# .env", ".env", false, false},
+		{"escaped hash", `\#notes`, "#notes", false, true},
+		{"trailing spaces", ".env   ", ".env", false, true},
+		{"no match", ".env", "main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			m := mustParse(t, tt.patterns)
+
+			// act
+			result := m.Match(tt.path, tt.isDir)
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("Match(%q) with %q = %v, want %v", tt.path, tt.patterns, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMatcher_Walk(t *testing.T) {
+	// arrange
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "SECRET=1")
+	writeFile(t, filepath.Join(root, ".env.example"), "SECRET=")
+	writeFile(t, filepath.Join(root, "main.go"), "package main")
+	writeFile(t, filepath.Join(root, "secrets", "db.json"), "{}")
+	writeFile(t, filepath.Join(root, "secrets", "nested", "api.json"), "{}")
+	writeFile(t, filepath.Join(root, "deploy", "id_ed25519"), "key")
+	writeFile(t, filepath.Join(root, ".git", "id_ed25519"), "not walked")
+	m := mustParse(t, ".env*\n!.env.example\nsecrets/\nid_*\n")
+
+	// act
+	matches, err := m.Walk(root)
+
+	// assert
+	if err != nil {
+		t.Fatalf("Walk error: %v", err)
+	}
+	expected := []Match{
+		{Path: ".env"},
+		{Path: "deploy/id_ed25519"},
+		{Path: "secrets", IsDir: true},
+	}
+	if !slices.Equal(matches, expected) {
+		t.Errorf("Walk = %v, want %v", matches, expected)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		// act
+		m, err := Load(t.TempDir())
+
+		// assert
+		if m != nil || err != nil {
+			t.Errorf("Load = %v, %v; want nil, nil", m, err)
+		}
+	})
+
+	t.Run("existing file", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, File), "*.pem\n")
+
+		// act
+		m, err := Load(dir)
+
+		// assert
+		if err != nil {
+			t.Fatalf("Load error: %v", err)
+		}
+		if !m.Match("tls/server.pem", false) {
+			t.Error("loaded pattern does not match")
+		}
+	})
+}
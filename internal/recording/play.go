@@ -0,0 +1,246 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no recording matches an id.
+var ErrNotFound = errors.New("recording not found")
+
+// Info describes a recording file.
+type Info struct {
+	ID       string
+	Path     string
+	Header   Header
+	Duration time.Duration
+}
+
+// Started returns the time the recording started.
+func (i Info) Started() time.Time {
+	return time.Unix(i.Header.Timestamp, 0)
+}
+
+// event is an event line of an asciicast v2 file: [time, code, data].
+type event struct {
+	Time float64
+	Code string
+	Data string
+}
+
+func (e *event) UnmarshalJSON(b []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return fmt.Errorf("event has %d fields, want 3", len(fields))
+	}
+	if err := json.Unmarshal(fields[0], &e.Time); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(fields[1], &e.Code); err != nil {
+		return err
+	}
+	return json.Unmarshal(fields[2], &e.Data)
+}
+
+// decoder reads an asciicast v2 stream.
+type decoder struct {
+	r    *bufio.Reader
+	line int
+}
+
+func newDecoder(r io.Reader) *decoder {
+	return &decoder{r: bufio.NewReader(r)}
+}
+
+// next decodes the next non-empty line into v and returns io.EOF at the end.
+func (d *decoder) next(v any) error {
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return err
+		}
+		d.line++
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if jsonErr := json.Unmarshal(line, v); jsonErr != nil {
+			return fmt.Errorf("line %d: %w", d.line, jsonErr)
+		}
+		return nil
+	}
+}
+
+func (d *decoder) header() (Header, error) {
+	var h Header
+	if err := d.next(&h); err != nil {
+		if errors.Is(err, io.EOF) {
+			return h, errors.New("empty recording")
+		}
+		return h, err
+	}
+	if h.Version != 2 {
+		return h, fmt.Errorf("unsupported asciicast version %d", h.Version)
+	}
+	return h, nil
+}
+
+// PlayOptions configures playback.
+type PlayOptions struct {
+	// Speed multiplies the playback speed, 1 if zero.
+	Speed float64
+	// IdleLimit caps pauses between events, no limit if zero.
+	IdleLimit time.Duration
+}
+
+// Play writes the output events of the recording to w in real time.
+func Play(r io.Reader, w io.Writer, opts PlayOptions) error {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+
+	d := newDecoder(r)
+	if _, err := d.header(); err != nil {
+		return err
+	}
+
+	var last float64
+	for {
+		var e event
+		err := d.next(&e)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			// a session that was killed can end with a partial line
+			return nil
+		}
+
+		pause := time.Duration((e.Time - last) * float64(time.Second))
+		if opts.IdleLimit > 0 {
+			pause = min(pause, opts.IdleLimit)
+		}
+		if pause > 0 {
+			time.Sleep(time.Duration(float64(pause) / opts.Speed))
+		}
+		last = e.Time
+
+		if e.Code == "o" {
+			if _, err := io.WriteString(w, e.Data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Read returns information about the recording at path.
+func Read(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+
+	d := newDecoder(f)
+	header, err := d.header()
+	if err != nil {
+		return Info{}, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var last float64
+	for {
+		var e event
+		if err := d.next(&e); err != nil {
+			break
+		}
+		last = e.Time
+	}
+
+	return Info{
+		ID:       ID(path),
+		Path:     path,
+		Header:   header,
+		Duration: time.Duration(last * float64(time.Second)),
+	}, nil
+}
+
+// List returns the recordings in dir, newest first. Unreadable files are skipped.
+func List(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []Info
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != Extension {
+			continue
+		}
+		info, err := Read(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].ID > infos[j].ID
+	})
+	return infos, nil
+}
+
+// Find returns the recording in dir whose id starts with id.
+func Find(dir, id string) (Info, error) {
+	infos, err := List(dir)
+	if err != nil {
+		return Info{}, err
+	}
+
+	var found []Info
+	for _, info := range infos {
+		if info.ID == id {
+			return info, nil
+		}
+		if id != "" && strings.HasPrefix(info.ID, id) {
+			found = append(found, info)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Info{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	case 1:
+		return found[0], nil
+	default:
+		return Info{}, fmt.Errorf("recording id %s is ambiguous", id)
+	}
+}
+
+// ID returns the id of the recording at path.
+func ID(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), Extension)
+}
+
+// NewPath returns a path for a new recording in dir, named after the start time.
+func NewPath(dir string, started time.Time) string {
+	id := started.Format("20060102-150405")
+	path := filepath.Join(dir, id+Extension)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", id, i, Extension))
+	}
+}
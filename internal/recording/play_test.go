@@ -0,0 +1,183 @@
+package recording
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sample = `{"version":2,"width":80,"height":24,"timestamp":1760000000,"title":"agentbox run"}
+[0.1,"o","hello "]
+[0.5,"r","100x30"]
+[1.5,"o","world\r\n"]
+`
+
+func writeRecording(t *testing.T, dir, id, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, id+Extension), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	// arrange
+	var out bytes.Buffer
+
+	// act
+	err := Play(strings.NewReader(sample), &out, PlayOptions{IdleLimit: time.Millisecond})
+
+	// assert
+	if err != nil {
+		t.Fatalf("Play error: %v", err)
+	}
+	if out.String() != "hello world\r\n" {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestPlay__truncated(t *testing.T) {
+	// arrange
+	var out bytes.Buffer
+	truncated := sample + `[2.0,"o","unfin`
+
+	// act
+	err := Play(strings.NewReader(truncated), &out, PlayOptions{Speed: 1000})
+
+	// assert
+	if err != nil {
+		t.Fatalf("Play error: %v", err)
+	}
+	if out.String() != "hello world\r\n" {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestPlay__invalid_header(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"empty", ""},
+		{"version 1", `{"version":1}`},
+		{"not json", "hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			err := Play(strings.NewReader(tt.content), &bytes.Buffer{}, PlayOptions{})
+
+			// assert
+			if err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	// arrange
+	dir := t.TempDir()
+	writeRecording(t, dir, "20261018-100000", sample)
+	writeRecording(t, dir, "20261018-120000", sample)
+	writeRecording(t, dir, "20261018-130000", "broken")
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	infos, err := List(dir)
+
+	// assert
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("got %d recordings, want 2: %+v", len(infos), infos)
+	}
+	if infos[0].ID != "20261018-120000" {
+		t.Errorf("first id = %s, want the newest", infos[0].ID)
+	}
+	if infos[0].Duration != 1500*time.Millisecond {
+		t.Errorf("duration = %s, want 1.5s", infos[0].Duration)
+	}
+	if infos[0].Header.Title != "agentbox run" {
+		t.Errorf("title = %q", infos[0].Header.Title)
+	}
+}
+
+func TestList__missing_dir(t *testing.T) {
+	// act
+	infos, err := List(filepath.Join(t.TempDir(), "missing"))
+
+	// assert
+	if err != nil || len(infos) != 0 {
+		t.Errorf("List = %v, %v; want no recordings", infos, err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	// arrange
+	dir := t.TempDir()
+	writeRecording(t, dir, "20261018-100000", sample)
+	writeRecording(t, dir, "20261018-100000-2", sample)
+	writeRecording(t, dir, "20261019-100000", sample)
+
+	tests := []struct {
+		id       string
+		expected string
+		err      bool
+	}{
+		{"20261018-100000", "20261018-100000", false},
+		{"20261019", "20261019-100000", false},
+		{"20261018", "", true},
+		{"2025", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			// act
+			info, err := Find(dir, tt.id)
+
+			// assert
+			if tt.err {
+				if err == nil {
+					t.Errorf("Find(%s) should fail", tt.id)
+				}
+				return
+			}
+			if err != nil || info.ID != tt.expected {
+				t.Errorf("Find(%s) = %s, %v; want %s", tt.id, info.ID, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFind__not_found(t *testing.T) {
+	// act
+	_, err := Find(t.TempDir(), "20261018")
+
+	// assert
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestNewPath(t *testing.T) {
+	// arrange
+	dir := t.TempDir()
+	started := time.Date(2026, 10, 18, 15, 30, 0, 0, time.Local)
+	writeRecording(t, dir, "20261018-153000", sample)
+
+	// act
+	path := NewPath(dir, started)
+
+	// assert
+	if filepath.Base(path) != "20261018-153000-2.cast" {
+		t.Errorf("path = %s", path)
+	}
+}
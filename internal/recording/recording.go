@@ -0,0 +1,175 @@
+// Package recording records terminal sessions as asciicast v2 files
+// (https://docs.asciinema.org/manual/asciicast/v2/) and replays them.
+package recording
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Extension is the file extension of recordings.
+const Extension = ".cast"
+
+// maxPending bounds the output held back while waiting for the end of a token.
+const maxPending = 4096
+
+// Header is the first line of an asciicast v2 file.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder writes terminal output as asciicast v2 events. Output is redacted
+// before it is written; the end of each write is held back until the token it
+// ends with is complete, so secrets split across writes are redacted too.
+type Recorder struct {
+	mu       sync.Mutex
+	w        io.Writer
+	redactor *Redactor
+	start    time.Time
+	pending  []byte
+	err      error
+}
+
+// New writes the header to w and returns a recorder appending events to it.
+// Width and height of the header default to 80x24.
+func New(w io.Writer, header Header, redactor *Redactor) (*Recorder, error) {
+	header.Version = 2
+	if header.Width <= 0 || header.Height <= 0 {
+		header.Width, header.Height = 80, 24
+	}
+	start := time.Now()
+	if header.Timestamp == 0 {
+		header.Timestamp = start.Unix()
+	}
+
+	line, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("write recording header: %w", err)
+	}
+	return &Recorder{w: w, redactor: redactor, start: start}, nil
+}
+
+// Create creates the recording file at path.
+func Create(path string, header Header, redactor *Redactor) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create recording: %w", err)
+	}
+	r, err := New(f, header, redactor)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write records terminal output. It never fails, so the recorder can be used
+// in an io.MultiWriter next to the terminal; the first error is returned by Close.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending = append(r.pending, p...)
+	keep := 0
+	if len(r.pending) < maxPending {
+		keep = incompleteTail(r.pending)
+	}
+	r.flush(len(r.pending) - keep)
+	return len(p), nil
+}
+
+// Resize records a change of the terminal size.
+func (r *Recorder) Resize(width, height int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.flush(len(r.pending))
+	r.event("r", strconv.Itoa(width)+"x"+strconv.Itoa(height))
+}
+
+// Close writes the held back output and closes the underlying writer if it is
+// an io.Closer.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.flush(len(r.pending))
+	if c, ok := r.w.(io.Closer); ok {
+		if err := c.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+	}
+	return r.err
+}
+
+// flush records the first n pending bytes as an output event.
+func (r *Recorder) flush(n int) {
+	if n <= 0 {
+		return
+	}
+	data := r.pending[:n]
+	if r.redactor != nil {
+		data = r.redactor.Redact(data)
+	}
+	r.event("o", string(data))
+	r.pending = append(r.pending[:0], r.pending[n:]...)
+}
+
+func (r *Recorder) event(code, data string) {
+	if r.err != nil {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+	line, err := json.Marshal([]any{json.Number(strconv.FormatFloat(elapsed, 'f', 6, 64)), code, data})
+	if err != nil {
+		r.err = err
+		return
+	}
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		r.err = fmt.Errorf("write recording: %w", err)
+	}
+}
+
+// incompleteTail returns the length of the end of b that may continue in the
+// next write: a run of token characters or an incomplete UTF-8 sequence.
+func incompleteTail(b []byte) int {
+	n := 0
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				n = len(b) - i
+			}
+			break
+		}
+	}
+	for n < len(b) && isTokenByte(b[len(b)-n-1]) {
+		n++
+	}
+	return n
+}
+
+func isTokenByte(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	switch c {
+	case '_', '-', '.', '/', '+', '=', ':':
+		return true
+	}
+	return false
+}
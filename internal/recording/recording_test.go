@@ -0,0 +1,146 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// outputOf joins the data of the output events of a recording and counts them.
+func outputOf(t *testing.T, recording string) (string, int) {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(recording), "\n")
+	var out strings.Builder
+	count := 0
+	for _, line := range lines[1:] {
+		var e event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		if e.Code == "o" {
+			out.WriteString(e.Data)
+			count++
+		}
+	}
+	return out.String(), count
+}
+
+func TestNew__header(t *testing.T) {
+	// arrange
+	var buf bytes.Buffer
+
+	// act
+	_, err := New(&buf, Header{Width: 120, Height: 40, Title: "agentbox run"}, nil)
+
+	// assert
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	var header Header
+	if err := json.Unmarshal(buf.Bytes(), &header); err != nil {
+		t.Fatalf("invalid header %q: %v", buf.String(), err)
+	}
+	if header.Version != 2 || header.Width != 120 || header.Height != 40 || header.Title != "agentbox run" {
+		t.Errorf("header = %+v", header)
+	}
+	if header.Timestamp == 0 {
+		t.Error("timestamp is not set")
+	}
+}
+
+func TestRecorder_Write(t *testing.T) {
+	tests := []struct {
+		name     string
+		writes   []string
+		expected string
+	}{
+		{"plain output", []string{"hello\r\n", "world\r\n"}, "hello\r\nworld\r\n"},
+		{"secret in one write", []string{"key: sk-ant-REDACTED\r\n"}, "key: [REDACTED]\r\n"},
+		{"secret split across writes", []string{"key: sk-ant-api03-abcdefghij", "klmnopqrstuvwxyz\r\n"}, "key: [REDACTED]\r\n"},
+		{"secret at the end", []string{"token ghp_", "abcdefghijklmnopqrstuvwxyz0123456789"}, "token [REDACTED]"},
+		{"configured pattern", []string{"password=hunter2\n"}, "[REDACTED]\n"},
+		{"split UTF-8 sequence", []string{"caf\xc3", "\xa9 \xe2\x9c", "\x93\n"}, "café ✓\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			redactor, err := NewRedactor([]string{`password=\S+`})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			r, err := New(&buf, Header{}, redactor)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// act
+			for _, w := range tt.writes {
+				if _, err := r.Write([]byte(w)); err != nil {
+					t.Fatalf("Write error: %v", err)
+				}
+			}
+			err = r.Close()
+
+			// assert
+			if err != nil {
+				t.Fatalf("Close error: %v", err)
+			}
+			if out, _ := outputOf(t, buf.String()); out != tt.expected {
+				t.Errorf("output = %q, want %q", out, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecorder_Write__holds_back_only_tokens(t *testing.T) {
+	// arrange
+	var buf bytes.Buffer
+	r, err := New(&buf, Header{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	_, _ = r.Write([]byte("$ ls\r\n"))
+	_, _ = r.Write([]byte("main.go"))
+
+	// assert
+	out, count := outputOf(t, buf.String())
+	if out != "$ ls\r\n" || count != 1 {
+		t.Errorf("output before close = %q in %d events", out, count)
+	}
+	_ = r.Close()
+	if out, _ := outputOf(t, buf.String()); out != "$ ls\r\nmain.go" {
+		t.Errorf("output after close = %q", out)
+	}
+}
+
+func TestRecorder_Resize(t *testing.T) {
+	// arrange
+	var buf bytes.Buffer
+	r, err := New(&buf, Header{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	r.Resize(100, 30)
+
+	// assert
+	if !strings.Contains(buf.String(), `"r","100x30"`) {
+		t.Errorf("recording has no resize event:\n%s", buf.String())
+	}
+}
+
+func TestNewRedactor__invalid_pattern(t *testing.T) {
+	// act
+	_, err := NewRedactor([]string{"("})
+
+	// assert
+	if err == nil {
+		t.Error("invalid pattern should fail")
+	}
+}
@@ -0,0 +1,48 @@
+package recording
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Redacted replaces secrets in recordings.
+const Redacted = "[REDACTED]"
+
+// DefaultPatterns match well-known API keys and tokens. They are always
+// applied in addition to the configured patterns.
+var DefaultPatterns = []string{
+	`sk-ant-[A-Za-z0-9_-]{20,}`,                                 // Anthropic
+	`sk-(proj-)?[A-Za-z0-9_-]{20,}`,                             // OpenAI
+	`gh[pousr]_[A-Za-z0-9]{36,}`,                                // GitHub
+	`github_pat_[A-Za-z0-9_]{22,}`,                              // GitHub fine-grained
+	`AIza[A-Za-z0-9_-]{35}`,                                     // Google
+	`AKIA[A-Z0-9]{16}`,                                          // AWS access key id
+	`xox[abprs]-[A-Za-z0-9-]{10,}`,                              // Slack
+	`eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]+`, // JWT
+}
+
+// Redactor replaces secrets matched by regular expressions.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor compiles the default patterns and the given ones.
+func NewRedactor(patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, p := range append(DefaultPatterns[:len(DefaultPatterns):len(DefaultPatterns)], patterns...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Redact returns b with every match replaced by Redacted.
+func (r *Redactor) Redact(b []byte) []byte {
+	for _, re := range r.patterns {
+		b = re.ReplaceAllLiteral(b, []byte(Redacted))
+	}
+	return b
+}
@@ -0,0 +1,113 @@
+package recording
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// TerminalSize returns the size of the terminal on stdout, or 80x24.
+func TerminalSize() (width, height int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// Run runs cmd in a pseudo-terminal connected to the current terminal and
+// records its output. The terminal is put into raw mode while cmd runs and
+// size changes are passed on to cmd.
+func (r *Recorder) Run(cmd *exec.Cmd) error {
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+	resize := func() {
+		if err := pty.InheritSize(os.Stdout, ptmx); err == nil {
+			r.Resize(TerminalSize())
+		}
+	}
+	_ = pty.InheritSize(os.Stdout, ptmx)
+
+	stdin := int(os.Stdin.Fd())
+	if term.IsTerminal(stdin) {
+		state, err := term.MakeRaw(stdin)
+		if err == nil {
+			defer func() { _ = term.Restore(stdin, state) }()
+		}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		copyInput(ptmx, done)
+	}()
+	go func() {
+		defer wg.Done()
+		// reading fails with EIO once cmd exits and the terminal is closed
+		_, _ = io.Copy(io.MultiWriter(os.Stdout, r), ptmx)
+	}()
+	go func() {
+		for {
+			select {
+			case <-winch:
+				resize()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	close(done)
+	wg.Wait()
+	return err
+}
+
+// copyInput copies stdin to dst until done is closed. Stdin is polled instead
+// of read in a blocking call, so no keystrokes are swallowed after cmd exits.
+func copyInput(dst io.Writer, done <-chan struct{}) {
+	fds := []unix.PollFd{{Fd: int32(os.Stdin.Fd()), Events: unix.POLLIN}}
+	buf := make([]byte, 4096)
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		n, err := unix.Poll(fds, 100)
+		if errors.Is(err, unix.EINTR) || n == 0 {
+			continue
+		}
+		if err != nil || fds[0].Revents&(unix.POLLIN|unix.POLLHUP) == 0 {
+			return
+		}
+
+		n, err = os.Stdin.Read(buf)
+		if n > 0 {
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package review
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aleksey925/agentbox/internal/diff"
+	"github.com/aleksey925/agentbox/internal/ignore"
+)
+
+// workDir is the copy of the project mounted into the container.
+const workDir = "app"
+
+// Kind is the kind of change made to a file.
+type Kind string
+
+const (
+	Added    Kind = "A"
+	Modified Kind = "M"
+	Deleted  Kind = "D"
+)
+
+// Change is a file changed in the working copy.
+type Change struct {
+	// Path is relative to the project root, with forward slashes.
+	Path string
+	Kind Kind
+	// Conflict is set when the project file also changed since the session started.
+	Conflict bool
+}
+
+// Session is a copy of a project that a sandbox works on instead of the project itself.
+type Session struct {
+	// Dir holds the working copy.
+	Dir        string
+	ProjectDir string
+	// baseline maps file paths to content hashes at the time of the copy.
+	baseline map[string]string
+	// ignored paths are neither copied nor reviewed, nil if there are none.
+	ignored *ignore.Matcher
+}
+
+// skipDirs are not copied: the repository is mounted read-only instead.
+var skipDirs = map[string]bool{".git": true}
+
+// Create copies projectDir into a new session under stateDir. Paths matched by
+// ignored, which may be nil, are not copied, so the copy holds no hidden secrets.
+func Create(stateDir, projectDir string, ignored *ignore.Matcher) (*Session, error) {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create review dir: %w", err)
+	}
+	dir, err := os.MkdirTemp(stateDir, "review-")
+	if err != nil {
+		return nil, fmt.Errorf("create review dir: %w", err)
+	}
+
+	s := &Session{Dir: dir, ProjectDir: projectDir, baseline: make(map[string]string), ignored: ignored}
+	if err := os.Mkdir(s.WorkDir(), 0o755); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("create review dir: %w", err)
+	}
+
+	err = walk(projectDir, ignored, func(rel string, d fs.DirEntry) error {
+		src := filepath.Join(projectDir, rel)
+		dst := filepath.Join(s.WorkDir(), rel)
+
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			// keep directories writable for the owner, otherwise the copy fails
+			return os.MkdirAll(dst, info.Mode().Perm()|0o700)
+		}
+
+		hash, err := copyEntry(src, dst, d)
+		if err != nil {
+			return err
+		}
+		if hash != "" {
+			s.baseline[filepath.ToSlash(rel)] = hash
+		}
+		return nil
+	})
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("copy project: %w", err)
+	}
+	return s, nil
+}
+
+// WorkDir returns the working copy directory.
+func (s *Session) WorkDir() string {
+	return filepath.Join(s.Dir, workDir)
+}
+
+// Volumes returns compose volumes that mount the working copy as the project.
+// The repository is mounted read-only so git commands keep working.
+func (s *Session) Volumes(containerDir string) []string {
+	volumes := []string{s.WorkDir() + ":" + containerDir}
+	for name := range skipDirs {
+		path := filepath.Join(s.ProjectDir, name)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			volumes = append(volumes, path+":"+containerDir+"/"+name+":ro")
+		}
+	}
+	sort.Strings(volumes)
+	return volumes
+}
+
+// Changes compares the working copy with the baseline.
+func (s *Session) Changes() ([]Change, error) {
+	var changes []Change
+	seen := make(map[string]bool)
+
+	// ignored paths are masked in the container, only their mount points end up in the copy
+	err := walk(s.WorkDir(), s.ignored, func(rel string, d fs.DirEntry) error {
+		if d.IsDir() {
+			return nil
+		}
+
+		key := filepath.ToSlash(rel)
+		hash, err := hashEntry(filepath.Join(s.WorkDir(), rel), d)
+		if err != nil || hash == "" {
+			return err
+		}
+		seen[key] = true
+
+		base, ok := s.baseline[key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: key, Kind: Added})
+		case base != hash:
+			changes = append(changes, Change{Path: key, Kind: Modified})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan working copy: %w", err)
+	}
+
+	for key := range s.baseline {
+		if !seen[key] {
+			changes = append(changes, Change{Path: key, Kind: Deleted})
+		}
+	}
+
+	for i := range changes {
+		changes[i].Conflict = s.projectChanged(changes[i].Path)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// projectChanged reports whether the project file differs from the baseline.
+func (s *Session) projectChanged(key string) bool {
+	path := filepath.Join(s.ProjectDir, filepath.FromSlash(key))
+	info, err := os.Lstat(path)
+	if err != nil {
+		_, existed := s.baseline[key]
+		return existed
+	}
+
+	hash, err := hashEntry(path, fs.FileInfoToDirEntry(info))
+	if err != nil {
+		return true
+	}
+	return hash != s.baseline[key]
+}
+
+// Diff returns a unified diff of the change.
+func (s *Session) Diff(c Change) (string, error) {
+	oldName, newName := "a/"+c.Path, "b/"+c.Path
+
+	var before, after []byte
+	var err error
+	if c.Kind != Added {
+		if before, err = readEntry(filepath.Join(s.ProjectDir, filepath.FromSlash(c.Path))); err != nil {
+			return "", err
+		}
+	} else {
+		oldName = "/dev/null"
+	}
+	if c.Kind != Deleted {
+		if after, err = readEntry(filepath.Join(s.WorkDir(), filepath.FromSlash(c.Path))); err != nil {
+			return "", err
+		}
+	} else {
+		newName = "/dev/null"
+	}
+
+	return diff.Unified(oldName, newName, before, after), nil
+}
+
+// Stats returns the number of inserted and deleted lines of the change.
+func (s *Session) Stats(c Change) (inserted, deleted int) {
+	var before, after []byte
+	if c.Kind != Added {
+		before, _ = readEntry(filepath.Join(s.ProjectDir, filepath.FromSlash(c.Path)))
+	}
+	if c.Kind != Deleted {
+		after, _ = readEntry(filepath.Join(s.WorkDir(), filepath.FromSlash(c.Path)))
+	}
+	if diff.IsBinary(before) || diff.IsBinary(after) {
+		return 0, 0
+	}
+	return diff.Stats(diff.Lines(diff.SplitLines(before), diff.SplitLines(after)))
+}
+
+// Apply copies the change to the project directory.
+func (s *Session) Apply(c Change) error {
+	dst := filepath.Join(s.ProjectDir, filepath.FromSlash(c.Path))
+
+	if c.Kind == Deleted {
+		if err := os.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("delete %s: %w", c.Path, err)
+		}
+		return nil
+	}
+
+	src := filepath.Join(s.WorkDir(), filepath.FromSlash(c.Path))
+	info, err := os.Lstat(src)
+	if err != nil {
+		return fmt.Errorf("apply %s: %w", c.Path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("apply %s: %w", c.Path, err)
+	}
+	// replace instead of writing in place, the kind of the file may have changed
+	if err := os.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("apply %s: %w", c.Path, err)
+	}
+	if _, err := copyEntry(src, dst, fs.FileInfoToDirEntry(info)); err != nil {
+		return fmt.Errorf("apply %s: %w", c.Path, err)
+	}
+	return nil
+}
+
+// Remove deletes the session directory.
+func (s *Session) Remove() error {
+	if err := os.RemoveAll(s.Dir); err != nil {
+		return fmt.Errorf("remove review dir: %w", err)
+	}
+	return nil
+}
+
+// walk calls fn for every directory, regular file and symlink under root,
+// except skipped directories at the top level and paths matched by ignored.
+// Paths are relative to root.
+func walk(root string, ignored *ignore.Matcher, fn func(rel string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if d.IsDir() && skipDirs[rel] {
+			return filepath.SkipDir
+		}
+		if ignored != nil && ignored.Match(filepath.ToSlash(rel), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && !d.Type().IsRegular() && d.Type()&fs.ModeSymlink == 0 {
+			// sockets, pipes and devices are not part of the project
+			return nil
+		}
+		return fn(rel, d)
+	})
+}
+
+// copyEntry copies a regular file or symlink and returns its content hash.
+func copyEntry(src, dst string, d fs.DirEntry) (string, error) {
+	if d.Type()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return "", err
+		}
+		if err := os.Symlink(target, dst); err != nil {
+			return "", err
+		}
+		return hashLink(target), nil
+	}
+
+	info, err := d.Info()
+	if err != nil {
+		return "", err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, h), in); err != nil {
+		out.Close()
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashEntry returns the content hash of a regular file or symlink.
+func hashEntry(path string, d fs.DirEntry) (string, error) {
+	if d.Type()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		return hashLink(target), nil
+	}
+	if !d.Type().IsRegular() {
+		return "", nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashLink(target string) string {
+	sum := sha256.Sum256([]byte("symlink:" + target))
+	return hex.EncodeToString(sum[:])
+}
+
+// readEntry returns file content, or "symlink -> target" for symlinks.
+func readEntry(path string) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		return []byte("symlink -> " + target + "\n"), nil
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,220 @@
+package review
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aleksey925/agentbox/internal/ignore"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newSession creates a project with a few files and a review session for it.
+func newSession(t *testing.T) *Session {
+	t.Helper()
+	projectDir := t.TempDir()
+	writeFile(t, filepath.Join(projectDir, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(projectDir, "docs", "README.md"), "# docs\n")
+	writeFile(t, filepath.Join(projectDir, "old.txt"), "old\n")
+	writeFile(t, filepath.Join(projectDir, ".git", "HEAD"), "ref: refs/heads/main\n")
+
+	s, err := Create(t.TempDir(), projectDir, nil)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	return s
+}
+
+func TestCreate(t *testing.T) {
+	// act
+	s := newSession(t)
+
+	// assert
+	content, err := os.ReadFile(filepath.Join(s.WorkDir(), "docs", "README.md"))
+	if err != nil || string(content) != "# docs\n" {
+		t.Errorf("working copy content = %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(s.WorkDir(), ".git")); !os.IsNotExist(err) {
+		t.Error(".git should not be copied")
+	}
+}
+
+func TestCreate__ignored(t *testing.T) {
+	// arrange
+	projectDir := t.TempDir()
+	writeFile(t, filepath.Join(projectDir, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(projectDir, ".env"), "TOKEN=secret\n")
+	writeFile(t, filepath.Join(projectDir, "secrets", "key.pem"), "key\n")
+	matcher, err := ignore.Parse(strings.NewReader(".env\nsecrets/\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	s, err := Create(t.TempDir(), projectDir, matcher)
+
+	// assert
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	for _, name := range []string{".env", "secrets"} {
+		if _, err := os.Stat(filepath.Join(s.WorkDir(), name)); !os.IsNotExist(err) {
+			t.Errorf("%s should not be copied", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(s.WorkDir(), "main.go")); err != nil {
+		t.Errorf("main.go should be copied: %v", err)
+	}
+
+	// mount points of masked paths are created in the copy by the container runtime
+	writeFile(t, filepath.Join(s.WorkDir(), ".env"), "")
+	changes, err := s.Changes()
+	if err != nil {
+		t.Fatalf("Changes error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Changes = %v, want ignored paths left out", changes)
+	}
+}
+
+func TestSession_Changes(t *testing.T) {
+	// arrange
+	s := newSession(t)
+	writeFile(t, filepath.Join(s.WorkDir(), "main.go"), "package main\n\nfunc main() {}\n")
+	writeFile(t, filepath.Join(s.WorkDir(), "new", "file.go"), "package new\n")
+	if err := os.Remove(filepath.Join(s.WorkDir(), "old.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	changes, err := s.Changes()
+
+	// assert
+	if err != nil {
+		t.Fatalf("Changes error: %v", err)
+	}
+	expected := []Change{
+		{Path: "main.go", Kind: Modified},
+		{Path: "new/file.go", Kind: Added},
+		{Path: "old.txt", Kind: Deleted},
+	}
+	if !slices.Equal(changes, expected) {
+		t.Errorf("Changes = %v, want %v", changes, expected)
+	}
+}
+
+func TestSession_Changes__conflict(t *testing.T) {
+	// arrange
+	s := newSession(t)
+	writeFile(t, filepath.Join(s.WorkDir(), "main.go"), "package sandbox\n")
+	writeFile(t, filepath.Join(s.ProjectDir, "main.go"), "package host\n")
+
+	// act
+	changes, err := s.Changes()
+
+	// assert
+	if err != nil {
+		t.Fatalf("Changes error: %v", err)
+	}
+	if len(changes) != 1 || !changes[0].Conflict {
+		t.Errorf("Changes = %v, want a conflicting change of main.go", changes)
+	}
+}
+
+func TestSession_Diff(t *testing.T) {
+	// arrange
+	s := newSession(t)
+	writeFile(t, filepath.Join(s.WorkDir(), "main.go"), "package app\n")
+
+	// act
+	result, err := s.Diff(Change{Path: "main.go", Kind: Modified})
+
+	// assert
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	expected := "--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-package main\n+package app\n"
+	if result != expected {
+		t.Errorf("Diff =\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestSession_Apply(t *testing.T) {
+	// arrange
+	s := newSession(t)
+	writeFile(t, filepath.Join(s.WorkDir(), "main.go"), "package app\n")
+	writeFile(t, filepath.Join(s.WorkDir(), "new", "file.go"), "package new\n")
+	if err := os.Remove(filepath.Join(s.WorkDir(), "old.txt")); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := s.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	for _, c := range changes {
+		if err := s.Apply(c); err != nil {
+			t.Fatalf("Apply(%s) error: %v", c.Path, err)
+		}
+	}
+
+	// assert
+	for path, expected := range map[string]string{"main.go": "package app\n", "new/file.go": "package new\n"} {
+		content, err := os.ReadFile(filepath.Join(s.ProjectDir, path))
+		if err != nil || string(content) != expected {
+			t.Errorf("%s = %q, %v, want %q", path, content, err, expected)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(s.ProjectDir, "old.txt")); !os.IsNotExist(err) {
+		t.Error("old.txt should be deleted")
+	}
+}
+
+func TestSession_Volumes(t *testing.T) {
+	// arrange
+	s := newSession(t)
+
+	// act
+	volumes := s.Volumes("/home/box/app")
+
+	// assert
+	expected := []string{
+		filepath.Join(s.ProjectDir, ".git") + ":/home/box/app/.git:ro",
+		s.WorkDir() + ":/home/box/app",
+	}
+	slices.Sort(expected)
+	if !slices.Equal(volumes, expected) {
+		t.Errorf("Volumes = %v, want %v", volumes, expected)
+	}
+}
+
+func TestSession_Remove(t *testing.T) {
+	// arrange
+	s := newSession(t)
+
+	// act
+	err := s.Remove()
+
+	// assert
+	if err != nil {
+		t.Fatalf("Remove error: %v", err)
+	}
+	if _, err := os.Stat(s.Dir); !os.IsNotExist(err) {
+		t.Errorf("session dir still exists: %s", s.Dir)
+	}
+	if !strings.HasPrefix(filepath.Base(s.Dir), "review-") {
+		t.Errorf("session dir = %s, want review- prefix", s.Dir)
+	}
+}
@@ -1,6 +1,7 @@
 package skeleton
 
 import (
+	"bytes"
 	"embed"
 	"fmt"
 	"os"
@@ -10,6 +11,11 @@ import (
 //go:embed files/*
 var embeddedFS embed.FS
 
+// baseDockerfile builds the base image shared by all projects.
+//
+//go:embed base/Dockerfile.base
+var baseDockerfile []byte
+
 // overwriteFiles are always overwritten by 'agentbox init'
 var overwriteFiles = []string{
 	"Dockerfile.agentbox",
@@ -18,30 +24,105 @@ var overwriteFiles = []string{
 
 // userFiles are created only if they don't exist (never overwritten)
 var userFiles = []string{
-	"docker-compose.agentbox.local.yml",
+	LocalFile,
 }
 
-// CopyTo copies embedded skeleton files directly to the destination directory.
-// These files are always overwritten.
-func CopyTo(destDir string) error {
+// Action is what CopyTo does with a generated file that was edited by hand.
+type Action int
+
+const (
+	// Overwrite replaces the file.
+	Overwrite Action = iota
+	// Keep leaves the file as it is.
+	Keep
+	// Backup saves the file with the .orig suffix, then replaces it.
+	Backup
+)
+
+// BackupSuffix is appended to the name of a file saved by the Backup action.
+const BackupSuffix = ".orig"
+
+// Resolver decides what to do with a hand-edited file, given its current and generated content.
+type Resolver func(name string, current, generated []byte) (Action, error)
+
+// Status is the outcome of CopyTo for a file.
+type Status string
+
+const (
+	Created   Status = "Created"
+	Updated   Status = "Updated"
+	Unchanged Status = "Unchanged"
+	Kept      Status = "Kept"
+	BackedUp  Status = "Backed up"
+)
+
+// Result is the outcome of CopyTo for a file.
+type Result struct {
+	Name   string
+	Status Status
+}
+
+// CopyTo renders skeleton files with a generation header to the destination
+// directory. Files unchanged since generation are overwritten; for files edited
+// by hand, resolve decides, a nil resolve overwrites them.
+func CopyTo(destDir string, r *Renderer, vars Vars, resolve Resolver) ([]Result, error) {
+	results := make([]Result, 0, len(overwriteFiles))
 	for _, name := range overwriteFiles {
-		data, err := embeddedFS.ReadFile("files/" + name)
+		data, err := Generate(r, name, vars)
 		if err != nil {
-			return fmt.Errorf("read embedded file %s: %w", name, err)
+			return results, err
 		}
 
 		destPath := filepath.Join(destDir, name)
+		status := Created
+		current, err := os.ReadFile(destPath)
+		switch {
+		case err == nil && bytes.Equal(current, data):
+			results = append(results, Result{Name: name, Status: Unchanged})
+			continue
+		case err == nil:
+			status = Updated
+			if Edited(current) && resolve != nil {
+				action, err := resolve(name, current, data)
+				if err != nil {
+					return results, err
+				}
+				if action == Keep {
+					results = append(results, Result{Name: name, Status: Kept})
+					continue
+				}
+				if action == Backup {
+					if err := os.WriteFile(destPath+BackupSuffix, current, 0o644); err != nil {
+						return results, fmt.Errorf("back up %s: %w", name, err)
+					}
+					status = BackedUp
+				}
+			}
+		case !os.IsNotExist(err):
+			return results, fmt.Errorf("read file %s: %w", name, err)
+		}
+
 		if err := os.WriteFile(destPath, data, 0o644); err != nil {
-			return fmt.Errorf("write file %s: %w", name, err)
+			return results, fmt.Errorf("write file %s: %w", name, err)
 		}
+		results = append(results, Result{Name: name, Status: status})
 	}
 
-	return nil
+	return results, nil
 }
 
-// CopyUserFilesIfMissing copies user-specific files only if they don't exist.
+// Generate renders a file that init overwrites, stamped with a generation header.
+func Generate(r *Renderer, name string, vars Vars) ([]byte, error) {
+	data, err := r.Render(name, vars)
+	if err != nil {
+		return nil, err
+	}
+	return Stamp(data, vars.Version), nil
+}
+
+// CopyUserFilesIfMissing renders user-specific files only if they don't exist.
 // These files are never overwritten to preserve user customizations.
-func CopyUserFilesIfMissing(destDir string) ([]string, error) {
+func CopyUserFilesIfMissing(destDir string, r *Renderer, vars Vars) ([]string, error) {
 	created := make([]string, 0, len(userFiles))
 
 	for _, name := range userFiles {
@@ -51,9 +132,9 @@ func CopyUserFilesIfMissing(destDir string) ([]string, error) {
 			continue
 		}
 
-		data, err := embeddedFS.ReadFile("files/" + name)
+		data, err := r.Render(name, vars)
 		if err != nil {
-			return created, fmt.Errorf("read embedded file %s: %w", name, err)
+			return created, err
 		}
 
 		if err := os.WriteFile(destPath, data, 0o644); err != nil {
@@ -77,3 +158,8 @@ func Files() []string {
 	all = append(all, userFiles...)
 	return all
 }
+
+// BaseDockerfile returns the Dockerfile of the shared base image.
+func BaseDockerfile() []byte {
+	return baseDockerfile
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -55,7 +56,7 @@ func TestCopyTo(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	// act
-	err := CopyTo(tmpDir)
+	_, err := CopyTo(tmpDir, NewRenderer(), Vars{}, nil)
 
 	// assert
 	if err != nil {
@@ -70,12 +71,100 @@ func TestCopyTo(t *testing.T) {
 	}
 }
 
+func TestCopyTo__edited_files(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   Action
+		expected Status
+		backup   bool
+	}{
+		{"keep", Keep, Kept, false},
+		{"overwrite", Overwrite, Updated, false},
+		{"backup", Backup, BackedUp, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			tmpDir := t.TempDir()
+			vars := Vars{Version: "1.4.0"}
+			if _, err := CopyTo(tmpDir, NewRenderer(), vars, nil); err != nil {
+				t.Fatal(err)
+			}
+			dockerfile := filepath.Join(tmpDir, "Dockerfile.agentbox")
+			edited := []byte("# my own Dockerfile\nFROM debian\n")
+			if err := os.WriteFile(dockerfile, edited, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var asked []string
+			resolve := func(name string, current, generated []byte) (Action, error) {
+				asked = append(asked, name)
+				return tt.action, nil
+			}
+
+			// act
+			results, err := CopyTo(tmpDir, NewRenderer(), vars, resolve)
+
+			// assert
+			if err != nil {
+				t.Fatalf("CopyTo error: %v", err)
+			}
+			if len(asked) != 1 || asked[0] != "Dockerfile.agentbox" {
+				t.Errorf("resolver asked about %v, want only Dockerfile.agentbox", asked)
+			}
+			expected := []Result{
+				{Name: "Dockerfile.agentbox", Status: tt.expected},
+				{Name: "docker-compose.agentbox.yml", Status: Unchanged},
+			}
+			if len(results) != len(expected) || results[0] != expected[0] || results[1] != expected[1] {
+				t.Errorf("results = %v, want %v", results, expected)
+			}
+			content, _ := os.ReadFile(dockerfile)
+			if (tt.action == Keep) != bytes.Equal(content, edited) {
+				t.Errorf("Dockerfile content = %q after %s", content, tt.name)
+			}
+			backup, err := os.ReadFile(dockerfile + BackupSuffix)
+			if tt.backup && !bytes.Equal(backup, edited) {
+				t.Errorf("backup = %q, want the edited file", backup)
+			}
+			if !tt.backup && err == nil {
+				t.Errorf("unexpected backup created")
+			}
+		})
+	}
+}
+
+func TestCopyTo__generated_files_overwritten_silently(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	if _, err := CopyTo(tmpDir, NewRenderer(), Vars{Version: "1.3.0"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	resolve := func(name string, current, generated []byte) (Action, error) {
+		t.Errorf("resolver called for unedited %s", name)
+		return Keep, nil
+	}
+
+	// act
+	results, err := CopyTo(tmpDir, NewRenderer(), Vars{Version: "1.4.0"}, resolve)
+
+	// assert
+	if err != nil {
+		t.Fatalf("CopyTo error: %v", err)
+	}
+	for _, r := range results {
+		if r.Status != Updated {
+			t.Errorf("%s status = %s, want %s", r.Name, r.Status, Updated)
+		}
+	}
+}
+
 func TestCopyUserFilesIfMissing__creates_file(t *testing.T) {
 	// arrange
 	tmpDir := t.TempDir()
 
 	// act
-	created, err := CopyUserFilesIfMissing(tmpDir)
+	created, err := CopyUserFilesIfMissing(tmpDir, NewRenderer(), Vars{})
 
 	// assert
 	if err != nil {
@@ -105,7 +194,7 @@ func TestCopyUserFilesIfMissing__skips_existing(t *testing.T) {
 	}
 
 	// act
-	created, err := CopyUserFilesIfMissing(tmpDir)
+	created, err := CopyUserFilesIfMissing(tmpDir, NewRenderer(), Vars{})
 
 	// assert
 	if err != nil {
@@ -124,3 +213,20 @@ func TestCopyUserFilesIfMissing__skips_existing(t *testing.T) {
 		t.Errorf("file was overwritten, content = %s", content)
 	}
 }
+
+func TestBaseDockerfile(t *testing.T) {
+	// act
+	base := string(BaseDockerfile())
+	project, err := embeddedFS.ReadFile("files/Dockerfile.agentbox")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(base, "# Shared base image") || !strings.Contains(base, "FROM debian:") {
+		t.Errorf("unexpected base Dockerfile:\n%s", base)
+	}
+	if !strings.Contains(string(project), "FROM ${AGENTBOX_BASE_IMAGE}") {
+		t.Errorf("project Dockerfile is not built from the base image:\n%s", project)
+	}
+}
@@ -0,0 +1,71 @@
+package skeleton
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// headerPrefix starts the header line of generated files.
+const headerPrefix = "# Generated by agentbox "
+
+// headerRe matches the header line, e.g.
+// "# Generated by agentbox 1.4.0, sha256:3f2a..., do not edit (see 'agentbox init')".
+var headerRe = regexp.MustCompile(`^# Generated by agentbox (\S+), sha256:([0-9a-f]{64}),`)
+
+// directiveRe matches Dockerfile parser directives, which must stay at the top of the file.
+var directiveRe = regexp.MustCompile(`^#\s*(syntax|escape|check)\s*=`)
+
+// Header describes how a file was generated.
+type Header struct {
+	// Version is the agentbox version that generated the file.
+	Version string
+	// Hash is the sha256 of the generated content without the header line.
+	Hash string
+}
+
+// Stamp adds a header line with the version and the content hash to a generated file.
+func Stamp(content []byte, version string) []byte {
+	lines := strings.SplitAfter(string(content), "\n")
+	at := 0
+	for at < len(lines) && directiveRe.MatchString(lines[at]) {
+		at++
+	}
+
+	header := fmt.Sprintf("%s%s, sha256:%s, do not edit (see 'agentbox init')\n", headerPrefix, version, hash(content))
+	var b strings.Builder
+	b.WriteString(strings.Join(lines[:at], ""))
+	b.WriteString(header)
+	b.WriteString(strings.Join(lines[at:], ""))
+	return []byte(b.String())
+}
+
+// ParseHeader returns the header of a generated file and its content without
+// the header line. ok is false if the file has no header.
+func ParseHeader(data []byte) (header Header, content []byte, ok bool) {
+	lines := strings.SplitAfter(string(data), "\n")
+	for i, line := range lines {
+		if m := headerRe.FindStringSubmatch(line); m != nil {
+			rest := strings.Join(lines[:i], "") + strings.Join(lines[i+1:], "")
+			return Header{Version: m[1], Hash: m[2]}, []byte(rest), true
+		}
+		if !directiveRe.MatchString(line) {
+			break
+		}
+	}
+	return Header{}, data, false
+}
+
+// Edited reports whether a generated file was changed after generation.
+// Files without a header, e.g. from older versions, count as edited.
+func Edited(data []byte) bool {
+	header, content, ok := ParseHeader(data)
+	return !ok || header.Hash != hash(content)
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
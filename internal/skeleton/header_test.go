@@ -0,0 +1,87 @@
+package skeleton
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStamp(t *testing.T) {
+	// arrange
+	content := []byte("FROM debian\nRUN true\n")
+
+	// act
+	result := Stamp(content, "1.4.0")
+
+	// assert
+	lines := strings.SplitAfter(string(result), "\n")
+	if !strings.HasPrefix(lines[0], "# Generated by agentbox 1.4.0, sha256:") {
+		t.Errorf("first line = %q, want the header", lines[0])
+	}
+	if strings.Join(lines[1:], "") != string(content) {
+		t.Errorf("content after the header = %q, want %q", strings.Join(lines[1:], ""), content)
+	}
+}
+
+func TestStamp__keeps_parser_directives_first(t *testing.T) {
+	// arrange
+	content := []byte("# syntax=docker/dockerfile:1\nFROM debian\n")
+
+	// act
+	result := Stamp(content, "1.4.0")
+
+	// assert
+	lines := strings.Split(string(result), "\n")
+	if lines[0] != "# syntax=docker/dockerfile:1" || !strings.HasPrefix(lines[1], headerPrefix) {
+		t.Errorf("Stamp() =\n%s\nwant the directive before the header", result)
+	}
+	header, rest, ok := ParseHeader(result)
+	if !ok || header.Version != "1.4.0" || string(rest) != string(content) {
+		t.Errorf("ParseHeader() = %v, %q, %v", header, rest, ok)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	// arrange
+	content := []byte("services: {}\n")
+	stamped := Stamp(content, "1.4.0")
+
+	// act
+	header, rest, ok := ParseHeader(stamped)
+
+	// assert
+	if !ok {
+		t.Fatal("ParseHeader() found no header")
+	}
+	if header.Version != "1.4.0" || header.Hash != hash(content) {
+		t.Errorf("header = %+v", header)
+	}
+	if string(rest) != string(content) {
+		t.Errorf("content = %q, want %q", rest, content)
+	}
+}
+
+func TestEdited(t *testing.T) {
+	stamped := string(Stamp([]byte("FROM debian\n"), "1.4.0"))
+	tests := []struct {
+		name     string
+		data     string
+		expected bool
+	}{
+		{"generated", stamped, false},
+		{"edited", stamped + "RUN apt-get install -y vim\n", true},
+		{"header edited", strings.Replace(stamped, "sha256:", "sha256:0", 1), true},
+		{"no header", "FROM debian\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			result := Edited([]byte(tt.data))
+
+			// assert
+			if result != tt.expected {
+				t.Errorf("Edited() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
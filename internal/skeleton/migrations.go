@@ -0,0 +1,73 @@
+package skeleton
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// LocalFile is the user's compose file, created once and never overwritten by init.
+const LocalFile = "docker-compose.agentbox.local.yml"
+
+// Migration rewrites the local compose file of projects generated by older
+// versions. Migrations only match outdated content, so applying them again is a no-op.
+type Migration struct {
+	// Description tells what the migration changes and why.
+	Description string
+	re          *regexp.Regexp
+	replacement string
+}
+
+func (m Migration) apply(content []byte) []byte {
+	return m.re.ReplaceAll(content, []byte(m.replacement))
+}
+
+var migrations = []Migration{
+	{
+		Description: "remove the obsolete top-level 'version' key, compose warns about it",
+		re:          regexp.MustCompile(`(?m)^version:[^\n]*\n`),
+	},
+	{
+		Description: "mount custom agent wrappers over the launchers in /opt/agentbox/launchers, " +
+			"agents are no longer started from ~/.local/bin",
+		re:          regexp.MustCompile(`:/home/box/\.local/bin/(claude|copilot|codex|gemini)\b`),
+		replacement: ":/opt/agentbox/launchers/$1",
+	},
+}
+
+// Migrations returns the documented migrations of the local compose file.
+func Migrations() []Migration {
+	return migrations
+}
+
+// Migrate applies the migrations to the content of the local compose file and
+// returns the new content with the migrations that changed it.
+func Migrate(content []byte) ([]byte, []Migration) {
+	var applied []Migration
+	for _, m := range migrations {
+		migrated := m.apply(content)
+		if string(migrated) != string(content) {
+			applied = append(applied, m)
+			content = migrated
+		}
+	}
+	return content, applied
+}
+
+// ProjectVersion returns the agentbox version that generated the project
+// skeleton, or "" if the files have no header, i.e. predate versioned files.
+func ProjectVersion(projectDir string) (string, error) {
+	for _, name := range overwriteFiles {
+		data, err := os.ReadFile(filepath.Join(projectDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if header, _, ok := ParseHeader(data); ok {
+			return header.Version, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,79 @@
+package skeleton
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	// arrange
+	content := []byte(`version: "3.8"
+services:
+  agentbox:
+    volumes:
+      - ~/bin/claude-wrapper:/home/box/.local/bin/claude:ro
+      - ~/bin/tool:/home/box/.local/bin/tool
+`)
+
+	// act
+	result, applied := Migrate(content)
+
+	// assert
+	expected := `services:
+  agentbox:
+    volumes:
+      - ~/bin/claude-wrapper:/opt/agentbox/launchers/claude:ro
+      - ~/bin/tool:/home/box/.local/bin/tool
+`
+	if string(result) != expected {
+		t.Errorf("Migrate() =\n%s\nwant:\n%s", result, expected)
+	}
+	if len(applied) != 2 {
+		t.Errorf("applied %d migrations, want 2", len(applied))
+	}
+}
+
+func TestMigrate__idempotent(t *testing.T) {
+	// arrange
+	data, err := embeddedFS.ReadFile("files/" + LocalFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated, _ := Migrate([]byte("version: '3'\n" + string(data)))
+
+	// act
+	result, applied := Migrate(migrated)
+
+	// assert
+	if string(result) != string(data) || len(applied) != 0 {
+		t.Errorf("Migrate() changed migrated content, applied %d migrations:\n%s", len(applied), result)
+	}
+}
+
+func TestProjectVersion(t *testing.T) {
+	// arrange
+	tmpDir := t.TempDir()
+	if _, err := CopyTo(tmpDir, NewRenderer(), Vars{Version: "1.4.0"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	unstamped := t.TempDir()
+	if err := os.WriteFile(filepath.Join(unstamped, "Dockerfile.agentbox"), []byte("FROM debian\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	version, err := ProjectVersion(tmpDir)
+	old, oldErr := ProjectVersion(unstamped)
+
+	// assert
+	if err != nil || oldErr != nil {
+		t.Fatalf("ProjectVersion() errors = %v, %v", err, oldErr)
+	}
+	if version != "1.4.0" {
+		t.Errorf("ProjectVersion() = %q, want 1.4.0", version)
+	}
+	if old != "" {
+		t.Errorf("ProjectVersion() of unstamped files = %q, want empty", old)
+	}
+}
@@ -0,0 +1,93 @@
+package skeleton
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Vars are the variables available in skeleton templates.
+type Vars struct {
+	// Version is the agentbox version, stamped into the generated files.
+	Version string
+	// ProjectName is the base name of the project directory.
+	ProjectName string
+	// UID and GID are the ids of the host user.
+	UID int
+	GID int
+	// Agents are the names of the supported agents.
+	Agents []string
+	// Packages are extra system packages from the templates.packages setting.
+	Packages []string
+	// Default is the file the template overrides, rendered from the next
+	// template directory or the embedded skeleton, so a template can extend it.
+	Default string
+}
+
+// Renderer renders skeleton files from template directories, falling back to
+// the embedded files. A file found in a directory replaces the same file of
+// the following directories.
+type Renderer struct {
+	dirs []string
+}
+
+// NewRenderer returns a renderer for template directories in priority order,
+// e.g. the user templates followed by the team templates. Missing directories
+// are skipped.
+func NewRenderer(dirs ...string) *Renderer {
+	return &Renderer{dirs: dirs}
+}
+
+var funcs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// Render renders the skeleton file name.
+func (r *Renderer) Render(name string, vars Vars) ([]byte, error) {
+	return r.render(name, vars, 0)
+}
+
+// render renders name from the first layer at or after layer that has it;
+// the layer after the last directory is the embedded skeleton.
+func (r *Renderer) render(name string, vars Vars, layer int) ([]byte, error) {
+	for ; layer < len(r.dirs); layer++ {
+		path := filepath.Join(r.dirs[layer], name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read template %s: %w", path, err)
+		}
+
+		next, err := r.render(name, vars, layer+1)
+		if err != nil {
+			return nil, err
+		}
+		vars.Default = string(next)
+		return execute(path, data, vars)
+	}
+
+	data, err := embeddedFS.ReadFile("files/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("read embedded file %s: %w", name, err)
+	}
+	vars.Default = ""
+	return execute(name, data, vars)
+}
+
+func execute(name string, data []byte, vars Vars) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse template %s: %w", name, err)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return nil, fmt.Errorf("render template %s: %w", name, err)
+	}
+	return b.Bytes(), nil
+}
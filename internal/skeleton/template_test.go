@@ -0,0 +1,110 @@
+package skeleton
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenderer_Render__embedded(t *testing.T) {
+	// arrange
+	r := NewRenderer(filepath.Join(t.TempDir(), "missing"))
+
+	// act
+	result, err := r.Render("Dockerfile.agentbox", Vars{})
+
+	// assert
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if strings.Contains(string(result), "{{") || strings.Contains(string(result), "apt-get") {
+		t.Errorf("unexpected Dockerfile without packages:\n%s", result)
+	}
+	if !strings.Contains(string(result), "FROM ${AGENTBOX_BASE_IMAGE}\n\n# project tools") {
+		t.Errorf("unexpected Dockerfile layout:\n%s", result)
+	}
+}
+
+func TestRenderer_Render__packages(t *testing.T) {
+	// arrange
+	r := NewRenderer()
+
+	// act
+	result, err := r.Render("Dockerfile.agentbox", Vars{Packages: []string{"postgresql-client", "graphviz"}})
+
+	// assert
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(string(result), "apt-get install -y --no-install-recommends postgresql-client graphviz") {
+		t.Errorf("packages are not installed:\n%s", result)
+	}
+}
+
+func TestRenderer_Render__user_overrides_team(t *testing.T) {
+	// arrange
+	user := t.TempDir()
+	team := t.TempDir()
+	writeTemplate(t, team, "Dockerfile.agentbox", "FROM team\n")
+	writeTemplate(t, user, "Dockerfile.agentbox", "FROM {{.ProjectName}}:{{.UID}}:{{.GID}}\n")
+	r := NewRenderer(user, team)
+
+	// act
+	result, err := r.Render("Dockerfile.agentbox", Vars{ProjectName: "app", UID: 501, GID: 20})
+
+	// assert
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if string(result) != "FROM app:501:20\n" {
+		t.Errorf("Render() = %q, want the user template", result)
+	}
+}
+
+func TestRenderer_Render__extends_default(t *testing.T) {
+	// arrange
+	user := t.TempDir()
+	team := t.TempDir()
+	writeTemplate(t, team, "Dockerfile.agentbox", "{{.Default}}RUN team\n")
+	writeTemplate(t, user, "Dockerfile.agentbox", "{{.Default}}RUN user {{join .Agents \",\"}}\n")
+	r := NewRenderer(user, team)
+
+	// act
+	result, err := r.Render("Dockerfile.agentbox", Vars{Agents: []string{"claude", "codex"}})
+
+	// assert
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	embedded, err := NewRenderer().Render("Dockerfile.agentbox", Vars{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := string(embedded) + "RUN team\nRUN user claude,codex\n"
+	if string(result) != expected {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, expected)
+	}
+}
+
+func TestRenderer_Render__invalid_template(t *testing.T) {
+	// arrange
+	user := t.TempDir()
+	writeTemplate(t, user, "docker-compose.agentbox.yml", "{{.Unknown}}")
+	r := NewRenderer(user)
+
+	// act
+	_, err := r.Render("docker-compose.agentbox.yml", Vars{})
+
+	// assert
+	if err == nil {
+		t.Fatal("Render() expected an error for an unknown variable")
+	}
+}